@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/lockfile"
+	"github.com/lexandro/codeindex-mcp/snapshot"
+	"github.com/lexandro/codeindex-mcp/symbols"
+)
+
+// cacheLockFileName marks a cache directory as in use. The on-disk Bleve index cannot be
+// shared by two processes, e.g. two standalone servers for the same root.
+const cacheLockFileName = "owner.lock"
+
+// lockCacheDir takes exclusive ownership of cacheDir. Returns cacheDir on success, or ""
+// (run without cache) if another live process already uses it.
+func lockCacheDir(cacheDir string, logger *slog.Logger) string {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		logger.Warn("cannot create cache dir, continuing without cache", "cacheDir", cacheDir, "error", err)
+		return ""
+	}
+	owner := struct {
+		PID int `json:"pid"`
+	}{PID: os.Getpid()}
+	err := lockfile.Acquire(filepath.Join(cacheDir, cacheLockFileName), owner)
+	if errors.Is(err, lockfile.ErrLocked) {
+		logger.Warn("cache dir in use by another process, continuing without cache", "cacheDir", cacheDir)
+		return ""
+	}
+	if err != nil {
+		logger.Warn("cannot lock cache dir, continuing without cache", "cacheDir", cacheDir, "error", err)
+		return ""
+	}
+	return cacheDir
+}
+
+// unlockCacheDir releases ownership of cacheDir taken by lockCacheDir.
+func unlockCacheDir(cacheDir string) {
+	lockfile.Release(filepath.Join(cacheDir, cacheLockFileName))
+}
+
+// openIndexes creates the content index and, if a usable snapshot exists in cacheDir,
+// restores both indexes from it. Returns warm=true when the indexes were restored and
+// only need reconciliation against the filesystem instead of a full indexing pass.
+// An empty cacheDir disables caching and always returns a fresh in-memory index.
+func openIndexes(
+	cacheDir string,
+	rootDir string,
+	fileIndex *index.FileIndex,
+	logger *slog.Logger,
+) (*index.ContentIndex, bool, error) {
+	if cacheDir == "" {
+		contentIndex, err := index.NewContentIndex()
+		return contentIndex, false, err
+	}
+
+	bleveDir := snapshot.BleveDir(cacheDir)
+
+	snap, err := snapshot.Load(cacheDir, rootDir)
+	if err == nil {
+		// The snapshot is consumed on load: until the next clean save the on-disk
+		// Bleve index may diverge from it.
+		if invalidateErr := snapshot.Invalidate(cacheDir); invalidateErr != nil {
+			logger.Warn("failed to invalidate snapshot", "error", invalidateErr)
+		}
+
+		contentIndex, openErr := index.OpenPersistentContentIndex(bleveDir, snap.Contents)
+		if openErr == nil {
+			fileIndex.AddFiles(snap.Files)
+			logger.Info("loaded index snapshot",
+				"cacheDir", cacheDir,
+				"files", len(snap.Files),
+				"createdAt", snap.CreatedAt,
+			)
+			return contentIndex, true, nil
+		}
+		logger.Warn("discarding unusable index snapshot", "cacheDir", cacheDir, "error", openErr)
+	} else {
+		logger.Debug("no usable index snapshot", "cacheDir", cacheDir, "error", err)
+	}
+
+	contentIndex, err := index.NewPersistentContentIndex(bleveDir)
+	if err != nil {
+		return nil, false, err
+	}
+	return contentIndex, false, nil
+}
+
+// rebuildSymbols extracts symbols for every indexed file from the in-memory content.
+// Symbols are not part of the snapshot; re-extracting them needs no disk I/O.
+func rebuildSymbols(fileIndex *index.FileIndex, contentIndex *index.ContentIndex, symbolIndex *symbols.Index) {
+	for _, file := range fileIndex.AllFiles() {
+		content, ok := contentIndex.GetFileContent(file.RelativePath)
+		if !ok {
+			continue
+		}
+		symbolIndex.SetFile(file.RelativePath, symbols.Extract(file.RelativePath, content, file.Language))
+	}
+}
+
+// saveSnapshot persists the current file and content indexes to cacheDir.
+func saveSnapshot(
+	cacheDir string,
+	rootDir string,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	logger *slog.Logger,
+) {
+	start := time.Now()
+	snap := &snapshot.Snapshot{
+		RootDir:   rootDir,
+		CreatedAt: time.Now(),
+		Files:     fileIndex.AllFiles(),
+		Contents:  contentIndex.FileContents(),
+	}
+	if err := snapshot.Save(cacheDir, snap); err != nil {
+		logger.Warn("failed to save index snapshot", "cacheDir", cacheDir, "error", err)
+		return
+	}
+	logger.Info("saved index snapshot",
+		"cacheDir", cacheDir,
+		"files", len(snap.Files),
+		"duration", time.Since(start),
+	)
+}
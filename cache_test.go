@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+)
+
+func Test_openIndexes_NoCacheDir_ColdStart(t *testing.T) {
+	tmpDir := t.TempDir()
+	fileIndex := index.NewFileIndex()
+
+	contentIndex, warm, err := openIndexes("", tmpDir, fileIndex, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	if warm {
+		t.Error("expected cold start without cache dir")
+	}
+}
+
+func Test_openIndexes_WarmStartAfterSave(t *testing.T) {
+	rootDir := t.TempDir()
+	cacheDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(rootDir)
+
+	os.WriteFile(filepath.Join(rootDir, "main.go"), []byte("package main\n\nfunc hello() {}\n"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "util.go"), []byte("package main\n"), 0644)
+
+	// First run: cold start, index, save
+	fileIndex := index.NewFileIndex()
+	contentIndex, warm, err := openIndexes(cacheDir, rootDir, fileIndex, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warm {
+		t.Fatal("expected cold start on empty cache")
+	}
+	performIndexing(context.Background(), testRoot(rootDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+	saveSnapshot(cacheDir, rootDir, fileIndex, contentIndex, logger)
+	contentIndex.Close()
+
+	// Second run: warm start restores both indexes without touching the filesystem
+	fileIndex = index.NewFileIndex()
+	contentIndex, warm, err = openIndexes(cacheDir, rootDir, fileIndex, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	if !warm {
+		t.Fatal("expected warm start from snapshot")
+	}
+	if fileIndex.FileCount() != 2 {
+		t.Errorf("expected 2 files restored, got %d", fileIndex.FileCount())
+	}
+	results, _, err := contentIndex.Search(context.Background(), index.SearchOptions{Query: "hello"})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 || results[0].RelativePath != "main.go" {
+		t.Errorf("expected main.go from restored index, got %+v", results)
+	}
+}
+
+func Test_openIndexes_SnapshotConsumedOnLoad(t *testing.T) {
+	rootDir := t.TempDir()
+	cacheDir := t.TempDir()
+	logger := testLogger()
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, _, err := openIndexes(cacheDir, rootDir, fileIndex, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveSnapshot(cacheDir, rootDir, fileIndex, contentIndex, logger)
+	contentIndex.Close()
+
+	contentIndex, warm, _ := openIndexes(cacheDir, rootDir, index.NewFileIndex(), logger)
+	contentIndex.Close()
+	if !warm {
+		t.Fatal("expected first load to be warm")
+	}
+
+	// Without another save (e.g. after a crash) the next start must be cold
+	contentIndex, warm, _ = openIndexes(cacheDir, rootDir, index.NewFileIndex(), logger)
+	contentIndex.Close()
+	if warm {
+		t.Error("expected cold start when snapshot was not saved again")
+	}
+}
+
+func Test_lockCacheDir_SecondOwnerRunsWithoutCache(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	logger := testLogger()
+
+	if got := lockCacheDir(cacheDir, logger); got != cacheDir {
+		t.Fatalf("expected first owner to get %s, got %q", cacheDir, got)
+	}
+	if got := lockCacheDir(cacheDir, logger); got != "" {
+		t.Errorf("expected second owner to run without cache, got %q", got)
+	}
+
+	unlockCacheDir(cacheDir)
+	if got := lockCacheDir(cacheDir, logger); got != cacheDir {
+		t.Errorf("expected cache to be available after unlock, got %q", got)
+	}
+	unlockCacheDir(cacheDir)
+}
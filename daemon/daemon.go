@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/lockfile"
+)
+
+const (
+	lockFileName   = "daemon.json"
+	socketFileName = "daemon.sock"
+
+	// pollInterval is how often Attach re-checks a daemon that is still starting.
+	pollInterval = 200 * time.Millisecond
+)
+
+// ErrVersionMismatch is returned when the running daemon has a different version than the client.
+var ErrVersionMismatch = errors.New("daemon version mismatch")
+
+// Info describes a running daemon. It is stored in the daemon lock file of its root,
+// which is readable only by the owning user because it contains the access token.
+type Info struct {
+	PID        int       `json:"pid"`
+	Version    string    `json:"version"`
+	RootDir    string    `json:"root"`
+	SocketPath string    `json:"socket"`
+	Token      string    `json:"token"`
+	StartedAt  time.Time `json:"startedAt"`
+}
+
+// LockPath returns the daemon lock file location inside a per-root directory.
+func LockPath(dir string) string {
+	return filepath.Join(dir, lockFileName)
+}
+
+// Acquire registers the current process as the daemon for the root served from dir.
+// Returns lockfile.ErrLocked if another daemon for the root is running.
+func Acquire(dir string, rootDir string, version string) (*Info, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating daemon dir: %w", err)
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating daemon token: %w", err)
+	}
+
+	info := &Info{
+		PID:        os.Getpid(),
+		Version:    version,
+		RootDir:    rootDir,
+		SocketPath: filepath.Join(dir, socketFileName),
+		Token:      hex.EncodeToString(token),
+		StartedAt:  time.Now(),
+	}
+	if err := lockfile.Acquire(LockPath(dir), info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Release removes the daemon lock file if it belongs to the current process.
+func Release(dir string) {
+	lockfile.Release(LockPath(dir))
+}
+
+// Read returns the registered daemon for the root served from dir.
+func Read(dir string) (*Info, error) {
+	var info Info
+	if err := lockfile.Read(LockPath(dir), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// HTTPClient returns an HTTP client that talks to the daemon over its Unix socket and
+// authenticates every request with the daemon token.
+func HTTPClient(info *Info) *http.Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", info.SocketPath)
+		},
+	}
+	return &http.Client{Transport: &bearerTransport{token: info.Token, base: transport}}
+}
+
+// bearerTransport adds the daemon token to every request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// Probe checks that the daemon answers its health endpoint with the expected version.
+func Probe(ctx context.Context, info *Info, healthPath string, version string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://daemon"+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := HTTPClient(info).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("daemon health check returned %s", resp.Status)
+	}
+
+	var health struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return fmt.Errorf("decoding daemon health: %w", err)
+	}
+	if health.Version != version {
+		return fmt.Errorf("%w: daemon %s, client %s", ErrVersionMismatch, health.Version, version)
+	}
+	return nil
+}
+
+// AttachOptions configures Attach.
+type AttachOptions struct {
+	Dir        string   // Per-root directory holding the daemon lock file and socket
+	Version    string   // Client version; a daemon with another version is not used
+	HealthPath string   // Health endpoint of the daemon
+	SpawnArgs  []string // Arguments to start a daemon with (the executable is the current one)
+	Timeout    time.Duration
+	Logger     *slog.Logger
+}
+
+// Attach returns a ready daemon for the root, starting one in the background if none
+// is running. It waits until the timeout expires for a daemon that holds the lock but is
+// not serving yet; a daemon serves while it is still indexing.
+func Attach(ctx context.Context, options AttachOptions) (*Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	var spawnExited <-chan error
+	for {
+		info, err := Read(options.Dir)
+		switch {
+		case err == nil:
+			if info.Version != options.Version {
+				return nil, fmt.Errorf("%w: daemon %s (pid %d), client %s", ErrVersionMismatch, info.Version, info.PID, options.Version)
+			}
+			probeErr := Probe(ctx, info, options.HealthPath, options.Version)
+			if probeErr == nil {
+				return info, nil
+			}
+			if errors.Is(probeErr, ErrVersionMismatch) {
+				return nil, probeErr
+			}
+			if !lockfile.ProcessAlive(info.PID) {
+				options.Logger.Info("removing stale daemon lock", "pid", info.PID)
+				os.Remove(LockPath(options.Dir))
+				continue
+			}
+			// Still starting: the daemon takes the lock before it loads its snapshot and
+			// opens the socket, and serves as soon as that is done, before indexing completes
+
+		case errors.Is(err, os.ErrNotExist):
+			if spawnExited == nil {
+				options.Logger.Info("starting shared daemon", "dir", options.Dir)
+				spawnExited, err = spawn(options.SpawnArgs)
+				if err != nil {
+					return nil, fmt.Errorf("starting daemon: %w", err)
+				}
+			}
+
+		default:
+			return nil, fmt.Errorf("reading daemon lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for daemon: %w", ctx.Err())
+		case exitErr := <-spawnExited:
+			// The daemon we started is gone; another one may have won the lock
+			if _, readErr := Read(options.Dir); readErr != nil {
+				return nil, fmt.Errorf("daemon exited during startup: %v", exitErr)
+			}
+			spawnExited = make(chan error) // never fires again
+		case <-time.After(pollInterval):
+		}
+	}
+}
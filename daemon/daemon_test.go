@@ -0,0 +1,219 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/lockfile"
+	"github.com/lexandro/codeindex-mcp/server"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestDir returns a short temporary directory; Unix socket paths are limited to ~100 bytes.
+func newTestDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "cidx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// startTestDaemon registers the test process as daemon for dir and serves a tool named
+// "echo" on the daemon socket until the test ends. The daemon reports server.Version.
+// echo sends one progress notification if the call has a progress token, and then keeps
+// the call running briefly so that the notification arrives before the result.
+func startTestDaemon(t *testing.T, dir string) *Info {
+	t.Helper()
+	version := server.Version
+	info, err := Acquire(dir, "/project", version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Release(dir) })
+
+	mcpServer := mcp.NewServer(&mcp.Implementation{Name: "daemon", Version: version}, &mcp.ServerOptions{Instructions: "test instructions"})
+	mcp.AddTool(mcpServer, &mcp.Tool{Name: "echo"}, func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+		if token := req.Params.GetProgressToken(); token != nil {
+			req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{ProgressToken: token, Progress: 1, Total: 2, Message: "echoing"})
+			time.Sleep(200 * time.Millisecond)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "echo: " + args.Text}}}, nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.RunHTTP(ctx, mcpServer, server.HTTPOptions{
+			UnixSocket:  info.SocketPath,
+			BearerToken: info.Token,
+			Logger:      testLogger(),
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for Probe(context.Background(), info, server.HealthPath, version) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("test daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return info
+}
+
+func Test_Acquire_ReadRelease(t *testing.T) {
+	dir := newTestDir(t)
+
+	info, err := Acquire(dir, "/project", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PID != os.Getpid() || info.Token == "" || info.SocketPath == "" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	if _, err := Acquire(dir, "/project", "1.0.0"); !errors.Is(err, lockfile.ErrLocked) {
+		t.Errorf("expected ErrLocked for a second daemon, got %v", err)
+	}
+
+	read, err := Read(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Token != info.Token || read.Version != "1.0.0" || read.RootDir != "/project" {
+		t.Errorf("read info does not match: %+v", read)
+	}
+
+	Release(dir)
+	if _, err := Read(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected lock to be removed, got %v", err)
+	}
+}
+
+func Test_Attach_RunningDaemon(t *testing.T) {
+	dir := newTestDir(t)
+	info := startTestDaemon(t, dir)
+
+	attached, err := Attach(context.Background(), AttachOptions{
+		Dir:        dir,
+		Version:    server.Version,
+		HealthPath: server.HealthPath,
+		Timeout:    5 * time.Second,
+		Logger:     testLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attached.SocketPath != info.SocketPath {
+		t.Errorf("expected socket %s, got %s", info.SocketPath, attached.SocketPath)
+	}
+}
+
+func Test_Attach_VersionMismatch(t *testing.T) {
+	dir := newTestDir(t)
+	startTestDaemon(t, dir)
+
+	_, err := Attach(context.Background(), AttachOptions{
+		Dir:        dir,
+		Version:    server.Version + "-other",
+		HealthPath: server.HealthPath,
+		Timeout:    5 * time.Second,
+		Logger:     testLogger(),
+	})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+}
+
+func Test_NewProxy_WrongToken(t *testing.T) {
+	dir := newTestDir(t)
+	info := startTestDaemon(t, dir)
+
+	wrong := *info
+	wrong.Token = "wrong"
+	if _, err := NewProxy(context.Background(), &wrong, server.MCPPath); err == nil {
+		t.Error("expected proxy connection with a wrong token to fail")
+	}
+}
+
+func Test_Proxy_ForwardsToolCalls(t *testing.T) {
+	dir := newTestDir(t)
+	info := startTestDaemon(t, dir)
+	ctx := context.Background()
+
+	proxy, err := NewProxy(ctx, info, server.MCPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	if proxy.ToolCount != 1 {
+		t.Errorf("expected 1 mirrored tool, got %d", proxy.ToolCount)
+	}
+
+	// Serve the proxy in memory, as it would be served on stdio
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	proxyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go proxy.Serve(proxyCtx, serverTransport)
+
+	progress := make(chan *mcp.ProgressNotificationParams, 1)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+			progress <- req.Params
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	if session.InitializeResult().Instructions != "test instructions" {
+		t.Errorf("expected daemon instructions, got %q", session.InitializeResult().Instructions)
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].(*mcp.TextContent).Text != "echo: hi" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Progress of the daemon reaches the client with the client's own token
+	params := &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{"text": "slow"}, Meta: mcp.Meta{}}
+	params.SetProgressToken("client-token")
+	if _, err := session.CallTool(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case notification := <-progress:
+		if notification.ProgressToken != "client-token" || notification.Progress != 1 || notification.Message != "echoing" {
+			t.Errorf("unexpected progress notification: %+v", notification)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the progress notification to be relayed")
+	}
+	if len(proxy.progress) != 0 {
+		t.Errorf("expected the progress token to be released, got %v", proxy.progress)
+	}
+}
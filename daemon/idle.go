@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdleTracker records HTTP activity so that the daemon can exit when no client uses it.
+// Connected clients keep a long-lived event stream open, which counts as activity.
+type IdleTracker struct {
+	mu         sync.Mutex
+	inFlight   int
+	lastActive time.Time
+}
+
+// NewIdleTracker creates a tracker that considers the daemon active as of now.
+func NewIdleTracker() *IdleTracker {
+	return &IdleTracker{lastActive: time.Now()}
+}
+
+// Middleware wraps an HTTP handler to track requests.
+func (t *IdleTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.inFlight++
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			t.inFlight--
+			t.lastActive = time.Now()
+			t.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IdleFor returns how long no request has been in flight (0 while any request is).
+func (t *IdleTracker) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight > 0 {
+		return 0
+	}
+	return time.Since(t.lastActive)
+}
+
+// WaitIdle blocks until the tracker has been idle for timeout or ctx is done.
+// Returns true if the idle timeout was reached.
+func (t *IdleTracker) WaitIdle(ctx context.Context, timeout time.Duration) bool {
+	checkInterval := timeout / 10
+	if checkInterval < 10*time.Millisecond {
+		checkInterval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if t.IdleFor() >= timeout {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_IdleTracker_InFlightRequestKeepsActive(t *testing.T) {
+	tracker := NewIdleTracker()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	<-started
+
+	time.Sleep(20 * time.Millisecond)
+	if idle := tracker.IdleFor(); idle != 0 {
+		t.Errorf("expected no idle time while a request is in flight, got %v", idle)
+	}
+
+	close(release)
+	<-done
+	if idle := tracker.IdleFor(); idle > 10*time.Millisecond {
+		t.Errorf("expected idle time to restart after the request, got %v", idle)
+	}
+}
+
+func Test_IdleTracker_WaitIdle(t *testing.T) {
+	tracker := NewIdleTracker()
+	if !tracker.WaitIdle(context.Background(), 30*time.Millisecond) {
+		t.Error("expected idle timeout to be reached")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if tracker.WaitIdle(ctx, time.Hour) {
+		t.Error("expected WaitIdle to return false when the context is done")
+	}
+}
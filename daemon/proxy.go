@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Proxy serves the tools of a daemon on a local transport (normally stdio), forwarding
+// every tool call to the daemon. It holds no index of its own.
+type Proxy struct {
+	session *mcp.ClientSession
+	server  *mcp.Server
+	// ToolCount is the number of tools mirrored from the daemon.
+	ToolCount int
+
+	mu        sync.Mutex
+	lastToken int
+	progress  map[string]progressTarget // by the progress token sent to the daemon
+}
+
+// progressTarget is where progress notifications of a forwarded call are relayed to.
+type progressTarget struct {
+	session *mcp.ServerSession
+	token   any // progress token of the original request
+}
+
+// NewProxy connects to the daemon and mirrors its tools and instructions.
+func NewProxy(ctx context.Context, info *Info, mcpPath string) (*Proxy, error) {
+	proxy := &Proxy{progress: map[string]progressTarget{}}
+	client := mcp.NewClient(&mcp.Implementation{Name: "codeindex-mcp-proxy", Version: info.Version}, &mcp.ClientOptions{
+		ProgressNotificationHandler: proxy.relayProgress,
+	})
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   "http://daemon" + mcpPath,
+		HTTPClient: HTTPClient(info),
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to daemon: %w", err)
+	}
+
+	initResult := session.InitializeResult()
+	proxy.session = session
+	proxy.server = mcp.NewServer(initResult.ServerInfo, &mcp.ServerOptions{Instructions: initResult.Instructions})
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			session.Close()
+			return nil, fmt.Errorf("listing daemon tools: %w", err)
+		}
+		proxy.server.AddTool(tool, proxy.forward)
+		proxy.ToolCount++
+	}
+	return proxy, nil
+}
+
+// Serve runs the proxy on transport until the client disconnects or ctx is done.
+func (p *Proxy) Serve(ctx context.Context, transport mcp.Transport) error {
+	return p.server.Run(ctx, transport)
+}
+
+// Close disconnects from the daemon.
+func (p *Proxy) Close() error {
+	return p.session.Close()
+}
+
+// forward calls the tool of the same name on the daemon. Cancelling ctx cancels the call
+// on the daemon, and progress notifications of the call are relayed to the client.
+func (p *Proxy) forward(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var arguments any = req.Params.Arguments
+	if len(req.Params.Arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	params := &mcp.CallToolParams{
+		Name:      req.Params.Name,
+		Arguments: arguments,
+	}
+
+	// Client tokens are only unique per client, so the daemon gets a token of the proxy
+	if token := req.Params.GetProgressToken(); token != nil {
+		p.mu.Lock()
+		p.lastToken++
+		proxyToken := fmt.Sprintf("proxy-%d", p.lastToken)
+		p.progress[proxyToken] = progressTarget{session: req.Session, token: token}
+		p.mu.Unlock()
+		defer func() {
+			p.mu.Lock()
+			delete(p.progress, proxyToken)
+			p.mu.Unlock()
+		}()
+		params.Meta = mcp.Meta{}
+		params.SetProgressToken(proxyToken)
+	}
+	return p.session.CallTool(ctx, params)
+}
+
+// relayProgress sends a progress notification of the daemon to the client whose call it
+// belongs to, with the client's progress token. Notifications arriving after the call
+// returned are dropped.
+func (p *Proxy) relayProgress(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+	proxyToken, _ := req.Params.ProgressToken.(string)
+	p.mu.Lock()
+	target, ok := p.progress[proxyToken]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	target.session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: target.token,
+		Progress:      req.Params.Progress,
+		Total:         req.Params.Total,
+		Message:       req.Params.Message,
+	})
+}
@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// spawn starts the current executable with args as a detached background process.
+// The returned channel receives the process exit status if it ends while the caller runs.
+func spawn(args []string) (<-chan error, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("resolving executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, args...)
+	// No stdio: the daemon must not write to the client's MCP stream
+	cmd.Stdin = nil
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	return exited, nil
+}
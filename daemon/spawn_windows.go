@@ -0,0 +1,14 @@
+//go:build windows
+
+package daemon
+
+import "syscall"
+
+// detachedProcessFlag is DETACHED_PROCESS: the daemon gets no console.
+const detachedProcessFlag = 0x00000008
+
+// detachedProcAttr starts the daemon detached from the client's console so that it
+// survives the client and does not receive its Ctrl+C.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcessFlag}
+}
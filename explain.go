@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/lexandro/codeindex-mcp/tools"
+)
+
+// runExplainIgnore executes the explain-ignore subcommand: it reports for each path why it
+// is or is not indexed, using the same ignore flags as the server. args is os.Args[2:].
+// Returns the process exit code.
+func runExplainIgnore(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explain-ignore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var roots rootSpecs
+	var workspaceFile string
+	var rootName string
+	var maxFileSizeBytes int64
+	var excludes excludePatterns
+	var forceIncludes forceIncludePatterns
+	flags.Var(&roots, "root", "Project root directory, optionally named as name=dir (repeatable; default: current working directory)")
+	flags.StringVar(&workspaceFile, "workspace", "", "JSON workspace file listing named roots")
+	flags.StringVar(&rootName, "in", "", "Root the paths are relative to (multi-root workspaces)")
+	flags.Var(&excludes, "exclude", "Extra ignore pattern (repeatable)")
+	flags.Var(&forceIncludes, "force-include", "Force-include pattern that overrides all excludes (repeatable)")
+	flags.Int64Var(&maxFileSizeBytes, "max-file-size", 1024*1024, "Maximum file size in bytes (default: 1MB)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: codeindex-mcp explain-ignore [flags] path...\n\n")
+		fmt.Fprintf(stderr, "Explains why each path is or is not indexed. Pass the same flags as the server.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ws, err := resolveWorkspace(roots, workspaceFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	ignoreMatchers := newIgnoreMatchers(ws, excludes, forceIncludes, maxFileSizeBytes)
+
+	exitCode := 0
+	for i, path := range flags.Args() {
+		report, err := tools.ExplainIgnore(ws, ignoreMatchers, nil, path, rootName)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			exitCode = 1
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprint(stdout, report)
+	}
+	return exitCode
+}
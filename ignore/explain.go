@@ -0,0 +1,93 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitignore "github.com/denormal/go-gitignore"
+)
+
+// RuleSource identifies the kind of rule that decided whether a path is ignored.
+type RuleSource string
+
+const (
+	SourceNone          RuleSource = ""               // No rule matches
+	SourceAlways        RuleSource = "always"         // .git directories are always skipped
+	SourceDefault       RuleSource = "default"        // Built-in default pattern
+	SourceGitignore     RuleSource = "gitignore"      // A .gitignore file
+	SourceInfoExclude   RuleSource = "info-exclude"   // .git/info/exclude
+	SourceGlobalExclude RuleSource = "global-exclude" // The user's core.excludesFile
+	SourceClaudeignore  RuleSource = "claudeignore"   // A .claudeignore file
+	SourceExclude       RuleSource = "exclude"        // A --exclude pattern
+)
+
+// Explanation describes the rule that decides whether a path is ignored.
+type Explanation struct {
+	Ignored bool
+	Source  RuleSource
+	Pattern string // The deciding pattern; a "!" prefix re-includes the path
+	File    string // Ignore file containing the pattern, if any
+	Line    int    // 1-based line of the pattern in File, 0 if unknown
+	// ParentDir is set when the rule matches a parent directory rather than the path itself.
+	// The directory is skipped during traversal unless ForceInclude is set.
+	ParentDir string
+	// ForceInclude is the --force-include pattern that overrides the rule, if any.
+	ForceInclude string
+}
+
+// explainMatch converts a match from an ignore file into an Explanation.
+func explainMatch(source RuleSource, match gitignore.Match, file string) Explanation {
+	return Explanation{
+		Ignored: match.Ignore(),
+		Source:  source,
+		Pattern: match.String(),
+		File:    file,
+		Line:    match.Position().Line,
+	}
+}
+
+// Explain reports which rule decides whether a path is indexed. Parent directories are
+// checked first, from the root down, because files below a pruned directory are never seen.
+func (m *Matcher) Explain(absolutePath string) Explanation {
+	if !filepath.IsAbs(absolutePath) {
+		absolutePath = filepath.Join(m.rootDir, absolutePath)
+	}
+
+	relativePath, err := filepath.Rel(m.rootDir, absolutePath)
+	if err == nil && relativePath != "." && !strings.HasPrefix(relativePath, "..") {
+		parts := strings.Split(filepath.ToSlash(relativePath), "/")
+		dir := m.rootDir
+		var overridden Explanation // A parent kept only because of a force-include pattern
+		for _, part := range parts[:len(parts)-1] {
+			dir = filepath.Join(dir, part)
+			explanation := m.explainDir(dir)
+			if explanation.Ignored || explanation.ForceInclude != "" {
+				explanation.ParentDir = dir
+			}
+			if explanation.Ignored {
+				return explanation
+			}
+			if explanation.ForceInclude != "" && overridden.ParentDir == "" {
+				overridden = explanation
+			}
+		}
+
+		// Report the overridden parent rule unless the path itself has a rule
+		if explanation := m.explainSelf(absolutePath); explanation.Source != SourceNone || overridden.ParentDir == "" {
+			return explanation
+		}
+		return overridden
+	}
+	return m.explainSelf(absolutePath)
+}
+
+// explainSelf explains a path without checking its parent directories.
+func (m *Matcher) explainSelf(absolutePath string) Explanation {
+	if info, err := os.Stat(absolutePath); err == nil && info.IsDir() {
+		return m.explainDir(absolutePath)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.explainPath(absolutePath)
+}
@@ -0,0 +1,69 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Matcher_Explain(t *testing.T) {
+	homeDir := isolateGitConfig(t)
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".git", "info"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "pkg", "gen"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".git", "info", "exclude"), []byte("notes.md\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "pkg", ".gitignore"), []byte("# generated\ngen/\n*.pb.go\n!keep.pb.go\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, ".claudeignore"), []byte("fixtures.json\n"), 0644)
+	globalFile := filepath.Join(homeDir, "global-ignore")
+	os.WriteFile(globalFile, []byte("*.orig\n"), 0644)
+	os.WriteFile(filepath.Join(homeDir, ".gitconfig"), []byte("[core]\n\texcludesFile = ~/global-ignore\n"), 0644)
+
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir, CustomPatterns: []string{"*.bak"}})
+
+	tests := []struct {
+		path      string
+		want      Explanation
+		parentDir string
+	}{
+		{"main.go", Explanation{}, ""},
+		{"app.exe", Explanation{Ignored: true, Source: SourceDefault, Pattern: "*.exe"}, ""},
+		{"node_modules/lib/index.js", Explanation{Ignored: true, Source: SourceDefault, Pattern: "node_modules"}, "node_modules"},
+		{".git/config", Explanation{Ignored: true, Source: SourceAlways, Pattern: ".git"}, ".git"},
+		{"pkg/api.pb.go", Explanation{Ignored: true, Source: SourceGitignore, Pattern: "*.pb.go", File: filepath.Join(tmpDir, "pkg", ".gitignore"), Line: 3}, ""},
+		{"pkg/keep.pb.go", Explanation{Source: SourceGitignore, Pattern: "!keep.pb.go", File: filepath.Join(tmpDir, "pkg", ".gitignore"), Line: 4}, ""},
+		{"pkg/gen/types.go", Explanation{Ignored: true, Source: SourceGitignore, Pattern: "gen/", File: filepath.Join(tmpDir, "pkg", ".gitignore"), Line: 2}, "pkg/gen"},
+		{"notes.md", Explanation{Ignored: true, Source: SourceInfoExclude, Pattern: "notes.md", File: filepath.Join(tmpDir, ".git", "info", "exclude"), Line: 1}, ""},
+		{"main.go.orig", Explanation{Ignored: true, Source: SourceGlobalExclude, Pattern: "*.orig", File: globalFile, Line: 1}, ""},
+		{"fixtures.json", Explanation{Ignored: true, Source: SourceClaudeignore, Pattern: "fixtures.json", File: filepath.Join(tmpDir, ".claudeignore"), Line: 1}, ""},
+		{"old.bak", Explanation{Ignored: true, Source: SourceExclude, Pattern: "*.bak"}, ""},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if tt.parentDir != "" {
+			want.ParentDir = filepath.Join(tmpDir, filepath.FromSlash(tt.parentDir))
+		}
+		got := matcher.Explain(filepath.Join(tmpDir, filepath.FromSlash(tt.path)))
+		if got != want {
+			t.Errorf("Explain(%s) = %+v, want %+v", tt.path, got, want)
+		}
+	}
+}
+
+func Test_Matcher_Explain_ForceInclude(t *testing.T) {
+	isolateGitConfig(t)
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "third_party", "lib"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("third_party/\n"), 0644)
+
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir, ForceIncludePatterns: []string{"third_party/lib/*.go", "debug.log"}})
+
+	got := matcher.Explain(filepath.Join(tmpDir, "debug.log"))
+	if got.Ignored || got.Source != SourceDefault || got.ForceInclude != "debug.log" {
+		t.Errorf("expected default rule overridden by force-include, got %+v", got)
+	}
+
+	got = matcher.Explain(filepath.Join(tmpDir, "third_party", "lib", "lib.go"))
+	if got.Ignored || got.Source != SourceGitignore || got.ForceInclude != "third_party/lib/*.go" || got.ParentDir != filepath.Join(tmpDir, "third_party") {
+		t.Errorf("expected parent rule overridden by force-include, got %+v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// globalExcludesFile returns the path of the user's git excludes file: core.excludesFile from
+// the repository, global or XDG git config (in git's precedence order), or the default
+// $XDG_CONFIG_HOME/git/ignore. Returns "" if no location can be determined.
+func globalExcludesFile(gitDir string) string {
+	homeDir, _ := os.UserHomeDir()
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" && homeDir != "" {
+		xdgConfigHome = filepath.Join(homeDir, ".config")
+	}
+
+	var configFiles []string
+	if gitDir != "" {
+		configFiles = append(configFiles, filepath.Join(gitDir, "config"))
+	}
+	if homeDir != "" {
+		configFiles = append(configFiles, filepath.Join(homeDir, ".gitconfig"))
+	}
+	if xdgConfigHome != "" {
+		configFiles = append(configFiles, filepath.Join(xdgConfigHome, "git", "config"))
+	}
+
+	// The first file in precedence order that sets the option wins
+	for _, configFile := range configFiles {
+		if value, ok := readGitConfigValue(configFile, "core", "excludesfile"); ok {
+			return expandHome(value, homeDir)
+		}
+	}
+	if xdgConfigHome == "" {
+		return ""
+	}
+	return filepath.Join(xdgConfigHome, "git", "ignore")
+}
+
+// readGitConfigValue returns the last value of section.key in a git config file.
+// Only plain sections are supported; subsections, includes and escapes are not.
+func readGitConfigValue(configFile string, section string, key string) (string, bool) {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	var value string
+	found := false
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			inSection = end > 0 && strings.EqualFold(strings.TrimSpace(line[1:end]), section)
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		name, rawValue, hasValue := strings.Cut(line, "=")
+		if !hasValue || !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+		value = strings.TrimSpace(rawValue)
+		if idx := strings.IndexAny(value, "#;"); idx >= 0 && !strings.HasPrefix(value, `"`) {
+			value = strings.TrimSpace(value[:idx])
+		}
+		value = strings.Trim(value, `"`)
+		found = true
+	}
+	return value, found
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string, homeDir string) string {
+	if homeDir != "" && (path == "~" || strings.HasPrefix(path, "~/")) {
+		return filepath.Join(homeDir, path[1:])
+	}
+	return path
+}
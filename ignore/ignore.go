@@ -10,13 +10,22 @@ import (
 )
 
 // Matcher determines whether a file path should be ignored during indexing.
-// It combines default patterns, .gitignore rules, .claudeignore rules, and custom CLI patterns.
+// It combines default patterns, git ignore rules (.gitignore files of every directory,
+// .git/info/exclude and core.excludesFile), .claudeignore files, and custom CLI patterns.
 // Thread-safe: Reload() acquires a write lock, ShouldIgnore()/ShouldIgnoreDir() acquire a read lock.
 type Matcher struct {
-	mu                   sync.RWMutex
-	rootDir              string
-	gitIgnore            gitignore.GitIgnore
-	claudeIgnore         gitignore.GitIgnore
+	mu            sync.RWMutex
+	rootDir       string
+	gitIgnores    *ignoreFileCache // .gitignore files, loaded per directory on first use
+	claudeIgnores *ignoreFileCache // .claudeignore files, loaded per directory on first use
+	// workTree is the top of the git work tree enclosing rootDir ("" outside git). The
+	// .gitignore files between it and rootDir apply as well, as they do for git.
+	workTree             string
+	gitDir               string
+	infoExclude          gitignore.GitIgnore // .git/info/exclude
+	infoExcludePath      string
+	globalExclude        gitignore.GitIgnore // core.excludesFile
+	globalExcludePath    string
 	customPatterns       []string
 	forceIncludePatterns []string
 	maxFileSizeBytes     int64
@@ -43,11 +52,13 @@ func NewMatcher(options MatcherOptions) *Matcher {
 		matcher.maxFileSizeBytes = 1024 * 1024 // 1MB default
 	}
 
-	// Load .gitignore from project root
-	matcher.gitIgnore = loadIgnoreFile(filepath.Join(options.RootDir, ".gitignore"), options.RootDir)
+	// Per-directory ignore files are discovered lazily while the tree is walked
+	matcher.gitIgnores = newIgnoreFileCache(".gitignore")
+	matcher.claudeIgnores = newIgnoreFileCache(".claudeignore")
 
-	// Load .claudeignore from project root
-	matcher.claudeIgnore = loadIgnoreFile(filepath.Join(options.RootDir, ".claudeignore"), options.RootDir)
+	matcher.workTree, matcher.gitDir = findGitDirs(options.RootDir)
+	matcher.infoExcludePath, matcher.globalExcludePath = matcher.excludeFilePaths()
+	matcher.infoExclude, matcher.globalExclude = matcher.loadExcludeFiles()
 
 	return matcher
 }
@@ -57,6 +68,15 @@ func NewMatcher(options MatcherOptions) *Matcher {
 func (m *Matcher) ShouldIgnore(absolutePath string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.explainPath(absolutePath).Ignored
+}
+
+// explainPath applies the ignore rules to a single path, without checking its parent
+// directories. Caller must hold m.mu.
+func (m *Matcher) explainPath(absolutePath string) Explanation {
+	if !filepath.IsAbs(absolutePath) {
+		absolutePath = filepath.Join(m.rootDir, absolutePath)
+	}
 
 	// Get path relative to root for pattern matching
 	relativePath, err := filepath.Rel(m.rootDir, absolutePath)
@@ -66,14 +86,24 @@ func (m *Matcher) ShouldIgnore(absolutePath string) bool {
 	// Normalize to forward slashes for consistent matching
 	relativePath = filepath.ToSlash(relativePath)
 
+	explanation := m.explainExcludes(relativePath, absolutePath)
+
 	// Force-include overrides ALL exclude rules
-	if m.matchesForceIncludePatterns(relativePath) {
-		return false
+	if explanation.Ignored {
+		if pattern, ok := m.matchesForceIncludePatterns(relativePath); ok {
+			explanation.Ignored = false
+			explanation.ForceInclude = pattern
+		}
 	}
+	return explanation
+}
 
+// explainExcludes returns the first exclude rule matching a path: default patterns, git
+// ignore rules, .claudeignore files, then custom CLI patterns. Caller must hold m.mu.
+func (m *Matcher) explainExcludes(relativePath string, absolutePath string) Explanation {
 	// Check default patterns
-	if m.matchesDefaultPatterns(relativePath, absolutePath) {
-		return true
+	if pattern, ok := m.matchesDefaultPatterns(relativePath, absolutePath); ok {
+		return Explanation{Ignored: true, Source: SourceDefault, Pattern: pattern}
 	}
 
 	// Determine if path is a directory (for gitignore matching)
@@ -82,69 +112,70 @@ func (m *Matcher) ShouldIgnore(absolutePath string) bool {
 		isDir = info.IsDir()
 	}
 
-	// Check .gitignore using Relative() which doesn't require the file to exist on disk
-	if m.gitIgnore != nil {
-		match := m.gitIgnore.Relative(relativePath, isDir)
-		if match != nil && match.Ignore() {
-			return true
-		}
+	// Check git ignore rules. A negated pattern ("!keep.txt") re-includes the path for git,
+	// but .claudeignore and custom patterns can still exclude it.
+	gitExplanation := m.matchGitRules(absolutePath, isDir)
+	if gitExplanation.Ignored {
+		return gitExplanation
 	}
 
-	// Check .claudeignore using Relative()
-	if m.claudeIgnore != nil {
-		match := m.claudeIgnore.Relative(relativePath, isDir)
-		if match != nil && match.Ignore() {
-			return true
-		}
+	// Check .claudeignore files, with the same per-directory precedence as .gitignore
+	if match, file := m.claudeIgnores.matchHierarchy(absolutePath, isDir, m.rootDir); match != nil && match.Ignore() {
+		return explainMatch(SourceClaudeignore, match, file)
 	}
 
 	// Check custom CLI patterns
-	if m.matchesCustomPatterns(relativePath) {
-		return true
+	if pattern, ok := m.matchesCustomPatterns(relativePath); ok {
+		return Explanation{Ignored: true, Source: SourceExclude, Pattern: pattern}
 	}
 
-	return false
+	return gitExplanation
 }
 
 // ShouldIgnoreDir returns true if a directory should be skipped entirely during traversal.
 func (m *Matcher) ShouldIgnoreDir(absolutePath string) bool {
+	return m.explainDir(absolutePath).Ignored
+}
+
+// explainDir decides whether a directory is pruned during traversal and why.
+func (m *Matcher) explainDir(absolutePath string) Explanation {
 	dirName := filepath.Base(absolutePath)
 
 	// .git is ALWAYS pruned — no force-include can override this
 	if dirName == ".git" {
-		return true
+		return Explanation{Ignored: true, Source: SourceAlways, Pattern: ".git"}
+	}
+
+	var explanation Explanation
+	switch dirName {
+	// Fast check: common directories that should always be skipped
+	case ".svn", ".hg", "node_modules", "__pycache__",
+		".idea", ".vscode", ".vs", ".next", ".nuxt",
+		".cache", ".parcel-cache", "coverage", ".nyc_output", "htmlcov",
+		".venv", "venv", ".env":
+		explanation = Explanation{Ignored: true, Source: SourceDefault, Pattern: dirName}
+	default:
+		// Full ignore check (includes .gitignore, .claudeignore, custom patterns)
+		m.mu.RLock()
+		explanation = m.explainPath(absolutePath)
+		m.mu.RUnlock()
 	}
 
 	// If force-include patterns exist, check if this directory could contain force-included files.
 	// If it could, don't prune it even if it would normally be ignored.
-	m.mu.RLock()
-	hasForceIncludes := len(m.forceIncludePatterns) > 0
-	m.mu.RUnlock()
-
-	if hasForceIncludes {
+	if explanation.Ignored {
 		relativePath, err := filepath.Rel(m.rootDir, absolutePath)
 		if err != nil {
 			relativePath = absolutePath
 		}
 		relativePath = filepath.ToSlash(relativePath)
 
-		if m.couldContainForceIncluded(relativePath) {
-			return false
+		if pattern, ok := m.couldContainForceIncluded(relativePath); ok {
+			explanation.Ignored = false
+			explanation.ForceInclude = pattern
 		}
 	}
-
-	// Fast check: common directories that should always be skipped
-	switch dirName {
-	case ".svn", ".hg", "node_modules", "__pycache__",
-		".idea", ".vscode", ".vs", ".next", ".nuxt",
-		".cache", ".parcel-cache", "coverage", ".nyc_output", "htmlcov",
-		".venv", "venv", ".env":
-		return true
-	}
-
-	// Full ignore check (includes .gitignore, .claudeignore, custom patterns)
-	// ShouldIgnore acquires the read lock internally
-	return m.ShouldIgnore(absolutePath)
+	return explanation
 }
 
 // IsFileTooLarge returns true if the file exceeds the max file size limit.
@@ -158,7 +189,8 @@ func (m *Matcher) MaxFileSizeBytes() int64 {
 }
 
 // matchesDefaultPatterns checks if the path matches any hardcoded default ignore pattern.
-func (m *Matcher) matchesDefaultPatterns(relativePath string, absolutePath string) bool {
+// Returns the matching pattern.
+func (m *Matcher) matchesDefaultPatterns(relativePath string, absolutePath string) (string, bool) {
 	baseName := filepath.Base(absolutePath)
 	baseNameLower := strings.ToLower(baseName)
 
@@ -167,13 +199,13 @@ func (m *Matcher) matchesDefaultPatterns(relativePath string, absolutePath strin
 		if !strings.ContainsAny(pattern, "*?[") {
 			// Exact basename match
 			if baseNameLower == strings.ToLower(pattern) {
-				return true
+				return pattern, true
 			}
 			// Check if any path component matches
 			parts := strings.Split(relativePath, "/")
 			for _, part := range parts {
 				if strings.ToLower(part) == strings.ToLower(pattern) {
-					return true
+					return pattern, true
 				}
 			}
 			continue
@@ -182,88 +214,158 @@ func (m *Matcher) matchesDefaultPatterns(relativePath string, absolutePath strin
 		// Glob pattern - match against basename
 		matched, err := filepath.Match(strings.ToLower(pattern), baseNameLower)
 		if err == nil && matched {
-			return true
+			return pattern, true
 		}
 
 		// Also try matching against the full relative path
 		matched, err = filepath.Match(strings.ToLower(pattern), strings.ToLower(relativePath))
 		if err == nil && matched {
-			return true
+			return pattern, true
 		}
 	}
-	return false
+	return "", false
 }
 
-// matchesCustomPatterns checks if the path matches any user-provided CLI exclude pattern.
-func (m *Matcher) matchesCustomPatterns(relativePath string) bool {
-	for _, pattern := range m.customPatterns {
-		// Try matching against relative path
-		matched, err := filepath.Match(pattern, relativePath)
-		if err == nil && matched {
-			return true
-		}
+// matchGitRules applies the git ignore rules in git's precedence order: .gitignore files
+// from the path's directory upwards, then .git/info/exclude, then core.excludesFile.
+// The first source with a matching pattern decides. Caller must hold m.mu.
+func (m *Matcher) matchGitRules(absolutePath string, isDir bool) Explanation {
+	if match, file := m.gitIgnores.matchHierarchy(absolutePath, isDir, m.excludeBaseDir()); match != nil {
+		return explainMatch(SourceGitignore, match, file)
+	}
 
-		// Try matching against basename
-		baseName := filepath.Base(relativePath)
-		matched, err = filepath.Match(pattern, baseName)
-		if err == nil && matched {
-			return true
+	relativePath, err := filepath.Rel(m.excludeBaseDir(), absolutePath)
+	if err != nil {
+		return Explanation{}
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	excludeFiles := []struct {
+		source RuleSource
+		rules  gitignore.GitIgnore
+		file   string
+	}{
+		{SourceInfoExclude, m.infoExclude, m.infoExcludePath},
+		{SourceGlobalExclude, m.globalExclude, m.globalExcludePath},
+	}
+	for _, excludeFile := range excludeFiles {
+		if excludeFile.rules == nil {
+			continue
+		}
+		if match := excludeFile.rules.Relative(relativePath, isDir); match != nil {
+			return explainMatch(excludeFile.source, match, excludeFile.file)
 		}
 	}
-	return false
+	return Explanation{}
+}
+
+// excludeBaseDir returns the directory that repository-wide exclude patterns are relative to.
+func (m *Matcher) excludeBaseDir() string {
+	if m.workTree != "" {
+		return m.workTree
+	}
+	return m.rootDir
+}
+
+// excludeFilePaths returns the locations of .git/info/exclude and the user's core.excludesFile
+// ("" if unknown).
+func (m *Matcher) excludeFilePaths() (infoExcludePath string, globalExcludePath string) {
+	if m.gitDir != "" {
+		infoExcludePath = filepath.Join(m.gitDir, "info", "exclude")
+	}
+	return infoExcludePath, globalExcludesFile(m.gitDir)
+}
+
+// loadExcludeFiles reads .git/info/exclude and the user's core.excludesFile.
+func (m *Matcher) loadExcludeFiles() (infoExclude gitignore.GitIgnore, globalExclude gitignore.GitIgnore) {
+	baseDir := m.excludeBaseDir()
+	if m.infoExcludePath != "" {
+		infoExclude = loadIgnoreFile(m.infoExcludePath, baseDir)
+	}
+	if m.globalExcludePath != "" {
+		globalExclude = loadIgnoreFile(m.globalExcludePath, baseDir)
+	}
+	return infoExclude, globalExclude
+}
+
+// matchesCustomPatterns checks if the path matches any user-provided CLI exclude pattern.
+// Returns the matching pattern.
+func (m *Matcher) matchesCustomPatterns(relativePath string) (string, bool) {
+	return matchPathOrBaseName(m.customPatterns, relativePath)
 }
 
 // matchesForceIncludePatterns checks if the path matches any force-include pattern.
 // Force-include patterns override ALL exclude rules (default, .gitignore, .claudeignore, custom).
-func (m *Matcher) matchesForceIncludePatterns(relativePath string) bool {
-	for _, pattern := range m.forceIncludePatterns {
+// Returns the matching pattern.
+func (m *Matcher) matchesForceIncludePatterns(relativePath string) (string, bool) {
+	return matchPathOrBaseName(m.forceIncludePatterns, relativePath)
+}
+
+// matchPathOrBaseName returns the first pattern matching the relative path or its base name.
+func matchPathOrBaseName(patterns []string, relativePath string) (string, bool) {
+	for _, pattern := range patterns {
 		// Try matching against relative path
 		matched, err := filepath.Match(pattern, relativePath)
 		if err == nil && matched {
-			return true
+			return pattern, true
 		}
 
 		// Try matching against basename
 		baseName := filepath.Base(relativePath)
 		matched, err = filepath.Match(pattern, baseName)
 		if err == nil && matched {
-			return true
+			return pattern, true
 		}
 	}
-	return false
+	return "", false
 }
 
-// couldContainForceIncluded returns true if the directory might contain files matching force-include patterns.
-// This prevents premature directory pruning when force-include patterns are active.
-func (m *Matcher) couldContainForceIncluded(relativeDirPath string) bool {
+// couldContainForceIncluded reports whether the directory might contain files matching force-include patterns,
+// returning the first such pattern. This prevents premature directory pruning when force-include patterns are active.
+func (m *Matcher) couldContainForceIncluded(relativeDirPath string) (string, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for _, pattern := range m.forceIncludePatterns {
 		// Pattern without directory component (e.g. "*.log") — could match files in ANY directory
 		if !strings.Contains(pattern, "/") {
-			return true
+			return pattern, true
 		}
 
 		// Pattern has directory prefix (e.g. "vendor/*.go") — check if this dir is a prefix of the pattern's dir
 		patternDir := pattern[:strings.LastIndex(pattern, "/")]
 		if strings.HasPrefix(patternDir, relativeDirPath) || strings.HasPrefix(relativeDirPath, patternDir) {
-			return true
+			return pattern, true
 		}
 	}
-	return false
+	return "", false
 }
 
-// Reload re-reads .gitignore and .claudeignore files from disk.
-// Used when the watcher detects changes to these files.
+// Reload re-reads all ignore files from disk: per-directory files are dropped and loaded
+// again on next use, .git/info/exclude and core.excludesFile are read immediately.
 func (m *Matcher) Reload() {
-	newGitIgnore := loadIgnoreFile(filepath.Join(m.rootDir, ".gitignore"), m.rootDir)
-	newClaudeIgnore := loadIgnoreFile(filepath.Join(m.rootDir, ".claudeignore"), m.rootDir)
+	// The exclude file locations are fixed; only git config changes could move core.excludesFile
+	infoExclude, globalExclude := m.loadExcludeFiles()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gitIgnores.clear()
+	m.claudeIgnores.clear()
+	m.infoExclude = infoExclude
+	m.globalExclude = globalExclude
+}
 
+// ReloadDir re-reads the .gitignore and .claudeignore files of one directory.
+// Used when the watcher detects a change to one of these files.
+func (m *Matcher) ReloadDir(absoluteDir string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.gitIgnore = newGitIgnore
-	m.claudeIgnore = newClaudeIgnore
+	m.gitIgnores.invalidate(absoluteDir)
+	m.claudeIgnores.invalidate(absoluteDir)
+}
+
+// IsIgnoreFile reports whether a file name is a per-directory ignore file (.gitignore or .claudeignore).
+func IsIgnoreFile(fileName string) bool {
+	return fileName == ".gitignore" || fileName == ".claudeignore"
 }
 
 // loadIgnoreFile reads an ignore file and creates a GitIgnore matcher from it.
@@ -302,3 +302,137 @@ func Test_Matcher_ForceInclude_GitAlwaysPruned(t *testing.T) {
 		t.Error("expected .git/ to ALWAYS be pruned regardless of force-include")
 	}
 }
+
+// isolateGitConfig points the user's git configuration at an empty temporary home,
+// so that the developer's own core.excludesFile does not affect the test.
+func isolateGitConfig(t *testing.T) string {
+	t.Helper()
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("USERPROFILE", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
+	return homeDir
+}
+
+func Test_Matcher_NestedGitignorePrecedence(t *testing.T) {
+	isolateGitConfig(t)
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "pkg", "tmpfiles"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "tmpfiles"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("*.gen.go\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "pkg", ".gitignore"), []byte("tmpfiles/\n!keep.gen.go\n"), 0644)
+
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir})
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"models.gen.go", true},
+		{"pkg/models.gen.go", true}, // root rule applies to subdirectories
+		{"pkg/keep.gen.go", false},  // deeper file re-includes
+		{"pkg/tmpfiles", true},      // nested rule applies inside its directory
+		{"tmpfiles", false},         // but not outside it
+		{"pkg/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := matcher.ShouldIgnore(filepath.Join(tmpDir, filepath.FromSlash(tt.path))); got != tt.ignored {
+			t.Errorf("ShouldIgnore(%s) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
+
+func Test_Matcher_ParentGitignoreInWorkTree(t *testing.T) {
+	isolateGitConfig(t)
+	repoDir := t.TempDir()
+	os.MkdirAll(filepath.Join(repoDir, ".git"), 0755)
+	os.MkdirAll(filepath.Join(repoDir, "services", "api"), 0755)
+	os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte("*.tmp\n"), 0644)
+
+	// The root is a subdirectory of the repository; the repository's .gitignore still applies
+	rootDir := filepath.Join(repoDir, "services", "api")
+	matcher := NewMatcher(MatcherOptions{RootDir: rootDir})
+
+	if !matcher.ShouldIgnore(filepath.Join(rootDir, "scratch.tmp")) {
+		t.Error("expected .gitignore above the root to apply")
+	}
+}
+
+func Test_Matcher_InfoExcludeAndGlobalExcludes(t *testing.T) {
+	homeDir := isolateGitConfig(t)
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".git", "info"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".git", "info", "exclude"), []byte("notes.md\n"), 0644)
+
+	globalFile := filepath.Join(homeDir, "global-ignore")
+	os.WriteFile(globalFile, []byte("*.swp\nREADME.md\n"), 0644)
+	os.WriteFile(filepath.Join(homeDir, ".gitconfig"), []byte("[user]\n\tname = Test\n[core]\n\texcludesFile = ~/global-ignore\n"), 0644)
+
+	// .gitignore takes precedence over the excludes files
+	os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("!README.md\n"), 0644)
+
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir})
+
+	if !matcher.ShouldIgnore(filepath.Join(tmpDir, "notes.md")) {
+		t.Error("expected .git/info/exclude pattern to apply")
+	}
+	if !matcher.ShouldIgnore(filepath.Join(tmpDir, "main.go.swp")) {
+		t.Error("expected core.excludesFile pattern to apply")
+	}
+	if matcher.ShouldIgnore(filepath.Join(tmpDir, "README.md")) {
+		t.Error("expected .gitignore negation to override core.excludesFile")
+	}
+}
+
+func Test_Matcher_DefaultGlobalExcludesLocation(t *testing.T) {
+	homeDir := isolateGitConfig(t)
+	os.MkdirAll(filepath.Join(homeDir, ".config", "git"), 0755)
+	os.WriteFile(filepath.Join(homeDir, ".config", "git", "ignore"), []byte(".DS_Store\n"), 0644)
+	tmpDir := t.TempDir()
+
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir})
+
+	if !matcher.ShouldIgnore(filepath.Join(tmpDir, "docs", ".DS_Store")) {
+		t.Error("expected $XDG_CONFIG_HOME/git/ignore to apply")
+	}
+}
+
+func Test_Matcher_ReloadDir(t *testing.T) {
+	isolateGitConfig(t)
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "web"), 0755)
+	matcher := NewMatcher(MatcherOptions{RootDir: tmpDir})
+
+	bundlePath := filepath.Join(tmpDir, "web", "bundle.js")
+	if matcher.ShouldIgnore(bundlePath) {
+		t.Fatal("expected bundle.js to be indexed before the .gitignore exists")
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "web", ".gitignore"), []byte("bundle.js\n"), 0644)
+	matcher.ReloadDir(filepath.Join(tmpDir, "web"))
+
+	if !matcher.ShouldIgnore(bundlePath) {
+		t.Error("expected the reloaded nested .gitignore to apply")
+	}
+}
+
+func Test_readGitConfigValue(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(configFile, []byte(`# comment
+[Core]
+	editor = vim
+	excludesfile = "/a/ignore" ; trailing comment
+[alias]
+	excludesFile = /not/core
+[core]
+	excludesFile = /b/ignore # last one wins
+`), 0644)
+
+	value, ok := readGitConfigValue(configFile, "core", "excludesfile")
+	if !ok || value != "/b/ignore" {
+		t.Errorf("expected /b/ignore, got %q (found=%v)", value, ok)
+	}
+	if _, ok := readGitConfigValue(configFile, "core", "missing"); ok {
+		t.Error("expected missing key not to be found")
+	}
+}
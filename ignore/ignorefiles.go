@@ -0,0 +1,107 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gitignore "github.com/denormal/go-gitignore"
+)
+
+// ignoreFileCache lazily loads the per-directory ignore files with one name (e.g. .gitignore).
+// Directories without the file are cached as well, so every directory is read at most once
+// until it is invalidated.
+type ignoreFileCache struct {
+	mu       sync.RWMutex
+	fileName string
+	files    map[string]gitignore.GitIgnore // key: absolute directory; nil = no ignore file
+}
+
+func newIgnoreFileCache(fileName string) *ignoreFileCache {
+	return &ignoreFileCache{fileName: fileName, files: make(map[string]gitignore.GitIgnore)}
+}
+
+// get returns the rules of the ignore file in dir, or nil if dir has none.
+func (c *ignoreFileCache) get(dir string) gitignore.GitIgnore {
+	c.mu.RLock()
+	rules, cached := c.files[dir]
+	c.mu.RUnlock()
+	if cached {
+		return rules
+	}
+
+	rules = loadIgnoreFile(filepath.Join(dir, c.fileName), dir)
+	c.mu.Lock()
+	c.files[dir] = rules
+	c.mu.Unlock()
+	return rules
+}
+
+// invalidate drops the cached rules of dir so they are re-read on next use.
+func (c *ignoreFileCache) invalidate(dir string) {
+	c.mu.Lock()
+	delete(c.files, dir)
+	c.mu.Unlock()
+}
+
+// clear drops all cached rules.
+func (c *ignoreFileCache) clear() {
+	c.mu.Lock()
+	c.files = make(map[string]gitignore.GitIgnore)
+	c.mu.Unlock()
+}
+
+// matchHierarchy applies the ignore files from the directory of absolutePath up to topDir.
+// As in git, a file in a deeper directory takes precedence over its parents, and within a
+// file the last matching pattern wins (so "!pattern" can re-include). Returns the deciding
+// match and the ignore file containing it, or nil if no pattern matches.
+func (c *ignoreFileCache) matchHierarchy(absolutePath string, isDir bool, topDir string) (gitignore.Match, string) {
+	for dir := filepath.Dir(absolutePath); isWithin(dir, topDir); dir = filepath.Dir(dir) {
+		if rules := c.get(dir); rules != nil {
+			relativePath, err := filepath.Rel(dir, absolutePath)
+			if err == nil {
+				if match := rules.Relative(filepath.ToSlash(relativePath), isDir); match != nil {
+					return match, filepath.Join(dir, c.fileName)
+				}
+			}
+		}
+		if dir == topDir || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return nil, ""
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(path string, dir string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// findGitDirs locates the git work tree enclosing dir. Returns the top of the work tree and
+// its git directory, or empty strings if dir is not inside a git repository.
+func findGitDirs(dir string) (workTree string, gitDir string) {
+	for current := dir; ; current = filepath.Dir(current) {
+		dotGit := filepath.Join(current, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return current, dotGit
+			}
+			// Worktrees and submodules have a .git file pointing to the git directory
+			if data, err := os.ReadFile(dotGit); err == nil {
+				if target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+					target = strings.TrimSpace(target)
+					if !filepath.IsAbs(target) {
+						target = filepath.Join(current, target)
+					}
+					return current, filepath.Clean(target)
+				}
+			}
+			return current, ""
+		}
+		if current == filepath.Dir(current) {
+			return "", ""
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package index
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	unicodetokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+const (
+	// CodeAnalyzerName is the Bleve analyzer used for file content.
+	// It tokenizes on Unicode word boundaries (keeping kebab-case words together), splits
+	// identifiers into sub-words and lowercases.
+	// No stop words are removed: words like "if", "for" and "in" are meaningful in code.
+	CodeAnalyzerName = "code"
+
+	// CasedCodeAnalyzerName is the code analyzer without lowercasing, used for case-sensitive search.
+	CasedCodeAnalyzerName = "code_cased"
+
+	// identifierSplitFilterName is the token filter that splits compound identifiers.
+	identifierSplitFilterName = "code_identifier_split"
+
+	// codeTokenizerName is the Unicode tokenizer that keeps kebab-case identifiers whole.
+	codeTokenizerName = "code_tokenizer"
+)
+
+func init() {
+	if err := registry.RegisterTokenizer(codeTokenizerName, codeTokenizerConstructor); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterTokenFilter(identifierSplitFilterName, identifierSplitFilterConstructor); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterAnalyzer(CodeAnalyzerName, codeAnalyzerConstructor); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterAnalyzer(CasedCodeAnalyzerName, casedCodeAnalyzerConstructor); err != nil {
+		panic(err)
+	}
+}
+
+// codeAnalyzerConstructor builds the code analyzer: code tokenizer -> identifier split -> lowercase.
+// The split filter must run before lowercasing because it relies on case transitions.
+func codeAnalyzerConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.Analyzer, error) {
+	tokenizer, err := cache.TokenizerNamed(codeTokenizerName)
+	if err != nil {
+		return nil, err
+	}
+	splitFilter, err := cache.TokenFilterNamed(identifierSplitFilterName)
+	if err != nil {
+		return nil, err
+	}
+	lowercaseFilter, err := cache.TokenFilterNamed(lowercase.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.DefaultAnalyzer{
+		Tokenizer:    tokenizer,
+		TokenFilters: []analysis.TokenFilter{splitFilter, lowercaseFilter},
+	}, nil
+}
+
+// casedCodeAnalyzerConstructor builds the cased code analyzer: code tokenizer -> identifier split.
+func casedCodeAnalyzerConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.Analyzer, error) {
+	tokenizer, err := cache.TokenizerNamed(codeTokenizerName)
+	if err != nil {
+		return nil, err
+	}
+	splitFilter, err := cache.TokenFilterNamed(identifierSplitFilterName)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.DefaultAnalyzer{
+		Tokenizer:    tokenizer,
+		TokenFilters: []analysis.TokenFilter{splitFilter},
+	}, nil
+}
+
+func codeTokenizerConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.Tokenizer, error) {
+	return &codeTokenizer{unicode: unicodetokenizer.NewUnicodeTokenizer()}, nil
+}
+
+// codeTokenizer splits text on Unicode word boundaries like the unicode tokenizer, but
+// joins words separated by a single '-' (codeindex-mcp), which the unicode tokenizer
+// splits, so that kebab-case identifiers are indexed whole like snake_case ones.
+type codeTokenizer struct {
+	unicode analysis.Tokenizer
+}
+
+func (t *codeTokenizer) Tokenize(input []byte) analysis.TokenStream {
+	tokens := t.unicode.Tokenize(input)
+	output := make(analysis.TokenStream, 0, len(tokens))
+	for _, token := range tokens {
+		if n := len(output); n > 0 {
+			prev := output[n-1]
+			if token.Start == prev.End+1 && input[prev.End] == '-' {
+				prev.End = token.End
+				prev.Term = input[prev.Start:prev.End]
+				continue
+			}
+		}
+		token.Position = len(output) + 1
+		output = append(output, token)
+	}
+	return output
+}
+
+func identifierSplitFilterConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+	return &identifierSplitFilter{}, nil
+}
+
+// identifierSplitFilter emits each compound identifier (camelCase, PascalCase, snake_case,
+// kebab-case, dotted) unchanged, followed by its sub-words. The full identifier shares the position
+// of its first sub-word and the sub-words take consecutive positions, so both
+// "getUserByID" and the phrase "user by id" match.
+type identifierSplitFilter struct{}
+
+func (f *identifierSplitFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+	positionShift := 0
+
+	for _, token := range input {
+		token.Position += positionShift
+		output = append(output, token)
+
+		// A single part is still a sub-word if separators surround it (__init__, _foo)
+		parts := splitIdentifier(token.Term)
+		if len(parts) == 0 || len(parts) == 1 && parts[0].end-parts[0].start == len(token.Term) {
+			continue
+		}
+		for i, part := range parts {
+			output = append(output, &analysis.Token{
+				Term:     token.Term[part.start:part.end],
+				Start:    token.Start + part.start,
+				End:      token.Start + part.end,
+				Position: token.Position + i,
+				Type:     token.Type,
+			})
+		}
+		positionShift += len(parts) - 1
+	}
+
+	return output
+}
+
+// identifierPart is a byte range of a sub-word within an identifier.
+type identifierPart struct {
+	start int
+	end   int
+}
+
+// isIdentifierSeparator reports whether r separates sub-words. Besides '_' and '-' this includes
+// '.', ':' and the apostrophe, which the Unicode tokenizer keeps inside words (foo.bar, a:b, don't).
+func isIdentifierSeparator(r rune) bool {
+	switch r {
+	case '_', '-', '.', ':', '\'':
+		return true
+	}
+	return false
+}
+
+// splitIdentifier splits an identifier into sub-words on separators (see isIdentifierSeparator),
+// lower-to-upper case transitions (getUser -> get, User) and the end of an
+// acronym (HTTPServer -> HTTP, Server). Digits stay attached to the preceding word.
+func splitIdentifier(term []byte) []identifierPart {
+	var parts []identifierPart
+	partStart := -1
+	var prev rune
+	prevUpper := false
+
+	for i := 0; i < len(term); {
+		r, size := utf8.DecodeRune(term[i:])
+		if isIdentifierSeparator(r) {
+			if partStart >= 0 {
+				parts = append(parts, identifierPart{partStart, i})
+				partStart = -1
+			}
+			i += size
+			prev = r
+			prevUpper = false
+			continue
+		}
+
+		isUpper := unicode.IsUpper(r)
+		if partStart < 0 {
+			partStart = i
+		} else if isUpper && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			// camelCase boundary: getUser -> get|User
+			parts = append(parts, identifierPart{partStart, i})
+			partStart = i
+		} else if !isUpper && unicode.IsLower(r) && prevUpper && i-partStart > utf8.RuneLen(prev) {
+			// acronym boundary: HTTPServer -> HTTP|Server (split before the last upper-case rune)
+			prevStart := i - utf8.RuneLen(prev)
+			parts = append(parts, identifierPart{partStart, prevStart})
+			partStart = prevStart
+		}
+
+		prev = r
+		prevUpper = isUpper
+		i += size
+	}
+	if partStart >= 0 {
+		parts = append(parts, identifierPart{partStart, len(term)})
+	}
+	return parts
+}
@@ -2,35 +2,98 @@ package index
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/mapping"
 )
 
 // ContentIndex provides full-text search over file contents using Bleve in-memory index.
+// When created with a storage path the Bleve index lives on disk so it can be reused across restarts.
 type ContentIndex struct {
 	mu    sync.RWMutex
 	index bleve.Index
+	// storagePath is the on-disk Bleve index directory; empty for a memory-only index
+	storagePath string
+	// indexPath is where the open Bleve index lives: storagePath, or a sibling directory
+	// after Swap adopted a generation built there. Close moves it back to storagePath.
+	indexPath string
 	// fileContents stores raw content for line-level result extraction
 	fileContents map[string]string // key: relative path, value: file content
+	// trigrams pre-filters candidate files for substring and regex queries
+	trigrams *trigramIndex
 }
 
 // NewContentIndex creates a new in-memory Bleve content index.
 func NewContentIndex() (*ContentIndex, error) {
-	indexMapping := buildIndexMapping()
-	bleveIndex, err := bleve.NewMemOnly(indexMapping)
+	return NewPersistentContentIndex("")
+}
+
+// NewPersistentContentIndex creates a new empty content index whose Bleve index is stored at storagePath.
+// Any index already present at storagePath is removed. An empty storagePath creates a memory-only index.
+func NewPersistentContentIndex(storagePath string) (*ContentIndex, error) {
+	bleveIndex, err := newBleveIndex(storagePath)
 	if err != nil {
 		return nil, fmt.Errorf("creating bleve index: %w", err)
 	}
 
 	return &ContentIndex{
 		index:        bleveIndex,
+		storagePath:  storagePath,
+		indexPath:    storagePath,
 		fileContents: make(map[string]string),
+		trigrams:     newTrigramIndex(),
 	}, nil
 }
 
+// OpenPersistentContentIndex opens an existing on-disk Bleve index at storagePath and
+// restores the raw file contents used for line extraction (Bleve does not store content).
+// Returns an error if the index cannot be opened or does not match fileContents.
+func OpenPersistentContentIndex(storagePath string, fileContents map[string]string) (*ContentIndex, error) {
+	bleveIndex, err := bleve.Open(storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("opening bleve index: %w", err)
+	}
+
+	docCount, err := bleveIndex.DocCount()
+	if err != nil || docCount != uint64(len(fileContents)) {
+		bleveIndex.Close()
+		return nil, fmt.Errorf("bleve index has %d documents, expected %d", docCount, len(fileContents))
+	}
+
+	// The trigram index is not persisted; rebuilding it from memory is cheap
+	trigrams := newTrigramIndex()
+	for relativePath, content := range fileContents {
+		trigrams.add(relativePath, content)
+	}
+
+	return &ContentIndex{
+		index:        bleveIndex,
+		storagePath:  storagePath,
+		indexPath:    storagePath,
+		fileContents: fileContents,
+		trigrams:     trigrams,
+	}, nil
+}
+
+// newBleveIndex creates an empty Bleve index, on disk at storagePath or in memory if storagePath is empty.
+func newBleveIndex(storagePath string) (bleve.Index, error) {
+	indexMapping := buildIndexMapping()
+	if storagePath == "" {
+		return bleve.NewMemOnly(indexMapping)
+	}
+	if err := os.RemoveAll(storagePath); err != nil {
+		return nil, fmt.Errorf("removing old index at %s: %w", storagePath, err)
+	}
+	return bleve.New(storagePath, indexMapping)
+}
+
+// casedContentField is the Bleve field holding the content analyzed without lowercasing.
+const casedContentField = "content_cased"
+
 // bleveDocument is the document structure stored in Bleve.
 type bleveDocument struct {
 	Content  string `json:"content"`
@@ -41,14 +104,23 @@ type bleveDocument struct {
 // buildIndexMapping creates the Bleve index mapping for code content.
 func buildIndexMapping() *mapping.IndexMappingImpl {
 	indexMapping := bleve.NewIndexMapping()
+	// Queries without a field go to _all, which is analyzed with the default analyzer
+	indexMapping.DefaultAnalyzer = CodeAnalyzerName
 
-	// Use a simple mapping - let Bleve handle the tokenization
 	docMapping := bleve.NewDocumentMapping()
 
+	// Content uses the code analyzer so identifiers are searchable by their sub-words
 	contentFieldMapping := bleve.NewTextFieldMapping()
+	contentFieldMapping.Analyzer = CodeAnalyzerName
 	contentFieldMapping.Store = false // Don't store content in Bleve; we keep it in fileContents
 	contentFieldMapping.IncludeInAll = true
-	docMapping.AddFieldMappingsAt("content", contentFieldMapping)
+	// The same content is also indexed without lowercasing as content_cased for case-sensitive search
+	casedFieldMapping := bleve.NewTextFieldMapping()
+	casedFieldMapping.Name = casedContentField
+	casedFieldMapping.Analyzer = CasedCodeAnalyzerName
+	casedFieldMapping.Store = false
+	casedFieldMapping.IncludeInAll = false
+	docMapping.AddFieldMappingsAt("content", contentFieldMapping, casedFieldMapping)
 
 	pathFieldMapping := bleve.NewTextFieldMapping()
 	pathFieldMapping.Store = true
@@ -68,17 +140,30 @@ func buildIndexMapping() *mapping.IndexMappingImpl {
 type ContentSearchResult struct {
 	RelativePath string
 	Matches      []LineMatch
+	// Terms are the distinct terms of the query found in the file, in query order
+	Terms []string
 }
 
 // LineMatch represents a single line match within a file.
 type LineMatch struct {
 	LineNumber int
 	LineText   string
+	// Spans are the matched byte ranges within LineText
+	Spans []MatchSpan
+	// Terms are the terms of the query found on the line: the words of plain text,
+	// "phrases", /regexes/ and sym:names
+	Terms []string
 	// Context lines before and after the match
 	ContextBefore []string
 	ContextAfter  []string
 }
 
+// MatchSpan is a matched byte range within a line: [Start, End), 0-based.
+type MatchSpan struct {
+	Start int
+	End   int
+}
+
 // SearchOptions configures a content search.
 type SearchOptions struct {
 	Query        string
@@ -86,23 +171,94 @@ type SearchOptions struct {
 	FileGlob     string
 	MaxResults   int
 	ContextLines int
+	// CaseSensitive matches words, phrases and symbol names with their case; the query can
+	// override it with case:yes or case:no. Regexes are always matched as written.
+	CaseSensitive bool
+	// WholeWord only matches terms that are not part of a longer identifier: a word or
+	// phrase that begins or ends with a letter, digit or underscore must not continue with
+	// another one there (id does not match valid or id_token), and regexes are enclosed in
+	// \b word boundaries.
+	WholeWord bool
+	// Terms selects how the words of plain text must occur; default TermsAny
+	Terms TermMode
+	// Progress, if set, is called before each candidate file is checked with the number of
+	// files checked so far and the number of candidates. It must not block.
+	Progress func(checked int, candidates int)
+	// SymbolDefinitions returns the lines defining symbols named name (ignoring case),
+	// keyed by relative path. Queries with sym: terms fail if it is nil.
+	SymbolDefinitions func(name string) map[string][]int
+	// Sort orders the files of the result; default SortRelevance
+	Sort SortOrder
+	// PreferTests ranks test files above other files instead of below them (SortRelevance only)
+	PreferTests bool
+	// FileDefinitions, if set, returns the names of the symbols defined in a file by line.
+	// SortRelevance ranks files whose matches are on the definition of a matching symbol higher.
+	FileDefinitions func(relativePath string) map[int][]string
+	// ModTime, if set, returns the modification time of a file for SortRecent and
+	// SortRelevance, which ranks recently modified files slightly higher.
+	ModTime func(relativePath string) time.Time
+}
+
+// TermMode selects how the words of plain text in a query must occur in a file.
+type TermMode string
+
+const (
+	// TermsAny matches files containing any of the words, like a Bleve match query, and
+	// lines containing any of them. Files with more distinct words rank first.
+	TermsAny TermMode = "any"
+	// TermsAll matches files containing all of the words, and lines containing any of them.
+	TermsAll TermMode = "all"
+	// TermsLine matches lines containing all of the words.
+	TermsLine TermMode = "line"
+)
+
+// Document is a file's content to add to the index with IndexBatch.
+type Document struct {
+	RelativePath string
+	Content      string
+	Language     string
 }
 
 // IndexFile adds or updates a file's content in the search index.
 func (ci *ContentIndex) IndexFile(relativePath string, content string, language string) error {
+	return ci.IndexBatch([]Document{{RelativePath: relativePath, Content: content, Language: language}})
+}
+
+// IndexBatch adds or updates the contents of several files with a single Bleve batch,
+// which is much faster than indexing them one by one. If the batch fails, none of the
+// files are updated.
+func (ci *ContentIndex) IndexBatch(docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
 	ci.mu.Lock()
 	defer ci.mu.Unlock()
 
-	doc := bleveDocument{
-		Content:  content,
-		Path:     relativePath,
-		Language: language,
+	batch := ci.index.NewBatch()
+	for _, doc := range docs {
+		bleveDoc := bleveDocument{
+			Content:  doc.Content,
+			Path:     doc.RelativePath,
+			Language: doc.Language,
+		}
+		if err := batch.Index(doc.RelativePath, bleveDoc); err != nil {
+			return fmt.Errorf("indexing file %s: %w", doc.RelativePath, err)
+		}
+	}
+	if err := ci.index.Batch(batch); err != nil {
+		if len(docs) == 1 {
+			return fmt.Errorf("indexing file %s: %w", docs[0].RelativePath, err)
+		}
+		return fmt.Errorf("indexing batch of %d files: %w", len(docs), err)
 	}
 
-	ci.fileContents[relativePath] = content
-
-	if err := ci.index.Index(relativePath, doc); err != nil {
-		return fmt.Errorf("indexing file %s: %w", relativePath, err)
+	for _, doc := range docs {
+		if oldContent, exists := ci.fileContents[doc.RelativePath]; exists {
+			ci.trigrams.remove(doc.RelativePath, oldContent)
+		}
+		ci.fileContents[doc.RelativePath] = doc.Content
+		ci.trigrams.add(doc.RelativePath, doc.Content)
 	}
 	return nil
 }
@@ -112,6 +268,9 @@ func (ci *ContentIndex) RemoveFile(relativePath string) {
 	ci.mu.Lock()
 	defer ci.mu.Unlock()
 
+	if oldContent, exists := ci.fileContents[relativePath]; exists {
+		ci.trigrams.remove(relativePath, oldContent)
+	}
 	delete(ci.fileContents, relativePath)
 	ci.index.Delete(relativePath)
 }
@@ -124,11 +283,77 @@ func (ci *ContentIndex) DocumentCount() uint64 {
 	return count
 }
 
-// Close closes the Bleve index.
+// Close closes the Bleve index. An on-disk index is left at the storage path it was
+// created with, where OpenPersistentContentIndex expects it.
 func (ci *ContentIndex) Close() error {
 	ci.mu.Lock()
 	defer ci.mu.Unlock()
-	return ci.index.Close()
+	if err := ci.index.Close(); err != nil {
+		return err
+	}
+	if ci.indexPath == ci.storagePath {
+		return nil
+	}
+	if err := os.RemoveAll(ci.storagePath); err != nil {
+		return fmt.Errorf("removing old index at %s: %w", ci.storagePath, err)
+	}
+	if err := os.Rename(ci.indexPath, ci.storagePath); err != nil {
+		return fmt.Errorf("moving index to %s: %w", ci.storagePath, err)
+	}
+	ci.indexPath = ci.storagePath
+	return nil
+}
+
+// NewGeneration creates an empty content index of the same kind as ci, to be filled in
+// the background and then swapped in with Swap. The Bleve index of a persistent index
+// is created in a directory next to the one in use.
+func (ci *ContentIndex) NewGeneration() (*ContentIndex, error) {
+	if ci.storagePath == "" {
+		return NewContentIndex()
+	}
+	ci.mu.RLock()
+	nextPath := ci.storagePath
+	if ci.indexPath == ci.storagePath {
+		nextPath = ci.storagePath + ".next"
+	}
+	ci.mu.RUnlock()
+	return NewPersistentContentIndex(nextPath)
+}
+
+// Swap atomically replaces the contents of ci with those of next, a generation created
+// with NewGeneration. Searches in progress finish on the previous contents. next must not
+// be used afterwards. Swap returns the previous generation, which no reader can reach any
+// more; the caller releases it with Discard, which may be slow for a large on-disk index.
+func (ci *ContentIndex) Swap(next *ContentIndex) *ContentIndex {
+	next.mu.Lock()
+	nextIndex, nextPath, nextContents, nextTrigrams := next.index, next.indexPath, next.fileContents, next.trigrams
+	next.mu.Unlock()
+
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	previous := &ContentIndex{index: ci.index, indexPath: ci.indexPath, fileContents: ci.fileContents, trigrams: ci.trigrams}
+	ci.index = nextIndex
+	ci.indexPath = nextPath
+	ci.fileContents = nextContents
+	ci.trigrams = nextTrigrams
+	return previous
+}
+
+// Discard closes a generation created with NewGeneration that will not be swapped in,
+// and deletes its Bleve index from disk.
+func (ci *ContentIndex) Discard() error {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if err := ci.index.Close(); err != nil {
+		return err
+	}
+	if ci.indexPath == "" {
+		return nil
+	}
+	if err := os.RemoveAll(ci.indexPath); err != nil {
+		return fmt.Errorf("removing discarded index at %s: %w", ci.indexPath, err)
+	}
+	return nil
 }
 
 // GetFileContent returns the raw content of an indexed file.
@@ -142,6 +367,19 @@ func (ci *ContentIndex) GetFileContent(relativePath string) (string, bool) {
 	return content, ok
 }
 
+// FileContents returns a copy of all indexed file contents keyed by relative path.
+// Used to persist the index to a snapshot.
+func (ci *ContentIndex) FileContents() map[string]string {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+
+	contents := make(map[string]string, len(ci.fileContents))
+	for path, content := range ci.fileContents {
+		contents[path] = content
+	}
+	return contents
+}
+
 // Clear removes all documents and recreates the index.
 func (ci *ContentIndex) Clear() error {
 	ci.mu.Lock()
@@ -151,13 +389,13 @@ func (ci *ContentIndex) Clear() error {
 		return fmt.Errorf("closing old index: %w", err)
 	}
 
-	indexMapping := buildIndexMapping()
-	newIndex, err := bleve.NewMemOnly(indexMapping)
+	newIndex, err := newBleveIndex(ci.indexPath)
 	if err != nil {
 		return fmt.Errorf("creating new index: %w", err)
 	}
 
 	ci.index = newIndex
 	ci.fileContents = make(map[string]string)
+	ci.trigrams = newTrigramIndex()
 	return nil
 }
@@ -1,20 +1,61 @@
 package index
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/blevesearch/bleve/v2"
+	unicodetokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
 	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/lexandro/codeindex-mcp/language"
 )
 
+// QueryError reports a malformed search query, such as a regex that does not compile.
+// Callers can detect it with errors.As to distinguish user input errors from index failures.
+type QueryError struct {
+	Query   string
+	Message string
+	// Column is the 1-based byte column in Query where the error was found, or 0 if
+	// the error is not about one position
+	Column int
+	Err    error
+}
+
+func (e *QueryError) Error() string {
+	message := e.Message
+	if e.Column > 0 {
+		message = fmt.Sprintf("%s at column %d", message, e.Column)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", message, e.Err)
+	}
+	return message
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
 // Search performs a full-text search across all indexed files.
-// Query format:
-//   - Plain text: match query (word-level matching)
-//   - "quoted text": phrase query (exact phrase match)
-//   - /regex/: regexp query
-func (ci *ContentIndex) Search(options SearchOptions) ([]ContentSearchResult, int, error) {
+// Query terms (see parseQuery for the grammar):
+//   - Plain text: match query (word-level matching via Bleve)
+//   - "quoted text": exact substring match, case-insensitive (trigram pre-filter)
+//   - /regex/: regular expression (Go regexp syntax, trigram pre-filter, matched line by line)
+//   - lang:, path:, file: and sym: restrict the files; case:yes makes matching case-sensitive
+//
+// Terms are combined with AND (the default), OR, NOT/-term and parentheses. A file matches
+// if it satisfies the whole query; its matching lines are those containing a term that is
+// not negated (see TermMode for the words of plain text). Files are ordered as options.Sort
+// asks; by relevance, files containing more distinct terms rank first (see rank.go). The
+// search stops with ctx.Err() once ctx is done.
+func (ci *ContentIndex) Search(ctx context.Context, options SearchOptions) ([]ContentSearchResult, int, error) {
 	ci.mu.RLock()
 	defer ci.mu.RUnlock()
 
@@ -24,114 +65,785 @@ func (ci *ContentIndex) Search(options SearchOptions) ([]ContentSearchResult, in
 	if options.ContextLines < 0 {
 		options.ContextLines = 0
 	}
+	if options.Sort == "" {
+		options.Sort = SortRelevance
+	}
 
-	bleveQuery := buildQuery(options.Query)
-
-	searchRequest := bleve.NewSearchRequest(bleveQuery)
-	searchRequest.Size = options.MaxResults * 5 // Get more results because we'll filter and group by file
-	searchRequest.Fields = []string{"path", "language"}
+	parsed, err := parseQuery(options.Query)
+	if err != nil {
+		return nil, 0, err
+	}
+	evaluator, err := ci.newQueryEvaluator(ctx, parsed, options)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	searchResults, err := ci.index.Search(searchRequest)
+	candidates, err := evaluator.candidates()
 	if err != nil {
-		return nil, 0, fmt.Errorf("searching index: %w", err)
+		return nil, 0, err
 	}
 
-	// Group results by file and find matching lines
-	resultMap := make(map[string]*ContentSearchResult)
-	var orderedPaths []string
+	// Candidates sorted by path are already in SortPath order, so the search can stop once
+	// it has enough results; any other order needs every matching file.
+	inResultOrder := options.Sort == SortPath && slices.IsSortedFunc(candidates, func(a, b candidate) int {
+		return strings.Compare(a.path, b.path)
+	})
+	var scored []scoredResult
 	totalMatches := 0
 
-	// Normalize FilePath: backslash to forward slash for cross-platform consistency
-	normalizedFilePath := strings.ReplaceAll(options.FilePath, "\\", "/")
+	for i, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		if options.Progress != nil {
+			options.Progress(i, len(candidates))
+		}
+		relativePath := candidate.path
 
-	for _, hit := range searchResults.Hits {
-		relativePath := hit.ID
 		content, ok := ci.fileContents[relativePath]
 		if !ok {
 			continue
 		}
 
-		// Apply file path filter (exact match, overrides FileGlob)
-		if normalizedFilePath != "" {
-			if relativePath != normalizedFilePath {
-				continue
-			}
-		} else if options.FileGlob != "" {
-			// Apply file glob filter if specified
-			normalizedGlob := strings.ReplaceAll(options.FileGlob, "\\", "/")
-			matched, matchErr := doublestar.Match(normalizedGlob, relativePath)
-			if matchErr != nil || !matched {
-				continue
-			}
-		}
-
 		// Find actual matching lines in the content
-		lineMatches := findMatchingLines(content, options.Query, options.ContextLines)
+		lineMatches, err := findMatchingLines(ctx, content, evaluator.lineMatcher(relativePath), options.ContextLines)
+		if err != nil {
+			return nil, 0, err
+		}
 		if len(lineMatches) == 0 {
 			continue
 		}
 
 		totalMatches += len(lineMatches)
 
-		if _, exists := resultMap[relativePath]; !exists {
-			resultMap[relativePath] = &ContentSearchResult{
-				RelativePath: relativePath,
-			}
-			orderedPaths = append(orderedPaths, relativePath)
+		entry := scoredResult{result: ContentSearchResult{
+			RelativePath: relativePath,
+			Matches:      lineMatches,
+			Terms:        evaluator.fileTerms(lineMatches),
+		}}
+		if options.ModTime != nil {
+			entry.modTime = options.ModTime(relativePath)
+		}
+		if options.Sort == SortRelevance {
+			entry.score = evaluator.relevance(&entry.result, content, candidate.score, entry.modTime)
 		}
-		resultMap[relativePath].Matches = append(resultMap[relativePath].Matches, lineMatches...)
+		scored = append(scored, entry)
 
-		if len(orderedPaths) >= options.MaxResults {
+		if inResultOrder && len(scored) >= options.MaxResults {
 			break
 		}
 	}
 
-	results := make([]ContentSearchResult, 0, len(orderedPaths))
-	for _, path := range orderedPaths {
-		results = append(results, *resultMap[path])
+	sortResults(scored, options.Sort)
+	if len(scored) > options.MaxResults {
+		for _, entry := range scored[options.MaxResults:] {
+			totalMatches -= len(entry.result.Matches)
+		}
+		scored = scored[:options.MaxResults]
 	}
 
+	results := make([]ContentSearchResult, 0, len(scored))
+	for _, entry := range scored {
+		results = append(results, entry.result)
+	}
 	return results, totalMatches, nil
 }
 
-// buildQuery parses the query string into a Bleve query.
-func buildQuery(queryString string) query.Query {
-	queryString = strings.TrimSpace(queryString)
+// FilesContaining returns the sorted relative paths of all files whose content contains
+// literal (case-sensitive). The trigram index narrows the files that need to be checked.
+func (ci *ContentIndex) FilesContaining(literal string) []string {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+
+	var paths []string
+	for _, relativePath := range ci.trigrams.candidates(literalTrigramQuery(literal)) {
+		if strings.Contains(ci.fileContents[relativePath], literal) {
+			paths = append(paths, relativePath)
+		}
+	}
+	return paths
+}
+
+// queryEvaluator resolves a parsed query against the index for one search. The documents
+// of all terms except plain text are computed up front; plain text is left to Bleve.
+// Caller must hold ci.mu.
+type queryEvaluator struct {
+	ci      *ContentIndex
+	ctx     context.Context
+	query   *parsedQuery
+	options SearchOptions
+	// caseSensitive is the case setting of the query, or else of the options
+	caseSensitive bool
+
+	// documents holds the files matching each phrase, regex, lang:, path:, file: and sym: term
+	documents map[*queryNode]map[string]bool
+	// regexes holds the pattern of each regex term, enclosed in word boundaries for WholeWord
+	regexes map[*queryNode]*regexp.Regexp
+	// groups holds the terms that are not negated, as they are found within lines
+	groups []*termGroup
+	// termNames lists the distinct names of the terms in groups in query order
+	termNames []string
+	// definitions holds the lines defining the symbols of sym: terms by file
+	definitions map[string]map[int]bool
+}
+
+// newQueryEvaluator prepares the evaluation of parsed. It fails with a QueryError if the query
+// has no term that can match a line, since every result is a list of matching lines.
+func (ci *ContentIndex) newQueryEvaluator(ctx context.Context, parsed *parsedQuery, options SearchOptions) (*queryEvaluator, error) {
+	e := &queryEvaluator{
+		ci:        ci,
+		ctx:       ctx,
+		query:     parsed,
+		options:   options,
+		documents: make(map[*queryNode]map[string]bool),
+		regexes:   make(map[*queryNode]*regexp.Regexp),
+	}
+	e.caseSensitive = options.CaseSensitive
+	if parsed.caseSensitive != nil {
+		e.caseSensitive = *parsed.caseSensitive
+	}
+	switch options.Terms {
+	case "", TermsAny, TermsAll, TermsLine:
+	default:
+		return nil, &QueryError{Query: options.Query, Message: fmt.Sprintf("unknown terms mode %q (expected any, all or line)", options.Terms)}
+	}
+	switch options.Sort {
+	case SortRelevance, SortPath, SortRecent:
+	default:
+		return nil, &QueryError{Query: options.Query, Message: fmt.Sprintf("unknown sort order %q (expected relevance, path or recent)", options.Sort)}
+	}
+	if err := e.prepare(parsed.root, true); err != nil {
+		return nil, err
+	}
+	if len(e.groups) == 0 {
+		return nil, &QueryError{Query: options.Query, Message: "query needs a word, phrase, regex or sym: term that is not negated"}
+	}
+	return e, nil
+}
+
+// prepare computes the documents of the terms below node and collects the line patterns of
+// the terms that are not negated (positive).
+func (e *queryEvaluator) prepare(node *queryNode, positive bool) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	switch node.kind {
+	case queryAnd, queryOr:
+		for _, child := range node.children {
+			if err := e.prepare(child, positive); err != nil {
+				return err
+			}
+		}
+		return nil
+	case queryNot:
+		return e.prepare(node.children[0], !positive)
+	case queryText:
+		group := &termGroup{allOnLine: e.options.Terms == TermsLine}
+		for _, word := range queryWords(node.value) {
+			group.terms = append(group.terms, lineTerm{name: word, pattern: e.literalPattern(word)})
+		}
+		if positive {
+			e.addGroup(group)
+		} else {
+			// Negated text is excluded where it would match, not by Bleve, which would also
+			// exclude files containing only one of the sub-words of an identifier
+			e.documents[node] = e.textDocuments(group)
+		}
+		return nil
+	case queryPhrase:
+		pattern := e.literalPattern(node.value)
+		if positive {
+			e.addGroup(&termGroup{terms: []lineTerm{{name: `"` + node.value + `"`, pattern: pattern}}})
+		}
+		e.documents[node] = e.phraseDocuments(node.value, pattern)
+		return nil
+	case queryRegex:
+		e.regexes[node] = node.regex
+		if e.options.WholeWord {
+			re, err := wholeWordRegex(node.value)
+			if err != nil {
+				return &QueryError{Query: e.options.Query, Message: "invalid regex", Column: node.column, Err: err}
+			}
+			e.regexes[node] = re
+		}
+		if positive {
+			e.addGroup(&termGroup{terms: []lineTerm{{name: "/" + node.value + "/", pattern: e.regexes[node]}}})
+		}
+		documents, err := e.regexDocuments(node)
+		e.documents[node] = documents
+		return err
+	case querySymbol:
+		if e.options.SymbolDefinitions == nil {
+			return &QueryError{Query: e.options.Query, Message: "sym: is not available", Column: node.column}
+		}
+		pattern := regexp.MustCompile(e.caseFlag() + `\b` + regexp.QuoteMeta(node.value) + `\b`)
+		if positive {
+			e.addGroup(&termGroup{terms: []lineTerm{{name: "sym:" + node.value, pattern: pattern, definitionsOnly: true}}})
+		}
+		e.documents[node] = e.symbolDocuments(node.value, pattern, positive)
+		return nil
+	default:
+		e.documents[node] = e.pathDocuments(node)
+		return nil
+	}
+}
+
+// addGroup adds the terms of a node that is not negated to the line matcher.
+func (e *queryEvaluator) addGroup(group *termGroup) {
+	e.groups = append(e.groups, group)
+	for _, term := range group.terms {
+		if !slices.Contains(e.termNames, term.name) {
+			e.termNames = append(e.termNames, term.name)
+		}
+	}
+}
 
-	// Regex query: /pattern/
-	if strings.HasPrefix(queryString, "/") && strings.HasSuffix(queryString, "/") && len(queryString) > 2 {
-		regexPattern := queryString[1 : len(queryString)-1]
-		return bleve.NewRegexpQuery(regexPattern)
+// literalPattern compiles literal text with the case and whole word settings of the search.
+func (e *queryEvaluator) literalPattern(text string) *regexp.Regexp {
+	return regexp.MustCompile(e.caseFlag() + e.wordBoundaries(regexp.QuoteMeta(text), text))
+}
+
+// caseFlag is the regexp flag prefix for words, phrases and symbol names.
+func (e *queryEvaluator) caseFlag() string {
+	if e.caseSensitive {
+		return ""
 	}
+	return "(?i)"
+}
 
-	// Phrase query: "exact phrase"
-	if strings.HasPrefix(queryString, "\"") && strings.HasSuffix(queryString, "\"") && len(queryString) > 2 {
-		phrase := queryString[1 : len(queryString)-1]
-		return bleve.NewMatchPhraseQuery(phrase)
+// wordBoundaries encloses pattern, the quoted form of the literal text, in \b where text
+// begins or ends with a word character, if the search is for whole words.
+func (e *queryEvaluator) wordBoundaries(pattern string, text string) string {
+	if !e.options.WholeWord {
+		return pattern
+	}
+	if isWordByte(text[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordByte(text[len(text)-1]) {
+		pattern += `\b`
 	}
+	return pattern
+}
 
-	// Default: match query (word-level)
-	return bleve.NewMatchQuery(queryString)
+// wholeWordRegex compiles pattern enclosed in \b word boundaries. The boundaries are added
+// to the parsed pattern rather than its text, which \Q...\E or a trailing \ could change.
+func wholeWordRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	boundary := &syntax.Regexp{Op: syntax.OpWordBoundary}
+	wrapped := &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{boundary, re, boundary}}
+	return regexp.Compile(wrapped.String())
+}
+
+// isWordByte reports whether c is a word character as matched by \w and \b.
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// phraseDocuments returns the files containing the phrase. The trigram index narrows the
+// files to check; pattern is the phrase compiled with the case setting of the query.
+func (e *queryEvaluator) phraseDocuments(phrase string, pattern *regexp.Regexp) map[string]bool {
+	documents := make(map[string]bool)
+	for _, relativePath := range e.ci.trigrams.candidates(literalTrigramQuery(phrase)) {
+		if pattern.MatchString(e.ci.fileContents[relativePath]) {
+			documents[relativePath] = true
+		}
+	}
+	return documents
+}
+
+// textDocuments returns the files in which the words of group occur as the terms mode
+// requires: any of them (TermsAny), all of them (TermsAll) or all on one line (TermsLine).
+func (e *queryEvaluator) textDocuments(group *termGroup) map[string]bool {
+	trigramQuery := &trigramQuery{op: trigramOpOr}
+	if e.options.Terms == TermsAll || e.options.Terms == TermsLine {
+		trigramQuery.op = trigramOpAnd
+	}
+	for _, term := range group.terms {
+		termQuery := literalTrigramQuery(term.name)
+		if trigramQuery.op == trigramOpAnd {
+			addAndOperand(trigramQuery, termQuery)
+		} else if termQuery.op == trigramOpAll {
+			trigramQuery = matchAllTrigramQuery
+			break
+		} else {
+			trigramQuery.subs = append(trigramQuery.subs, termQuery)
+		}
+	}
+
+	matcher := &lineMatcher{groups: []*termGroup{group}}
+	documents := make(map[string]bool)
+	for _, relativePath := range e.ci.trigrams.candidates(trigramQuery) {
+		content := e.ci.fileContents[relativePath]
+		if e.options.Terms == TermsAll {
+			if !slices.ContainsFunc(group.terms, func(term lineTerm) bool { return !term.pattern.MatchString(content) }) {
+				documents[relativePath] = true
+			}
+			continue
+		}
+		for line := range strings.SplitSeq(content, "\n") {
+			if _, _, matched := matcher.match(0, line); matched {
+				documents[relativePath] = true
+				break
+			}
+		}
+	}
+	return documents
+}
+
+// regexDocuments returns the files with a line matching the regex of node.
+func (e *queryEvaluator) regexDocuments(node *queryNode) (map[string]bool, error) {
+	trigramQuery, err := regexpTrigramQuery(node.value)
+	if err != nil {
+		return nil, &QueryError{Query: e.options.Query, Message: "invalid regex", Column: node.column, Err: err}
+	}
+	documents := make(map[string]bool)
+	for _, relativePath := range e.ci.trigrams.candidates(trigramQuery) {
+		for line := range strings.SplitSeq(e.ci.fileContents[relativePath], "\n") {
+			if e.regexes[node].MatchString(line) {
+				documents[relativePath] = true
+				break
+			}
+		}
+	}
+	return documents, nil
+}
+
+// symbolDocuments returns the files defining a symbol named name on a line matching pattern.
+// The definition lines of positive terms are recorded for line matching.
+func (e *queryEvaluator) symbolDocuments(name string, pattern *regexp.Regexp, positive bool) map[string]bool {
+	documents := make(map[string]bool)
+	for relativePath, lineNumbers := range e.options.SymbolDefinitions(name) {
+		content, ok := e.ci.fileContents[relativePath]
+		if !ok {
+			continue
+		}
+		lines := strings.Split(content, "\n")
+		for _, lineNumber := range lineNumbers {
+			if lineNumber < 1 || lineNumber > len(lines) || !pattern.MatchString(lines[lineNumber-1]) {
+				continue
+			}
+			documents[relativePath] = true
+			if !positive {
+				break
+			}
+			if e.definitions == nil {
+				e.definitions = make(map[string]map[int]bool)
+			}
+			if e.definitions[relativePath] == nil {
+				e.definitions[relativePath] = make(map[int]bool)
+			}
+			e.definitions[relativePath][lineNumber] = true
+		}
+	}
+	return documents
+}
+
+// pathDocuments returns the files matching a lang:, path: or file: term.
+func (e *queryEvaluator) pathDocuments(node *queryNode) map[string]bool {
+	documents := make(map[string]bool)
+	for relativePath := range e.ci.fileContents {
+		if matchesPathTerm(node, relativePath) {
+			documents[relativePath] = true
+		}
+	}
+	return documents
 }
 
-// findMatchingLines searches content line by line for the query terms.
-// Returns LineMatch entries with context lines.
-func findMatchingLines(content string, queryString string, contextLines int) []LineMatch {
+// matchesPathTerm reports whether the file at relativePath satisfies a lang:, path: or file: term.
+//   - lang: the language name (ignoring case) or a file extension of the language: lang:go, lang:ts
+//   - path: a substring of the path, ignoring case: path:internal/
+//   - file: a glob matched against the path, or against the file name if it has no '/': file:*_test.go
+func matchesPathTerm(node *queryNode, relativePath string) bool {
+	switch node.kind {
+	case queryLang:
+		fileLanguage := language.DetectLanguage(relativePath)
+		if strings.EqualFold(fileLanguage, node.value) {
+			return true
+		}
+		extensionLanguage, ok := language.ExtensionToLanguage[strings.ToLower(node.value)]
+		return ok && extensionLanguage == fileLanguage
+	case queryPath:
+		return strings.Contains(strings.ToLower(relativePath), strings.ToLower(node.value))
+	case queryFile:
+		name := relativePath
+		if !strings.Contains(node.value, "/") {
+			name = relativePath[strings.LastIndex(relativePath, "/")+1:]
+		}
+		matched, _ := doublestar.Match(node.value, name)
+		return matched
+	}
+	return false
+}
+
+// candidate is a file that may match the query, with its Bleve score (1 without Bleve).
+type candidate struct {
+	path  string
+	score float64
+}
+
+// minCandidatePool is the least number of Bleve hits a search considers, so that files
+// Bleve scores low but the relevance ranking favors (definitions, file names) are seen.
+const minCandidatePool = 200
+
+// candidates returns the files matching the query within the FilePath or FileGlob scope.
+// Queries with plain text that is not negated are answered by Bleve in score order (at most
+// MaxResults*10 or minCandidatePool files, as results are verified and ranked afterwards);
+// the other terms and the scope take part as document ID sets, so files outside the scope
+// cannot crowd out those inside. Queries without such text are evaluated on the term
+// documents and returned sorted by path. Candidates are verified line by line.
+func (e *queryEvaluator) candidates() ([]candidate, error) {
+	scope := e.scope()
+	if !hasPositiveText(e.query.root, true) {
+		documents, err := e.evaluate(e.query.root)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			for relativePath := range documents {
+				if !scope[relativePath] {
+					delete(documents, relativePath)
+				}
+			}
+		}
+		paths := sortedPaths(documents)
+		candidates := make([]candidate, 0, len(paths))
+		for _, path := range paths {
+			candidates = append(candidates, candidate{path: path, score: 1})
+		}
+		return candidates, nil
+	}
+
+	bleveQuery := e.bleveQuery(e.query.root)
+	if scope != nil {
+		bleveQuery = query.NewBooleanQuery([]query.Query{bleveQuery, bleve.NewDocIDQuery(sortedPaths(scope))}, nil, nil)
+	}
+	searchRequest := bleve.NewSearchRequest(bleveQuery)
+	searchRequest.Size = max(e.options.MaxResults*10, minCandidatePool)
+	searchRequest.Fields = []string{"path", "language"}
+	return e.search(searchRequest)
+}
+
+// scope returns the documents the FilePath (exact, overrides FileGlob) or FileGlob option
+// restricts the search to, or nil if neither is set. Backslashes count as slashes.
+func (e *queryEvaluator) scope() map[string]bool {
+	filePath := strings.ReplaceAll(e.options.FilePath, "\\", "/")
+	glob := strings.ReplaceAll(e.options.FileGlob, "\\", "/")
+	if filePath == "" && glob == "" {
+		return nil
+	}
+	documents := make(map[string]bool)
+	if filePath != "" {
+		if _, ok := e.ci.fileContents[filePath]; ok {
+			documents[filePath] = true
+		}
+		return documents
+	}
+	for relativePath := range e.ci.fileContents {
+		if matched, err := doublestar.Match(glob, relativePath); err == nil && matched {
+			documents[relativePath] = true
+		}
+	}
+	return documents
+}
+
+// search runs a Bleve request and returns its hits.
+func (e *queryEvaluator) search(searchRequest *bleve.SearchRequest) ([]candidate, error) {
+	searchResults, err := e.ci.index.SearchInContext(e.ctx, searchRequest)
+	if ctxErr := e.ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("searching index: %w", err)
+	}
+
+	candidates := make([]candidate, 0, len(searchResults.Hits))
+	for _, hit := range searchResults.Hits {
+		candidates = append(candidates, candidate{path: hit.ID, score: hit.Score})
+	}
+	return candidates, nil
+}
+
+// bleveQuery converts node into a Bleve query. Plain text that is not negated becomes a
+// match query; the other terms match the documents computed by prepare.
+func (e *queryEvaluator) bleveQuery(node *queryNode) query.Query {
+	switch node.kind {
+	case queryAnd:
+		var must, mustNot []query.Query
+		for _, child := range node.children {
+			if child.kind == queryNot {
+				mustNot = append(mustNot, e.bleveQuery(child.children[0]))
+			} else {
+				must = append(must, e.bleveQuery(child))
+			}
+		}
+		return query.NewBooleanQuery(must, nil, mustNot)
+	case queryOr:
+		disjuncts := make([]query.Query, 0, len(node.children))
+		for _, child := range node.children {
+			disjuncts = append(disjuncts, e.bleveQuery(child))
+		}
+		return bleve.NewDisjunctionQuery(disjuncts...)
+	case queryNot:
+		// A boolean query with only excluded clauses matches all other documents
+		return query.NewBooleanQuery(nil, nil, []query.Query{e.bleveQuery(node.children[0])})
+	case queryText:
+		if _, negated := e.documents[node]; !negated {
+			matchQuery := bleve.NewMatchQuery(node.value)
+			if e.options.Terms == TermsAll || e.options.Terms == TermsLine {
+				matchQuery.SetOperator(query.MatchQueryOperatorAnd)
+			}
+			if e.caseSensitive {
+				matchQuery.SetField(casedContentField)
+				matchQuery.Analyzer = CasedCodeAnalyzerName
+			}
+			return matchQuery
+		}
+	}
+	return bleve.NewDocIDQuery(sortedPaths(e.documents[node]))
+}
+
+// evaluate returns the documents matching node. Plain text only occurs negated here, so
+// prepare has computed its documents like those of a phrase.
+func (e *queryEvaluator) evaluate(node *queryNode) (map[string]bool, error) {
+	switch node.kind {
+	case queryAnd:
+		var result map[string]bool // nil = all documents
+		for _, child := range node.children {
+			negated := child.kind == queryNot
+			if negated {
+				child = child.children[0]
+			}
+			documents, err := e.evaluate(child)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				if !negated {
+					result = maps.Clone(documents)
+					continue
+				}
+				result = e.allDocuments()
+			}
+			for relativePath := range result {
+				if documents[relativePath] == negated {
+					delete(result, relativePath)
+				}
+			}
+		}
+		return result, nil
+	case queryOr:
+		result := make(map[string]bool)
+		for _, child := range node.children {
+			documents, err := e.evaluate(child)
+			if err != nil {
+				return nil, err
+			}
+			for relativePath := range documents {
+				result[relativePath] = true
+			}
+		}
+		return result, nil
+	case queryNot:
+		documents, err := e.evaluate(node.children[0])
+		if err != nil {
+			return nil, err
+		}
+		result := e.allDocuments()
+		for relativePath := range documents {
+			delete(result, relativePath)
+		}
+		return result, nil
+	}
+	return e.documents[node], nil
+}
+
+// allDocuments returns a new set of all indexed files.
+func (e *queryEvaluator) allDocuments() map[string]bool {
+	documents := make(map[string]bool, len(e.ci.fileContents))
+	for relativePath := range e.ci.fileContents {
+		documents[relativePath] = true
+	}
+	return documents
+}
+
+// lineMatcher returns the matcher for the lines of the file at relativePath.
+func (e *queryEvaluator) lineMatcher(relativePath string) *lineMatcher {
+	return &lineMatcher{
+		groups:          e.groups,
+		definitionLines: e.definitions[relativePath],
+	}
+}
+
+// fileTerms returns the distinct terms found on the matching lines of a file, in query order.
+func (e *queryEvaluator) fileTerms(matches []LineMatch) []string {
+	found := make(map[string]bool)
+	for _, match := range matches {
+		for _, term := range match.Terms {
+			found[term] = true
+		}
+	}
+	terms := make([]string, 0, len(found))
+	for _, name := range e.termNames {
+		if found[name] {
+			terms = append(terms, name)
+		}
+	}
+	return terms
+}
+
+// hasPositiveText reports whether node contains plain text that is not negated.
+func hasPositiveText(node *queryNode, positive bool) bool {
+	switch node.kind {
+	case queryText:
+		return positive
+	case queryNot:
+		return hasPositiveText(node.children[0], !positive)
+	}
+	for _, child := range node.children {
+		if hasPositiveText(child, positive) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPaths returns the paths of a document set in sorted order.
+func sortedPaths(documents map[string]bool) []string {
+	paths := make([]string, 0, len(documents))
+	for relativePath := range documents {
+		paths = append(paths, relativePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// lineTerm is a term of the query as it is found within lines.
+type lineTerm struct {
+	// name is the term as written in the query, reported in LineMatch.Terms
+	name    string
+	pattern *regexp.Regexp
+	// definitionsOnly restricts a sym: term to the lines defining its symbol
+	definitionsOnly bool
+}
+
+// termGroup holds the terms of one query node: the words of plain text, or a single
+// phrase, regex or sym: term.
+type termGroup struct {
+	terms []lineTerm
+	// allOnLine only matches lines containing every term (TermsLine)
+	allOnLine bool
+}
+
+// lineMatcher finds the terms of a query in the lines of one file.
+type lineMatcher struct {
+	groups []*termGroup
+	// definitionLines are the lines of the file where sym: terms can match
+	definitionLines map[int]bool
+}
+
+// match returns the matched byte ranges of line, sorted and with overlapping ranges merged,
+// the names of the terms found, and whether the line matches at all (a regex can match an
+// empty range).
+func (m *lineMatcher) match(lineNumber int, line string) ([]MatchSpan, []string, bool) {
+	var spans []MatchSpan
+	var terms []string
+	matched := false
+	for _, group := range m.groups {
+		var groupSpans []MatchSpan
+		var groupTerms []string
+		for _, term := range group.terms {
+			var locations [][]int
+			if !term.definitionsOnly || m.definitionLines[lineNumber] {
+				locations = term.pattern.FindAllStringIndex(line, -1)
+			}
+			if len(locations) == 0 {
+				if group.allOnLine {
+					groupTerms = nil
+					break
+				}
+				continue
+			}
+			for _, loc := range locations {
+				groupSpans = append(groupSpans, MatchSpan{Start: loc[0], End: loc[1]})
+			}
+			groupTerms = append(groupTerms, term.name)
+		}
+		if len(groupTerms) == 0 {
+			continue
+		}
+		matched = true
+		spans = append(spans, groupSpans...)
+		for _, name := range groupTerms {
+			if !slices.Contains(terms, name) {
+				terms = append(terms, name)
+			}
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Start != spans[j].Start {
+			return spans[i].Start < spans[j].Start
+		}
+		return spans[i].End > spans[j].End
+	})
+	merged := spans[:0]
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.Start < merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged, terms, matched
+}
+
+// queryWords splits plain text into the words Bleve searches for, before identifiers are
+// split into sub-words: "user session_timeout" -> user, session_timeout. Each word is
+// matched as a substring of a line. Text without any word is a single word.
+func queryWords(text string) []string {
+	var words []string
+	for _, token := range unicodetokenizer.NewUnicodeTokenizer().Tokenize([]byte(text)) {
+		word := string(token.Term)
+		if !slices.ContainsFunc(words, func(w string) bool { return strings.EqualFold(w, word) }) {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return []string{text}
+	}
+	return words
+}
+
+// cancelCheckLines is how many lines findMatchingLines scans between checks of its context.
+const cancelCheckLines = 1024
+
+// findMatchingLines searches content line by line using the matcher.
+// Returns LineMatch entries with match spans and context lines, or ctx.Err() once ctx is done.
+func findMatchingLines(ctx context.Context, content string, matcher *lineMatcher, contextLines int) ([]LineMatch, error) {
 	lines := strings.Split(content, "\n")
-	searchTerm := extractSearchTerm(queryString)
-	searchTermLower := strings.ToLower(searchTerm)
 
 	var matches []LineMatch
 
 	for lineIdx, line := range lines {
-		lineLower := strings.ToLower(line)
-		if !strings.Contains(lineLower, searchTermLower) {
+		if lineIdx%cancelCheckLines == cancelCheckLines-1 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		spans, terms, matched := matcher.match(lineIdx+1, line)
+		if !matched {
 			continue
 		}
 
 		match := LineMatch{
 			LineNumber: lineIdx + 1, // 1-based
 			LineText:   line,
+			Spans:      spans,
+			Terms:      terms,
 		}
 
 		// Gather context lines before
@@ -159,22 +871,5 @@ func findMatchingLines(content string, queryString string, contextLines int) []L
 		matches = append(matches, match)
 	}
 
-	return matches
-}
-
-// extractSearchTerm strips query syntax to get the raw search term for line matching.
-func extractSearchTerm(queryString string) string {
-	queryString = strings.TrimSpace(queryString)
-
-	// Strip regex delimiters
-	if strings.HasPrefix(queryString, "/") && strings.HasSuffix(queryString, "/") && len(queryString) > 2 {
-		return queryString[1 : len(queryString)-1]
-	}
-
-	// Strip phrase quotes
-	if strings.HasPrefix(queryString, "\"") && strings.HasSuffix(queryString, "\"") && len(queryString) > 2 {
-		return queryString[1 : len(queryString)-1]
-	}
-
-	return queryString
+	return matches, nil
 }
@@ -1,7 +1,18 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
 )
 
 func newTestContentIndex(t *testing.T) *ContentIndex {
@@ -28,7 +39,7 @@ func main() {
 		t.Fatalf("failed to index file: %v", err)
 	}
 
-	results, totalMatches, err := ci.Search(SearchOptions{
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{
 		Query:      "hello",
 		MaxResults: 10,
 	})
@@ -56,7 +67,7 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("hello world"))
 }`, "Go")
 
-	results, _, err := ci.Search(SearchOptions{
+	results, _, err := ci.Search(context.Background(), SearchOptions{
 		Query:      `"hello world"`,
 		MaxResults: 10,
 	})
@@ -78,7 +89,7 @@ line3 target
 line4
 line5`, "Go")
 
-	results, _, err := ci.Search(SearchOptions{
+	results, _, err := ci.Search(context.Background(), SearchOptions{
 		Query:        "target",
 		MaxResults:   10,
 		ContextLines: 1,
@@ -109,7 +120,7 @@ func Test_ContentIndex_SearchWithFileGlob(t *testing.T) {
 	ci.IndexFile("main.go", "hello from Go", "Go")
 	ci.IndexFile("app.ts", "hello from TypeScript", "TypeScript")
 
-	results, _, err := ci.Search(SearchOptions{
+	results, _, err := ci.Search(context.Background(), SearchOptions{
 		Query:      "hello",
 		FileGlob:   "*.go",
 		MaxResults: 10,
@@ -137,6 +148,36 @@ func Test_ContentIndex_RemoveFile(t *testing.T) {
 	}
 }
 
+func Test_ContentIndex_IndexBatch(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("a.go", "func oldName() {}", "Go")
+	err := ci.IndexBatch([]Document{
+		{RelativePath: "a.go", Content: "func newName() {}", Language: "Go"},
+		{RelativePath: "b.go", Content: "func other() {}", Language: "Go"},
+	})
+	if err != nil {
+		t.Fatalf("batch error: %v", err)
+	}
+
+	if ci.DocumentCount() != 2 {
+		t.Errorf("expected 2 documents, got %d", ci.DocumentCount())
+	}
+	if content, _ := ci.GetFileContent("a.go"); content != "func newName() {}" {
+		t.Errorf("expected updated content, got %q", content)
+	}
+	for query, want := range map[string]int{"oldName": 0, "newName": 1, "other": 1} {
+		results, _, err := ci.Search(context.Background(), SearchOptions{Query: query, MaxResults: 10})
+		if err != nil {
+			t.Fatalf("search error: %v", err)
+		}
+		if len(results) != want {
+			t.Errorf("%s: expected %d results, got %d", query, want, len(results))
+		}
+	}
+}
+
 func Test_ContentIndex_Clear(t *testing.T) {
 	ci := newTestContentIndex(t)
 	defer ci.Close()
@@ -162,7 +203,7 @@ func Test_ContentIndex_SearchWithFilePath(t *testing.T) {
 	ci.IndexFile("app.go", "hello from app", "Go")
 	ci.IndexFile("lib/util.go", "hello from util", "Go")
 
-	results, _, err := ci.Search(SearchOptions{
+	results, _, err := ci.Search(context.Background(), SearchOptions{
 		Query:    "hello",
 		FilePath: "app.go",
 	})
@@ -185,7 +226,7 @@ func Test_ContentIndex_SearchWithFilePath_PrecedenceOverFileGlob(t *testing.T) {
 	ci.IndexFile("app.ts", "hello from app", "TypeScript")
 
 	// FilePath should override FileGlob — search app.ts even though glob says *.go
-	results, _, err := ci.Search(SearchOptions{
+	results, _, err := ci.Search(context.Background(), SearchOptions{
 		Query:    "hello",
 		FilePath: "app.ts",
 		FileGlob: "*.go",
@@ -207,7 +248,7 @@ func Test_ContentIndex_SearchWithFilePath_NotFound(t *testing.T) {
 
 	ci.IndexFile("main.go", "hello from main", "Go")
 
-	results, totalMatches, err := ci.Search(SearchOptions{
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{
 		Query:    "hello",
 		FilePath: "nonexistent.go",
 	})
@@ -259,3 +300,936 @@ func Test_ContentIndex_DocumentCount(t *testing.T) {
 		t.Errorf("expected 2 documents, got %d", ci.DocumentCount())
 	}
 }
+
+func Test_ContentIndex_PersistentReopen(t *testing.T) {
+	storagePath := t.TempDir() + "/bleve"
+
+	ci, err := NewPersistentContentIndex(storagePath)
+	if err != nil {
+		t.Fatalf("failed to create persistent index: %v", err)
+	}
+	ci.IndexFile("main.go", "hello persistent world", "Go")
+	contents := ci.FileContents()
+	ci.Close()
+
+	reopened, err := OpenPersistentContentIndex(storagePath, contents)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer reopened.Close()
+
+	results, _, err := reopened.Search(context.Background(), SearchOptions{Query: "persistent"})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result after reopen, got %d", len(results))
+	}
+}
+
+func Test_ContentIndex_SwapGeneration(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "bleve")
+	ci, err := NewPersistentContentIndex(storagePath)
+	if err != nil {
+		t.Fatalf("failed to create persistent index: %v", err)
+	}
+	ci.IndexFile("old.go", "previous generation", "Go")
+
+	// Rebuild twice, so the live index moves to the sibling directory and back
+	for _, name := range []string{"first.go", "second.go"} {
+		next, err := ci.NewGeneration()
+		if err != nil {
+			t.Fatalf("failed to create generation: %v", err)
+		}
+		next.IndexFile(name, "generation "+name, "Go")
+
+		// Queries hit the live generation until the swap
+		if _, ok := ci.GetFileContent(name); ok {
+			t.Fatalf("%s visible before the swap", name)
+		}
+		if err := ci.Swap(next).Discard(); err != nil {
+			t.Fatalf("discard error: %v", err)
+		}
+		if _, ok := ci.GetFileContent(name); !ok || ci.DocumentCount() != 1 {
+			t.Fatalf("expected only %s after the swap, got %d documents", name, ci.DocumentCount())
+		}
+		results, _, err := ci.Search(context.Background(), SearchOptions{Query: "generation"})
+		if err != nil || len(results) != 1 || results[0].RelativePath != name {
+			t.Fatalf("expected the new generation to be searchable, got %+v (err %v)", results, err)
+		}
+	}
+
+	// The third generation is built in the sibling directory; Close moves it to the storage path
+	next, _ := ci.NewGeneration()
+	next.IndexFile("third.go", "generation third", "Go")
+	ci.Swap(next).Discard()
+	contents := ci.FileContents()
+	if err := ci.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+	if _, err := os.Stat(storagePath + ".next"); !os.IsNotExist(err) {
+		t.Errorf("expected the sibling directory to be gone, got %v", err)
+	}
+	reopened, err := OpenPersistentContentIndex(storagePath, contents)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer reopened.Close()
+	if results, _, _ := reopened.Search(context.Background(), SearchOptions{Query: "third"}); len(results) != 1 {
+		t.Errorf("expected the last generation at the storage path, got %d results", len(results))
+	}
+}
+
+func Test_ContentIndex_DiscardGeneration(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "bleve")
+	ci, err := NewPersistentContentIndex(storagePath)
+	if err != nil {
+		t.Fatalf("failed to create persistent index: %v", err)
+	}
+	defer ci.Close()
+	ci.IndexFile("live.go", "live generation", "Go")
+
+	next, _ := ci.NewGeneration()
+	next.IndexFile("abandoned.go", "abandoned generation", "Go")
+	if err := next.Discard(); err != nil {
+		t.Fatalf("discard error: %v", err)
+	}
+
+	if _, err := os.Stat(storagePath + ".next"); !os.IsNotExist(err) {
+		t.Errorf("expected the discarded generation to be deleted, got %v", err)
+	}
+	if _, ok := ci.GetFileContent("live.go"); !ok || ci.DocumentCount() != 1 {
+		t.Errorf("expected the live generation to be untouched, got %d documents", ci.DocumentCount())
+	}
+}
+
+func Test_ContentIndex_OpenPersistent_MismatchedContents(t *testing.T) {
+	storagePath := t.TempDir() + "/bleve"
+
+	ci, _ := NewPersistentContentIndex(storagePath)
+	ci.IndexFile("main.go", "hello", "Go")
+	ci.Close()
+
+	_, err := OpenPersistentContentIndex(storagePath, map[string]string{})
+	if err == nil {
+		t.Error("expected error when contents do not match the index")
+	}
+}
+
+func Test_ContentIndex_RegexSearch_AcrossWhitespace(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("handlers.go", `package api
+
+func UserHandler(w http.ResponseWriter) {}
+
+func helper() {}
+
+func  OrderHandler(w http.ResponseWriter) {}`, "Go")
+
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{Query: `/func\s+\w+Handler/`})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if totalMatches != 2 {
+		t.Fatalf("expected 2 matching lines, got %d", totalMatches)
+	}
+	if results[0].Matches[0].LineNumber != 3 || results[0].Matches[1].LineNumber != 7 {
+		t.Errorf("expected lines 3 and 7, got %d and %d",
+			results[0].Matches[0].LineNumber, results[0].Matches[1].LineNumber)
+	}
+}
+
+func Test_ContentIndex_RegexSearch_Spans(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("ids.go", "a := id1 + id22", "Go")
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: `/id\d+/`})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	spans := results[0].Matches[0].Spans
+	expected := []MatchSpan{{Start: 5, End: 8}, {Start: 11, End: 15}}
+	if len(spans) != len(expected) {
+		t.Fatalf("expected %d spans, got %d", len(expected), len(spans))
+	}
+	for i, span := range spans {
+		if span != expected[i] {
+			t.Errorf("span %d: expected %+v, got %+v", i, expected[i], span)
+		}
+	}
+}
+
+func Test_ContentIndex_RegexSearch_InvalidPattern(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("main.go", "package main", "Go")
+
+	_, _, err := ci.Search(context.Background(), SearchOptions{Query: `/func(/`})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("expected QueryError, got %v", err)
+	}
+}
+
+func Test_ContentIndex_PlainSearch_SpansIgnoreCase(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("main.go", "Hello hello", "Go")
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: "hello"})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if len(results[0].Matches[0].Spans) != 2 {
+		t.Errorf("expected 2 spans, got %d", len(results[0].Matches[0].Spans))
+	}
+}
+
+// analyzeTerms runs the code analyzer over text and returns the emitted terms.
+func analyzeTerms(t *testing.T, text string) []string {
+	t.Helper()
+	tokens, err := buildIndexMapping().AnalyzeText(CodeAnalyzerName, []byte(text))
+	if err != nil {
+		t.Fatalf("analyze error: %v", err)
+	}
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, string(token.Term))
+	}
+	return terms
+}
+
+func Test_CodeAnalyzer_SplitsIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"camelCase", "getUserByID", []string{"getuserbyid", "get", "user", "by", "id"}},
+		{"PascalCase acronym", "HTTPServer", []string{"httpserver", "http", "server"}},
+		{"snake_case", "max_file_size", []string{"max_file_size", "max", "file", "size"}},
+		{"kebab-case", "codeindex-mcp", []string{"codeindex-mcp", "codeindex", "mcp"}},
+		{"dunder", "__init__", []string{"__init__", "init"}},
+		{"leading underscore", "_privateName", []string{"_privatename", "private", "name"}},
+		{"only a leading underscore", "_foo", []string{"_foo", "foo"}},
+		{"trailing underscore", "name_", []string{"name_", "name"}},
+		{"simple word", "config", []string{"config"}},
+		{"digits stay attached", "utf8Decode", []string{"utf8decode", "utf8", "decode"}},
+		{"no stop words", "if err != nil", []string{"if", "err", "nil"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := analyzeTerms(t, tt.input)
+			if strings.Join(terms, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, terms)
+			}
+		})
+	}
+}
+
+func Test_CodeAnalyzer_Punctuation(t *testing.T) {
+	terms := analyzeTerms(t, "foo.bar() a::b")
+	expected := []string{"foo.bar", "foo", "bar", "a", "b"}
+	if strings.Join(terms, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, terms)
+	}
+}
+
+func Test_CodeAnalyzer_SubWordSearchAcrossLanguages(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("user.go", "func getUserByID(id string) (*User, error) {}", "Go")
+	ci.IndexFile("user.py", "def load_user_profile(user_id):\n    pass", "Python")
+	ci.IndexFile("user.ts", "export class UserSessionStore {}", "TypeScript")
+	ci.IndexFile("user.rs", "impl Repo { fn find_by_email(&self) {} }", "Rust")
+	ci.IndexFile("Order.java", "public OrderService orderService;", "Java")
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"getUserByID", "user.go"},
+		{"ByID", "user.go"},
+		{"profile", "user.py"},
+		{"load_user_profile", "user.py"},
+		{"session", "user.ts"},
+		{"UserSessionStore", "user.ts"},
+		{"email", "user.rs"},
+		{"service", "Order.java"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, _, err := ci.Search(context.Background(), SearchOptions{Query: tt.query})
+			if err != nil {
+				t.Fatalf("search error: %v", err)
+			}
+			found := false
+			for _, result := range results {
+				if result.RelativePath == tt.expected {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected %s in results for %q, got %+v", tt.expected, tt.query, results)
+			}
+		})
+	}
+}
+
+func Test_CodeAnalyzer_SearchSubWordsOfIdentifiers(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("camel.go", "var userId = 1\n", "Go")
+	ci.IndexFile("snake.py", "user_id = 2\n", "Python")
+	ci.IndexFile("kebab.yaml", "name: codeindex-mcp\n", "YAML")
+	ci.IndexFile("dunder.py", "def __init__(self):\n    pass\n", "Python")
+	ci.IndexFile("private.js", "const _cache = new Map()\n", "JavaScript")
+	ci.IndexFile("other.go", "var count = 3\n", "Go")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"user id", "camel.go snake.py"},
+		{"codeindex-mcp", "kebab.yaml"},
+		{"mcp", "kebab.yaml"},
+		{"init", "dunder.py"},
+		{"__init__", "dunder.py"},
+		{"cache", "private.js"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: tt.query}), " "); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: "user id", FilePath: "camel.go"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d (err %v)", len(results), err)
+	}
+	if spans := results[0].Matches[0].Spans; len(spans) != 2 || spans[0] != (MatchSpan{Start: 4, End: 8}) || spans[1] != (MatchSpan{Start: 8, End: 10}) {
+		t.Errorf("expected user and Id to be highlighted, got %+v", spans)
+	}
+}
+
+func Test_CodeAnalyzer_PhraseOfSubWords(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("user.go", "func getUserByID(id string) {}", "Go")
+
+	// Bleve-level check: the sub-word phrase hits the compound identifier
+	request := bleve.NewSearchRequest(bleve.NewMatchPhraseQuery("user by id"))
+	searchResults, err := ci.index.Search(request)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if searchResults.Total != 1 {
+		t.Errorf("expected phrase 'user by id' to match getUserByID, got %d hits", searchResults.Total)
+	}
+}
+
+func Test_ContentIndex_RegexSearch_SpansPunctuation(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("a.go", "x, err := f()\nif err != nil {\n\treturn err\n}", "Go")
+	ci.IndexFile("b.go", "if err == nil {\n}", "Go")
+
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{Query: `/if err != nil \{/`})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 || results[0].RelativePath != "a.go" {
+		t.Fatalf("expected only a.go, got %+v", results)
+	}
+	if totalMatches != 1 || results[0].Matches[0].LineNumber != 2 {
+		t.Errorf("expected a single match on line 2, got %+v", results[0].Matches)
+	}
+}
+
+func Test_ContentIndex_QuotedSubstringSearch(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("a.go", "cfg.Server.Port = 8080", "Go")
+	ci.IndexFile("b.go", "server port", "Go")
+
+	// Substring inside an identifier and across punctuation
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: `"er.port"`})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 || results[0].RelativePath != "a.go" {
+		t.Errorf("expected only a.go, got %+v", results)
+	}
+}
+
+func Test_ContentIndex_TrigramsUpdatedOnReindexAndRemove(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("a.go", "old content", "Go")
+	ci.IndexFile("a.go", "new content", "Go")
+
+	results, _, _ := ci.Search(context.Background(), SearchOptions{Query: `"old content"`})
+	if len(results) != 0 {
+		t.Errorf("expected no results for replaced content, got %+v", results)
+	}
+	results, _, _ = ci.Search(context.Background(), SearchOptions{Query: `"new content"`})
+	if len(results) != 1 {
+		t.Errorf("expected 1 result for new content, got %d", len(results))
+	}
+
+	ci.RemoveFile("a.go")
+	results, _, _ = ci.Search(context.Background(), SearchOptions{Query: `/new/`})
+	if len(results) != 0 {
+		t.Errorf("expected no results after remove, got %+v", results)
+	}
+}
+
+func Test_ContentIndex_FilesContaining(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+
+	ci.IndexFile("b.go", "srv.Start()", "Go")
+	ci.IndexFile("a.go", "func (s *Server) Start() {}", "Go")
+	ci.IndexFile("c.go", "// start here", "Go")
+
+	paths := ci.FilesContaining("Start")
+	if len(paths) != 2 || paths[0] != "a.go" || paths[1] != "b.go" {
+		t.Errorf("expected [a.go b.go] (case-sensitive, sorted), got %v", paths)
+	}
+}
+
+// benchmarkDocuments returns n synthetic Go files of a few hundred bytes each.
+func benchmarkDocuments(n int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{
+			RelativePath: fmt.Sprintf("pkg%d/file%d.go", i%50, i),
+			Content:      fmt.Sprintf("package pkg%d\n\n// Handler%d serves requests.\nfunc Handler%d(ctx context.Context, req *Request) (*Response, error) {\n\treturn process(ctx, req, %d)\n}\n", i%50, i, i, i),
+			Language:     "Go",
+		}
+	}
+	return docs
+}
+
+func Benchmark_ContentIndex_IndexFile(b *testing.B) {
+	docs := benchmarkDocuments(1000)
+	for b.Loop() {
+		ci, _ := NewContentIndex()
+		for _, doc := range docs {
+			ci.IndexFile(doc.RelativePath, doc.Content, doc.Language)
+		}
+		ci.Close()
+	}
+	b.ReportMetric(float64(len(docs)*b.N)/b.Elapsed().Seconds(), "files/s")
+}
+
+func Benchmark_ContentIndex_IndexBatch(b *testing.B) {
+	docs := benchmarkDocuments(1000)
+	for _, batchSize := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("batch=%d", batchSize), func(b *testing.B) {
+			for b.Loop() {
+				ci, _ := NewContentIndex()
+				for start := 0; start < len(docs); start += batchSize {
+					ci.IndexBatch(docs[start:min(start+batchSize, len(docs))])
+				}
+				ci.Close()
+			}
+			b.ReportMetric(float64(len(docs)*b.N)/b.Elapsed().Seconds(), "files/s")
+		})
+	}
+}
+
+func Test_ContentIndex_Search_Cancelled(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	ci.IndexFile("main.go", "package main\n\nfunc main() {}\n", "Go")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := ci.Search(ctx, SearchOptions{Query: "main"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func Test_ContentIndex_Search_Progress(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	for i := 0; i < 5; i++ {
+		ci.IndexFile(fmt.Sprintf("file%d.go", i), "func shared() {}\n", "Go")
+	}
+
+	var calls []string
+	_, _, err := ci.Search(context.Background(), SearchOptions{
+		Query: "shared",
+		Progress: func(checked int, candidates int) {
+			calls = append(calls, fmt.Sprintf("%d/%d", checked, candidates))
+		},
+	})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if got := strings.Join(calls, " "); got != "0/5 1/5 2/5 3/5 4/5" {
+		t.Errorf("expected progress for every candidate, got %q", got)
+	}
+}
+
+func Test_findMatchingLines_Cancelled(t *testing.T) {
+	content := strings.Repeat("needle\n", 3*cancelCheckLines)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := findMatchingLines(ctx, content, &lineMatcher{groups: []*termGroup{{terms: []lineTerm{{name: "needle", pattern: regexp.MustCompile("needle")}}}}}, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// searchPaths runs a search and returns the sorted paths of the matching files.
+func searchPaths(t *testing.T, ci *ContentIndex, options SearchOptions) []string {
+	t.Helper()
+	results, _, err := ci.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("search %q: %v", options.Query, err)
+	}
+	paths := make([]string, 0, len(results))
+	for _, result := range results {
+		paths = append(paths, result.RelativePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// newBooleanTestIndex indexes a small tree for the query language tests.
+func newBooleanTestIndex(t *testing.T) *ContentIndex {
+	t.Helper()
+	ci := newTestContentIndex(t)
+	t.Cleanup(func() { ci.Close() })
+	ci.IndexFile("server/handler.go", "package server\n\nfunc Handler() {\n\tlog(\"session started\")\n}\n", "Go")
+	ci.IndexFile("server/handler_test.go", "package server\n\nfunc TestHandler() {\n\tHandler()\n}\n", "Go")
+	ci.IndexFile("vendor/lib/session.go", "package lib\n\n// session cache\nvar cache = 1\n", "Go")
+	ci.IndexFile("web/app.ts", "export function handler() {\n  // session timeout\n}\n", "TypeScript")
+	return ci
+}
+
+func Test_ContentIndex_BooleanSearch(t *testing.T) {
+	ci := newBooleanTestIndex(t)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"session AND cache", "vendor/lib/session.go"},
+		{"cache OR timeout", "vendor/lib/session.go web/app.ts"},
+		{"session -cache", "server/handler.go web/app.ts"},
+		{"session NOT (cache OR timeout)", "server/handler.go"},
+		{`"session started" OR /var\s+cache/`, "server/handler.go vendor/lib/session.go"},
+		{`handler -"session"`, "server/handler_test.go"},
+		{`/Handler\(\)/ -TestHandler`, "server/handler.go"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: tt.query}), " "); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+}
+
+func Test_ContentIndex_QualifierSearch(t *testing.T) {
+	ci := newBooleanTestIndex(t)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"session lang:go", "server/handler.go vendor/lib/session.go"},
+		{"session lang:ts", "web/app.ts"},
+		{"session -path:vendor", "server/handler.go web/app.ts"},
+		{"handler path:SERVER/", "server/handler.go server/handler_test.go"},
+		{"handler file:*_test.go", "server/handler_test.go"},
+		{"handler -file:server/**", "web/app.ts"},
+		{`"session" (lang:typescript OR path:lib)`, "vendor/lib/session.go web/app.ts"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: tt.query}), " "); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+}
+
+func Test_ContentIndex_BooleanSearch_MatchingLines(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	ci.IndexFile("main.go", "alpha\nbeta\nalpha beta\ngamma\n", "Go")
+
+	// Lines of every term that is not negated match; spans are sorted
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{Query: "(beta OR alpha) -gamma"})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("expected no result for a file containing gamma, got %+v (err %v)", results, err)
+	}
+	results, totalMatches, err = ci.Search(context.Background(), SearchOptions{Query: "(beta OR alpha) -delta"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d (err %v)", len(results), err)
+	}
+	if totalMatches != 3 {
+		t.Errorf("expected lines 1-3 to match, got %d matches", totalMatches)
+	}
+	if spans := results[0].Matches[2].Spans; len(spans) != 2 || spans[0] != (MatchSpan{Start: 0, End: 5}) || spans[1] != (MatchSpan{Start: 6, End: 10}) {
+		t.Errorf("expected spans of alpha and beta on line 3, got %+v", spans)
+	}
+}
+
+func Test_ContentIndex_CaseSensitiveQualifier(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	ci.IndexFile("lower.go", "var config = 1\n", "Go")
+	ci.IndexFile("upper.go", "type Config struct{}\n", "Go")
+
+	if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: "config"}), " "); got != "lower.go upper.go" {
+		t.Errorf("expected both files ignoring case, got %q", got)
+	}
+	if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: "case:yes Config"}), " "); got != "upper.go" {
+		t.Errorf("expected only upper.go with case:yes, got %q", got)
+	}
+	if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: `case:yes "config"`}), " "); got != "lower.go" {
+		t.Errorf("expected only lower.go for a case-sensitive phrase, got %q", got)
+	}
+}
+
+func Test_ContentIndex_SymbolSearch(t *testing.T) {
+	ci := newBooleanTestIndex(t)
+	definitions := func(name string) map[string][]int {
+		if strings.EqualFold(name, "handler") {
+			return map[string][]int{"server/handler.go": {3}, "web/app.ts": {1}}
+		}
+		return nil
+	}
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: "sym:Handler lang:go", SymbolDefinitions: definitions})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(results) != 1 || results[0].RelativePath != "server/handler.go" {
+		t.Fatalf("expected the Go definition only, got %+v", results)
+	}
+	// Only the definition line matches, not the other occurrences of the name
+	matches := results[0].Matches
+	if len(matches) != 1 || matches[0].LineNumber != 3 || matches[0].Spans[0] != (MatchSpan{Start: 5, End: 12}) {
+		t.Errorf("expected the definition on line 3, got %+v", matches)
+	}
+
+	if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: "Handler -sym:handler", SymbolDefinitions: definitions}), " "); got != "server/handler_test.go" {
+		t.Errorf("expected the files not defining handler, got %q", got)
+	}
+
+	_, _, err = ci.Search(context.Background(), SearchOptions{Query: "foo sym:Handler"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Column != 5 {
+		t.Errorf("expected a QueryError at column 5 without SymbolDefinitions, got %v", err)
+	}
+}
+
+func Test_ContentIndex_Search_OnlyFilters(t *testing.T) {
+	ci := newBooleanTestIndex(t)
+
+	for _, query := range []string{"lang:go", "-session", "path:server -file:*_test.go"} {
+		_, _, err := ci.Search(context.Background(), SearchOptions{Query: query})
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%q: expected a QueryError for a query without a term to match lines, got %v", query, err)
+		}
+	}
+}
+
+func Test_ContentIndex_CaseSensitiveSearch(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	ci.IndexFile("a.go", "var config = load()\n", "Go")
+	ci.IndexFile("b.go", "const CONFIG = 1\n", "Go")
+	ci.IndexFile("c.go", "type Config struct{}\nvar config Config\n", "Go")
+	ci.IndexFile("d.go", "func NewConfigLoader() {}\n", "Go")
+
+	// The cased field leaves only files containing Config as a word or sub-word as candidates
+	candidates := 0
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{
+		Query:         "Config",
+		CaseSensitive: true,
+		Progress:      func(checked int, total int) { candidates = total },
+	})
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if candidates != 2 || len(results) != 2 || totalMatches != 3 {
+		t.Errorf("expected 3 lines in 2 of 2 candidates, got %d lines in %d of %d", totalMatches, len(results), candidates)
+	}
+	for _, result := range results {
+		for _, match := range result.Matches {
+			for _, span := range match.Spans {
+				if text := match.LineText[span.Start:span.End]; text != "Config" {
+					t.Errorf("%s:%d: expected only Config to be highlighted, got %q", result.RelativePath, match.LineNumber, text)
+				}
+			}
+		}
+	}
+
+	// case:no in the query overrides the option
+	if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: "config case:no", CaseSensitive: true}), " "); got != "a.go b.go c.go d.go" {
+		t.Errorf("expected all files with case:no, got %q", got)
+	}
+}
+
+func Test_ContentIndex_WholeWordSearch(t *testing.T) {
+	ci := newTestContentIndex(t)
+	defer ci.Close()
+	ci.IndexFile("valid.go", "valid := true\n", "Go")
+	ci.IndexFile("token.go", "id_token := \"x\"\nuserId := 2\n", "Go")
+	ci.IndexFile("user.go", "user.id = 1\n", "Go")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"id", "user.go"},
+		{`"id"`, "user.go"},
+		{`"id ="`, "user.go"},
+		{`"d :="`, ""},
+		{"/i[d]/", "user.go"},
+		// \Q quotes up to \E or the end, so the boundaries are not added as text
+		{`/\Quser.id/`, "user.go"},
+		{`/\Qd/`, ""},
+		{"/(?i)ID/", "user.go"},
+		{"token", ""},
+		{"id_token", "token.go"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: tt.query, WholeWord: true}), " "); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: "id", WholeWord: true})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d (err %v)", len(results), err)
+	}
+	if spans := results[0].Matches[0].Spans; len(spans) != 1 || spans[0] != (MatchSpan{Start: 5, End: 7}) {
+		t.Errorf("expected only the standalone id to be highlighted, got %+v", spans)
+	}
+}
+
+// newMultiTermTestIndex indexes files containing different subsets of user, session and timeout.
+func newMultiTermTestIndex(t *testing.T) *ContentIndex {
+	t.Helper()
+	ci := newTestContentIndex(t)
+	t.Cleanup(func() { ci.Close() })
+	ci.IndexFile("a_user.go", "func loadUser() {}\n", "Go")
+	ci.IndexFile("b_two.go", "// user\nvar session = 1\n", "Go")
+	ci.IndexFile("c_all.go", "// user session\nconst timeout = 5\n", "Go")
+	ci.IndexFile("d_line.go", "user.session.timeout = 3\n", "Go")
+	ci.IndexFile("e_none.go", "package none\n", "Go")
+	return ci
+}
+
+func Test_ContentIndex_MultiTermSearch(t *testing.T) {
+	ci := newMultiTermTestIndex(t)
+
+	tests := []struct {
+		terms TermMode
+		want  string
+		lines int
+	}{
+		// Files with any word; those with more distinct words first
+		{TermsAny, "d_line.go:3 c_all.go:3 b_two.go:2 a_user.go:1", 6},
+		{TermsAll, "d_line.go:3 c_all.go:3", 3},
+		{TermsLine, "d_line.go:3", 1},
+	}
+	for _, tt := range tests {
+		results, totalMatches, err := ci.Search(context.Background(), SearchOptions{Query: "user session timeout", Terms: tt.terms})
+		if err != nil {
+			t.Fatalf("%s: search error: %v", tt.terms, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, fmt.Sprintf("%s:%d", result.RelativePath, len(result.Terms)))
+		}
+		if strings.Join(got, " ") != tt.want || totalMatches != tt.lines {
+			t.Errorf("%s: expected %s with %d lines, got %s with %d lines", tt.terms, tt.want, tt.lines, strings.Join(got, " "), totalMatches)
+		}
+	}
+
+	_, _, err := ci.Search(context.Background(), SearchOptions{Query: "user", Terms: "most"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("expected a QueryError for an unknown terms mode, got %v", err)
+	}
+}
+
+func Test_ContentIndex_MultiTermSearch_LineTerms(t *testing.T) {
+	ci := newMultiTermTestIndex(t)
+
+	results, _, err := ci.Search(context.Background(), SearchOptions{Query: `user timeout OR "session ="`, FilePath: "c_all.go"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d (err %v)", len(results), err)
+	}
+	result := results[0]
+	if got := strings.Join(result.Terms, ","); got != "user,timeout" {
+		t.Errorf("expected the file terms in query order, got %s", got)
+	}
+	if got := strings.Join(result.Matches[0].Terms, ","); got != "user" || result.Matches[0].Spans[0] != (MatchSpan{Start: 3, End: 7}) {
+		t.Errorf("expected user highlighted on line 1, got %s %+v", got, result.Matches[0].Spans)
+	}
+	if got := strings.Join(result.Matches[1].Terms, ","); got != "timeout" {
+		t.Errorf("expected timeout on line 2, got %s", got)
+	}
+
+	results, _, err = ci.Search(context.Background(), SearchOptions{Query: `session /ti\w+/ "user."`, FilePath: "d_line.go"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d (err %v)", len(results), err)
+	}
+	if got := strings.Join(results[0].Matches[0].Terms, ","); got != `session,/ti\w+/,"user."` {
+		t.Errorf("expected every kind of term to be named, got %s", got)
+	}
+}
+
+func Test_ContentIndex_MultiTermSearch_Negated(t *testing.T) {
+	ci := newMultiTermTestIndex(t)
+
+	// A negated text term excludes files as the terms mode would match them
+	tests := []struct {
+		terms TermMode
+		want  string
+	}{
+		{TermsAny, "a_user.go"},
+		{TermsAll, "a_user.go b_two.go"},
+		{TermsLine, "a_user.go b_two.go c_all.go"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(searchPaths(t, ci, SearchOptions{Query: "user -(session timeout)", Terms: tt.terms}), " "); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.terms, tt.want, got)
+		}
+	}
+}
+
+// resultOrder returns the relative paths of a search's results in result order.
+func resultOrder(t *testing.T, ci *ContentIndex, options SearchOptions) string {
+	t.Helper()
+	results, _, err := ci.Search(context.Background(), options)
+	if err != nil {
+		t.Fatalf("search %q: %v", options.Query, err)
+	}
+	paths := make([]string, 0, len(results))
+	for _, result := range results {
+		paths = append(paths, result.RelativePath)
+	}
+	return strings.Join(paths, " ")
+}
+
+// newRankingTestIndex indexes the same content under paths the ranking treats differently,
+// so their Bleve scores are equal and only the ranking factors order them.
+func newRankingTestIndex(t *testing.T) *ContentIndex {
+	t.Helper()
+	ci := newTestContentIndex(t)
+	t.Cleanup(func() { ci.Close() })
+	content := "func NewSession() {}\n"
+	for _, path := range []string{"a/util.go", "b/session.go", "session/c.go", "d/util_test.go", "vendor/e/util.go", "f/def.go"} {
+		ci.IndexFile(path, content, "Go")
+	}
+	return ci
+}
+
+func Test_ContentIndex_RelevanceRanking(t *testing.T) {
+	ci := newRankingTestIndex(t)
+	definitions := func(relativePath string) map[int][]string {
+		if relativePath == "f/def.go" {
+			return map[int][]string{1: {"NewSession"}}
+		}
+		return nil
+	}
+
+	got := resultOrder(t, ci, SearchOptions{Query: "session", FileDefinitions: definitions})
+	want := "f/def.go b/session.go session/c.go a/util.go d/util_test.go vendor/e/util.go"
+	if got != want {
+		t.Errorf("expected definition, file name, path, plain, test, vendored order:\n got %s\nwant %s", got, want)
+	}
+
+	// Preferring tests demotes the other files instead; a file name match still outweighs it
+	got = resultOrder(t, ci, SearchOptions{Query: "session", PreferTests: true})
+	want = "b/session.go d/util_test.go session/c.go a/util.go f/def.go vendor/e/util.go"
+	if got != want {
+		t.Errorf("expected the test file above the plain files with PreferTests:\n got %s\nwant %s", got, want)
+	}
+
+	// Without Bleve every candidate scores the same, so the factors alone decide
+	got = resultOrder(t, ci, SearchOptions{Query: `"session"`, MaxResults: 2, FileDefinitions: definitions})
+	if got != "f/def.go b/session.go" {
+		t.Errorf("expected the two best files of a phrase search, got %s", got)
+	}
+}
+
+func Test_ContentIndex_SortOrders(t *testing.T) {
+	ci := newRankingTestIndex(t)
+	now := time.Now()
+	modTimes := map[string]time.Time{
+		"a/util.go":        now.Add(-2 * time.Hour),
+		"b/session.go":     now.Add(-72 * time.Hour),
+		"session/c.go":     now.Add(-1 * time.Hour),
+		"vendor/e/util.go": now.Add(-48 * time.Hour),
+	}
+	modTime := func(relativePath string) time.Time { return modTimes[relativePath] }
+
+	got := resultOrder(t, ci, SearchOptions{Query: "session", Sort: SortPath, ModTime: modTime})
+	if want := "a/util.go b/session.go d/util_test.go f/def.go session/c.go vendor/e/util.go"; got != want {
+		t.Errorf("path: got %s, want %s", got, want)
+	}
+
+	// Files without a known modification time come last, by path
+	got = resultOrder(t, ci, SearchOptions{Query: "session", Sort: SortRecent, ModTime: modTime})
+	if want := "session/c.go a/util.go vendor/e/util.go b/session.go d/util_test.go f/def.go"; got != want {
+		t.Errorf("recent: got %s, want %s", got, want)
+	}
+
+	// Recently modified files rank higher by relevance
+	got = resultOrder(t, ci, SearchOptions{Query: "session", ModTime: func(relativePath string) time.Time {
+		if relativePath == "a/util.go" {
+			return now
+		}
+		return now.Add(-365 * 24 * time.Hour)
+	}})
+	if !strings.HasPrefix(got, "b/session.go a/util.go session/c.go ") {
+		t.Errorf("expected the recent a/util.go above the directory match, got %s", got)
+	}
+
+	// A path-sorted phrase search stops at MaxResults and still counts only the returned lines
+	results, totalMatches, err := ci.Search(context.Background(), SearchOptions{Query: `"session"`, Sort: SortPath, MaxResults: 2})
+	if err != nil || len(results) != 2 || totalMatches != 2 || results[1].RelativePath != "b/session.go" {
+		t.Errorf("expected a/util.go and b/session.go with 2 lines, got %+v, %d (err %v)", results, totalMatches, err)
+	}
+
+	_, _, err = ci.Search(context.Background(), SearchOptions{Query: "session", Sort: "size"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("expected a QueryError for an unknown sort order, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package index
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"sort"
 	"strings"
@@ -19,6 +21,13 @@ type IndexedFile struct {
 	SizeBytes    int64     // File size in bytes
 	ModTime      time.Time // Last modification time
 	LineCount    int       // Number of lines in the file
+	ContentHash  string    // Hex-encoded SHA-256 of the file content
+}
+
+// ContentHash returns the hash stored in IndexedFile.ContentHash for a file's content.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
 }
 
 // FileIndex maintains an in-memory index of file paths for fast glob-based searching.
@@ -51,6 +60,25 @@ func (fi *FileIndex) AddFile(file *IndexedFile) {
 	}
 }
 
+// AddFiles adds or updates multiple files at once, sorting the path list only once.
+// Prefer this over repeated AddFile calls when loading many files (e.g. from a snapshot).
+func (fi *FileIndex) AddFiles(files []*IndexedFile) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	added := false
+	for _, file := range files {
+		if _, exists := fi.files[file.RelativePath]; !exists {
+			fi.sortedPaths = append(fi.sortedPaths, file.RelativePath)
+			added = true
+		}
+		fi.files[file.RelativePath] = file
+	}
+	if added {
+		sort.Strings(fi.sortedPaths)
+	}
+}
+
 // RemoveFile removes a file from the index by its relative path.
 func (fi *FileIndex) RemoveFile(relativePath string) {
 	fi.mu.Lock()
@@ -164,6 +192,22 @@ func (fi *FileIndex) AllFiles() []*IndexedFile {
 	return result
 }
 
+// PathsWithPrefix returns the sorted relative paths starting with prefix
+// (e.g. "src/api/" for every file below a directory).
+func (fi *FileIndex) PathsWithPrefix(prefix string) []string {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+
+	var result []string
+	for i := sort.SearchStrings(fi.sortedPaths, prefix); i < len(fi.sortedPaths); i++ {
+		if !strings.HasPrefix(fi.sortedPaths[i], prefix) {
+			break
+		}
+		result = append(result, fi.sortedPaths[i])
+	}
+	return result
+}
+
 // Clear removes all files from the index.
 func (fi *FileIndex) Clear() {
 	fi.mu.Lock()
@@ -172,3 +216,15 @@ func (fi *FileIndex) Clear() {
 	fi.files = make(map[string]*IndexedFile)
 	fi.sortedPaths = make([]string, 0)
 }
+
+// Swap atomically replaces the contents of fi with those of next. next must not be used afterwards.
+func (fi *FileIndex) Swap(next *FileIndex) {
+	next.mu.Lock()
+	files, sortedPaths := next.files, next.sortedPaths
+	next.mu.Unlock()
+
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.files = files
+	fi.sortedPaths = sortedPaths
+}
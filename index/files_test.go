@@ -127,6 +127,23 @@ func Test_FileIndex_Clear(t *testing.T) {
 	}
 }
 
+func Test_FileIndex_Swap(t *testing.T) {
+	fi := NewFileIndex()
+	fi.AddFile(newTestFile("old.go", "Go", 100))
+	next := NewFileIndex()
+	next.AddFile(newTestFile("b.go", "Go", 100))
+	next.AddFile(newTestFile("a.go", "Go", 100))
+
+	fi.Swap(next)
+
+	if fi.GetFile("old.go") != nil || fi.FileCount() != 2 {
+		t.Errorf("expected only the new generation's files, got %d files", fi.FileCount())
+	}
+	if paths := fi.PathsWithPrefix(""); len(paths) != 2 || paths[0] != "a.go" {
+		t.Errorf("expected sorted paths of the new generation, got %v", paths)
+	}
+}
+
 func Test_FileIndex_MaxResults(t *testing.T) {
 	fi := NewFileIndex()
 	for i := 0; i < 100; i++ {
@@ -141,3 +158,39 @@ func Test_FileIndex_MaxResults(t *testing.T) {
 		t.Errorf("expected at most 5 results, got %d", len(results))
 	}
 }
+
+func Test_FileIndex_AddFiles(t *testing.T) {
+	fi := NewFileIndex()
+	fi.AddFile(newTestFile("b.go", "Go", 10))
+	fi.AddFiles([]*IndexedFile{
+		newTestFile("c.go", "Go", 10),
+		newTestFile("a.go", "Go", 10),
+		newTestFile("b.go", "Go", 20),
+	})
+
+	all := fi.AllFiles()
+	if len(all) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(all))
+	}
+	if all[0].RelativePath != "a.go" || all[2].RelativePath != "c.go" {
+		t.Errorf("expected sorted order, got %s..%s", all[0].RelativePath, all[2].RelativePath)
+	}
+	if fi.GetFile("b.go").SizeBytes != 20 {
+		t.Error("expected b.go to be updated")
+	}
+}
+
+func Test_FileIndex_PathsWithPrefix(t *testing.T) {
+	fi := NewFileIndex()
+	for _, path := range []string{"src/api/handler.go", "src/api/v2/routes.go", "src/apiclient.go", "src/main.go"} {
+		fi.AddFile(newTestFile(path, "Go", 100))
+	}
+
+	got := fi.PathsWithPrefix("src/api/")
+	if len(got) != 2 || got[0] != "src/api/handler.go" || got[1] != "src/api/v2/routes.go" {
+		t.Errorf("expected the two files below src/api/, got %v", got)
+	}
+	if got := fi.PathsWithPrefix("docs/"); len(got) != 0 {
+		t.Errorf("expected no files below docs/, got %v", got)
+	}
+}
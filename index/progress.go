@@ -0,0 +1,119 @@
+package index
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase is a stage of building the index at startup.
+type Phase string
+
+const (
+	PhaseIndexing Phase = "indexing" // initial indexing from scratch
+	PhaseSyncing  Phase = "syncing"  // reconciling a loaded snapshot with the filesystem
+	PhaseReady    Phase = "ready"    // the index is complete and kept up to date by the watcher
+)
+
+// ProgressState is a point-in-time copy of the startup progress.
+type ProgressState struct {
+	Phase      Phase
+	Processed  int // files read so far
+	Discovered int // files found so far; grows while directories are still being walked
+	Elapsed    time.Duration
+}
+
+// Progress tracks the startup indexing so tools can answer from the partially built index.
+// The zero value is not usable; create it with NewProgress. All methods may be called
+// concurrently, and on a nil *Progress they do nothing.
+type Progress struct {
+	mu         sync.Mutex
+	phase      Phase
+	processed  int
+	discovered int
+	startedAt  time.Time
+	finishedAt time.Time
+	ready      chan struct{}
+}
+
+// NewProgress creates a tracker in the given phase, started now.
+func NewProgress(phase Phase) *Progress {
+	return &Progress{
+		phase:     phase,
+		startedAt: time.Now(),
+		ready:     make(chan struct{}),
+	}
+}
+
+// AddDiscovered records that n more files were found and will be processed.
+func (p *Progress) AddDiscovered(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discovered += n
+}
+
+// AddProcessed records that n more files were read, whether or not they were indexed.
+func (p *Progress) AddProcessed(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed += n
+}
+
+// Finish marks the index as ready and releases everyone waiting in Ready.
+func (p *Progress) Finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.phase == PhaseReady {
+		return
+	}
+	p.phase = PhaseReady
+	p.finishedAt = time.Now()
+	close(p.ready)
+}
+
+// Ready returns a channel that is closed once the index is ready.
+func (p *Progress) Ready() <-chan struct{} {
+	if p == nil {
+		ready := make(chan struct{})
+		close(ready)
+		return ready
+	}
+	return p.ready
+}
+
+// IsReady reports whether the index is ready.
+func (p *Progress) IsReady() bool {
+	select {
+	case <-p.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+// State returns the current progress. Elapsed is the total duration once ready.
+func (p *Progress) State() ProgressState {
+	if p == nil {
+		return ProgressState{Phase: PhaseReady}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	end := p.finishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return ProgressState{
+		Phase:      p.phase,
+		Processed:  p.processed,
+		Discovered: p.discovered,
+		Elapsed:    end.Sub(p.startedAt),
+	}
+}
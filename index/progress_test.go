@@ -0,0 +1,36 @@
+package index
+
+import "testing"
+
+func Test_Progress_Finish(t *testing.T) {
+	p := NewProgress(PhaseSyncing)
+	p.AddDiscovered(3)
+	p.AddProcessed(2)
+
+	if p.IsReady() {
+		t.Fatal("expected not ready before Finish")
+	}
+	if state := p.State(); state.Phase != PhaseSyncing || state.Processed != 2 || state.Discovered != 3 {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	p.Finish()
+	p.Finish() // idempotent
+	select {
+	case <-p.Ready():
+	default:
+		t.Fatal("expected Ready to be closed after Finish")
+	}
+	if state := p.State(); state.Phase != PhaseReady || state.Processed != 2 {
+		t.Errorf("unexpected state after Finish %+v", state)
+	}
+}
+
+func Test_Progress_Nil(t *testing.T) {
+	var p *Progress
+	p.AddDiscovered(1)
+	p.AddProcessed(1)
+	if !p.IsReady() || p.State().Phase != PhaseReady {
+		t.Error("expected a nil tracker to report ready")
+	}
+}
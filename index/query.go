@@ -0,0 +1,388 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// queryKind is the type of a queryNode.
+type queryKind int
+
+const (
+	queryAnd    queryKind = iota // all children match
+	queryOr                      // any child matches
+	queryNot                     // the only child does not match
+	queryText                    // plain words, matched by Bleve
+	queryPhrase                  // "quoted" exact substring
+	queryRegex                   // /regex/, matched line by line
+	queryLang                    // lang: the file's language
+	queryPath                    // path: substring of the relative path
+	queryFile                    // file: glob of the relative path or file name
+	querySymbol                  // sym: a symbol defined in the file
+)
+
+// queryNode is a node of a parsed search query.
+type queryNode struct {
+	kind     queryKind
+	value    string
+	column   int            // 1-based byte column of the node in the query
+	regex    *regexp.Regexp // compiled pattern of a queryRegex node
+	children []*queryNode
+}
+
+// parsedQuery is a search query parsed by parseQuery.
+type parsedQuery struct {
+	root *queryNode
+	// caseSensitive is set by case:yes or case:no; nil leaves it to SearchOptions.CaseSensitive
+	caseSensitive *bool
+}
+
+// qualifiers are the field names recognized before a colon. Any other word containing a
+// colon (std::string, http://) is a plain word.
+var qualifiers = []string{"lang", "path", "file", "case", "sym"}
+
+// parseQuery parses a search query:
+//
+//	query     = or
+//	or        = and { "OR" and }
+//	and       = unary { ["AND"] unary }
+//	unary     = ("NOT" | "-") unary | primary
+//	primary   = "(" or ")" | "quoted phrase" | /regex/ | qualifier:value | word
+//
+// Operators are upper case. Consecutive plain words form a single text term, so
+// "user session" is still one Bleve match query. Syntax errors are returned as a
+// *QueryError with the column where they were found.
+func parseQuery(input string) (*parsedQuery, error) {
+	p := &queryParser{input: input}
+	p.skipSpace()
+	if p.atEnd() {
+		return nil, p.errorAt(p.pos, "empty query")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		// parseOr only stops early at a ')' without a matching '('
+		return nil, p.errorAt(p.pos, "unexpected )")
+	}
+	if root == nil {
+		return nil, &QueryError{Query: input, Message: "query has no search terms besides case:"}
+	}
+	return &parsedQuery{root: root, caseSensitive: p.caseSensitive}, nil
+}
+
+// queryParser is the state of parseQuery.
+type queryParser struct {
+	input         string
+	pos           int // byte offset of the next unread byte
+	depth         int // number of open groups
+	caseSensitive *bool
+}
+
+// parseOr parses operands separated by OR. It returns nil if the operands only set case:.
+func (p *queryParser) parseOr() (*queryNode, error) {
+	column := p.pos + 1
+	var operands []*queryNode
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if operand != nil {
+			operands = append(operands, operand)
+		}
+		p.skipSpace()
+		if !p.atOperator("OR") {
+			break
+		}
+		p.pos += len("OR")
+	}
+	return combine(queryOr, operands, column), nil
+}
+
+// parseAnd parses operands joined by AND or by juxtaposition, merging adjacent plain words.
+func (p *queryParser) parseAnd() (*queryNode, error) {
+	column := p.pos + 1
+	var operands []*queryNode
+	parsed := false
+	for {
+		p.skipSpace()
+		if p.atEnd() || p.atGroupEnd() || p.atOperator("OR") {
+			if !parsed {
+				return nil, p.missingTerm()
+			}
+			break
+		}
+		explicit := false
+		if parsed && p.atOperator("AND") {
+			p.pos += len("AND")
+			explicit = true
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		parsed = true
+		if operand == nil {
+			continue
+		}
+		if n := len(operands); n > 0 && !explicit && operand.kind == queryText && operands[n-1].kind == queryText {
+			operands[n-1].value += " " + operand.value
+			continue
+		}
+		operands = append(operands, operand)
+	}
+	return combine(queryAnd, operands, column), nil
+}
+
+// parseUnary parses an optionally negated primary.
+func (p *queryParser) parseUnary() (*queryNode, error) {
+	p.skipSpace()
+	column := p.pos + 1
+	negated := false
+	if p.atOperator("NOT") {
+		p.pos += len("NOT")
+		negated = true
+	} else if p.atNegation() {
+		p.pos++
+		negated = true
+	}
+	if !negated {
+		return p.parsePrimary()
+	}
+
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if operand == nil {
+		return nil, p.errorAt(column-1, "case: cannot be negated")
+	}
+	return &queryNode{kind: queryNot, column: column, children: []*queryNode{operand}}, nil
+}
+
+// parsePrimary parses a group, phrase, regex, qualifier or word. It returns nil for case:.
+func (p *queryParser) parsePrimary() (*queryNode, error) {
+	p.skipSpace()
+	if p.atEnd() || p.atGroupEnd() || p.atOperator("AND") || p.atOperator("OR") {
+		return nil, p.missingTerm()
+	}
+	start := p.pos
+	column := start + 1
+
+	switch p.input[start] {
+	case '(':
+		p.pos++
+		p.depth++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.atGroupEnd() {
+			return nil, p.errorAt(start, "missing ) for this (")
+		}
+		p.pos++
+		p.depth--
+		return node, nil
+
+	case '"':
+		phrase, err := p.scanPhrase()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{kind: queryPhrase, value: phrase, column: column}, nil
+
+	case '/':
+		return p.scanRegex()
+	}
+
+	for _, field := range qualifiers {
+		if strings.HasPrefix(p.input[start:], field+":") {
+			p.pos += len(field) + 1
+			return p.parseQualifier(field, start)
+		}
+	}
+
+	return &queryNode{kind: queryText, value: p.scanWord(), column: column}, nil
+}
+
+// parseQualifier parses the value of a qualifier whose "field:" prefix starts at start.
+func (p *queryParser) parseQualifier(field string, start int) (*queryNode, error) {
+	var value string
+	if !p.atEnd() && p.input[p.pos] == '"' {
+		phrase, err := p.scanPhrase()
+		if err != nil {
+			return nil, err
+		}
+		value = phrase
+	} else {
+		value = p.scanWord()
+	}
+	if value == "" {
+		return nil, p.errorAt(start, "missing value after "+field+":")
+	}
+
+	node := &queryNode{value: value, column: start + 1}
+	switch field {
+	case "case":
+		switch value {
+		case "yes", "no":
+			caseSensitive := value == "yes"
+			p.caseSensitive = &caseSensitive
+		default:
+			return nil, p.errorAt(start, "case: must be yes or no")
+		}
+		return nil, nil
+	case "lang":
+		node.kind = queryLang
+	case "path":
+		node.kind = queryPath
+		node.value = strings.ReplaceAll(value, "\\", "/")
+	case "file":
+		node.kind = queryFile
+		node.value = strings.ReplaceAll(value, "\\", "/")
+		if !doublestar.ValidatePattern(node.value) {
+			return nil, p.errorAt(start, "invalid glob in file:")
+		}
+	case "sym":
+		node.kind = querySymbol
+	}
+	return node, nil
+}
+
+// scanPhrase reads a double-quoted string starting at p.pos. Inside it \" is a quote and
+// \\ a backslash; other backslashes are kept as written.
+func (p *queryParser) scanPhrase() (string, error) {
+	start := p.pos
+	var phrase strings.Builder
+	for i := start + 1; i < len(p.input); i++ {
+		c := p.input[i]
+		if c == '\\' && i+1 < len(p.input) && (p.input[i+1] == '"' || p.input[i+1] == '\\') {
+			phrase.WriteByte(p.input[i+1])
+			i++
+			continue
+		}
+		if c == '"' {
+			p.pos = i + 1
+			if phrase.Len() == 0 {
+				return "", p.errorAt(start, "empty phrase")
+			}
+			return phrase.String(), nil
+		}
+		phrase.WriteByte(c)
+	}
+	return "", p.errorAt(start, "unterminated phrase")
+}
+
+// scanRegex reads a /regex/ starting at p.pos. The regex ends at a '/' followed by
+// whitespace, the end of the query or the ')' of an open group, so slashes elsewhere
+// (a/b) need no escaping.
+func (p *queryParser) scanRegex() (*queryNode, error) {
+	start := p.pos
+	for i := start + 1; i < len(p.input); i++ {
+		switch p.input[i] {
+		case '\\':
+			i++
+		case '/':
+			if i+1 < len(p.input) && !isQuerySpace(p.input[i+1]) && !(p.depth > 0 && p.input[i+1] == ')') {
+				continue
+			}
+			pattern := p.input[start+1 : i]
+			if pattern == "" {
+				return nil, p.errorAt(start, "empty regex")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, &QueryError{Query: p.input, Message: "invalid regex", Column: start + 1, Err: err}
+			}
+			p.pos = i + 1
+			return &queryNode{kind: queryRegex, value: pattern, column: start + 1, regex: re}, nil
+		}
+	}
+	return nil, p.errorAt(start, "unterminated regex (a regex ends with / followed by a space)")
+}
+
+// scanWord reads a word: everything up to whitespace, or up to the ')' closing a group.
+func (p *queryParser) scanWord() string {
+	start := p.pos
+	for !p.atEnd() && !isQuerySpace(p.input[p.pos]) && !p.atGroupEnd() {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.atEnd() && isQuerySpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) atEnd() bool {
+	return p.pos >= len(p.input)
+}
+
+// atGroupEnd reports whether the next byte closes a group. At the top level a ')'
+// is only a group end at the start of a term, where parseQuery rejects it.
+func (p *queryParser) atGroupEnd() bool {
+	if p.atEnd() || p.input[p.pos] != ')' {
+		return false
+	}
+	return p.depth > 0 || p.pos == 0 || isQuerySpace(p.input[p.pos-1])
+}
+
+// atOperator reports whether the next word is the operator op.
+func (p *queryParser) atOperator(op string) bool {
+	if !strings.HasPrefix(p.input[p.pos:], op) {
+		return false
+	}
+	end := p.pos + len(op)
+	return end == len(p.input) || isQuerySpace(p.input[end]) || (op == "NOT" && p.input[end] == '(')
+}
+
+// atNegation reports whether the next term starts with a negating '-'. A '-' followed by
+// whitespace or another '-' (--flag) is part of a word.
+func (p *queryParser) atNegation() bool {
+	if p.atEnd() || p.input[p.pos] != '-' || p.pos+1 >= len(p.input) {
+		return false
+	}
+	next := p.input[p.pos+1]
+	return !isQuerySpace(next) && next != '-'
+}
+
+// missingTerm returns the error for a position where a search term was expected.
+func (p *queryParser) missingTerm() error {
+	switch {
+	case p.atEnd():
+		return p.errorAt(p.pos, "missing search term at end of query")
+	case p.atGroupEnd():
+		return p.errorAt(p.pos, "missing search term before )")
+	case p.atOperator("AND"):
+		return p.errorAt(p.pos, "missing search term before AND")
+	default:
+		return p.errorAt(p.pos, "missing search term before OR")
+	}
+}
+
+// errorAt returns a QueryError for the byte offset pos of the query.
+func (p *queryParser) errorAt(pos int, message string) error {
+	return &QueryError{Query: p.input, Message: message, Column: pos + 1}
+}
+
+func isQuerySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// combine returns the only operand, or a node of kind joining all operands.
+func combine(kind queryKind, operands []*queryNode, column int) *queryNode {
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	}
+	return &queryNode{kind: kind, column: column, children: operands}
+}
@@ -0,0 +1,116 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatQueryNode renders a query tree compactly, e.g. and(text:a, not(path:vendor)).
+func formatQueryNode(node *queryNode) string {
+	names := map[queryKind]string{
+		queryAnd: "and", queryOr: "or", queryNot: "not", queryText: "text", queryPhrase: "phrase",
+		queryRegex: "regex", queryLang: "lang", queryPath: "path", queryFile: "file", querySymbol: "sym",
+	}
+	if len(node.children) == 0 {
+		return names[node.kind] + ":" + node.value
+	}
+	children := make([]string, len(node.children))
+	for i, child := range node.children {
+		children[i] = formatQueryNode(child)
+	}
+	return names[node.kind] + "(" + strings.Join(children, ", ") + ")"
+}
+
+func Test_parseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"handleRequest", "text:handleRequest"},
+		{"user session timeout", "text:user session timeout"},
+		{`"err != nil {"`, "phrase:err != nil {"},
+		{`/func\s+\w+Handler/`, `regex:func\s+\w+Handler`},
+		{"foo AND bar", "and(text:foo, text:bar)"},
+		{"foo OR bar baz", "or(text:foo, text:bar baz)"},
+		{"foo OR bar AND baz", "or(text:foo, and(text:bar, text:baz))"},
+		{"(foo OR bar) baz", "and(or(text:foo, text:bar), text:baz)"},
+		{"NOT foo bar", "and(not(text:foo), text:bar)"},
+		{"Handler -test", "and(text:Handler, not(text:test))"},
+		{`-"TODO" -/x+/ -(a OR b) c`, "and(not(phrase:TODO), not(regex:x+), not(or(text:a, text:b)), text:c)"},
+		{"lang:go path:internal/ file:*_test.go -path:vendor sym:Handler", "and(lang:go, path:internal/, file:*_test.go, not(path:vendor), sym:Handler)"},
+		{`path:"my dir/" config`, "and(path:my dir/, text:config)"},
+		{"case:yes Config", "text:Config"},
+		{`"say \"hi\""`, `phrase:say "hi"`},
+		{"/a/b/ c", "and(regex:a/b, text:c)"},
+		{"(/x/)", "regex:x"},
+		// Words that only look like syntax
+		{"std::string http://host --verbose a-b and or", "text:std::string http://host --verbose a-b and or"},
+		{"foo(bar) x)", "text:foo(bar) x)"},
+		{`say"hi"`, `text:say"hi"`},
+	}
+	for _, tt := range tests {
+		parsed, err := parseQuery(tt.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if got := formatQueryNode(parsed.root); got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.query, tt.want, got)
+		}
+	}
+}
+
+func Test_parseQuery_CaseSensitive(t *testing.T) {
+	for query, want := range map[string]string{"Config": "default", "case:yes Config": "true", "Config case:no": "false"} {
+		parsed, err := parseQuery(query)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", query, err)
+		}
+		got := "default"
+		if parsed.caseSensitive != nil {
+			got = fmt.Sprint(*parsed.caseSensitive)
+		}
+		if got != want {
+			t.Errorf("%q: expected caseSensitive %s, got %s", query, want, got)
+		}
+	}
+}
+
+func Test_parseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		query   string
+		message string
+		column  int
+	}{
+		{"   ", "empty query", 4},
+		{`foo "bar`, "unterminated phrase", 5},
+		{`""`, "empty phrase", 1},
+		{"x /usr/bin", "unterminated regex (a regex ends with / followed by a space)", 3},
+		{"a /[a-/", "invalid regex", 3},
+		{"(foo OR bar", "missing ) for this (", 1},
+		{"foo )", "unexpected )", 5},
+		{"()", "missing search term before )", 2},
+		{"foo OR", "missing search term at end of query", 7},
+		{"AND foo", "missing search term before AND", 1},
+		{"foo AND OR bar", "missing search term before OR", 9},
+		{"NOT", "missing search term at end of query", 4},
+		{"lang: foo", "missing value after lang:", 1},
+		{"foo case:maybe", "case: must be yes or no", 5},
+		{"foo -case:yes", "case: cannot be negated", 5},
+		{"file:[a", "invalid glob in file:", 1},
+		{"case:yes", "query has no search terms besides case:", 0},
+	}
+	for _, tt := range tests {
+		_, err := parseQuery(tt.query)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%q: expected QueryError, got %v", tt.query, err)
+			continue
+		}
+		if queryErr.Message != tt.message || queryErr.Column != tt.column {
+			t.Errorf("%q: expected %q at column %d, got %q at column %d", tt.query, tt.message, tt.column, queryErr.Message, queryErr.Column)
+		}
+	}
+}
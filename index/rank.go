@@ -0,0 +1,205 @@
+package index
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortOrder orders the files of a search result.
+type SortOrder string
+
+const (
+	// SortRelevance lists files with more distinct query terms first, then by relevance score.
+	SortRelevance SortOrder = "relevance"
+	// SortPath lists files by relative path.
+	SortPath SortOrder = "path"
+	// SortRecent lists the most recently modified files first.
+	SortRecent SortOrder = "recent"
+)
+
+// Relevance weights. The score of a file is its Bleve score (1 for queries answered without
+// Bleve) multiplied by the factors that apply to it.
+const (
+	// definitionBoost applies if a matching line defines a symbol whose name contains the match
+	definitionBoost = 2.0
+	// fileNameBoost applies if a term matches the file name, pathBoost if it only matches the directory
+	fileNameBoost = 1.8
+	pathBoost     = 1.3
+	// testFileFactor applies to test files, or to all other files if tests are preferred
+	testFileFactor = 0.6
+	// generatedFactor applies to generated, vendored and lock files
+	generatedFactor = 0.3
+	// lengthNormLines is the length at which a file's score drops to about 80%; the factor
+	// keeps falling slowly (logarithmically) for longer files
+	lengthNormLines = 200
+	// recencyBoost is the extra weight of a file modified just now; it halves every recencyHalfLife
+	recencyBoost    = 0.5
+	recencyHalfLife = 30 * 24 * time.Hour
+)
+
+// scoredResult is a search result with the values it is sorted by.
+type scoredResult struct {
+	result  ContentSearchResult
+	score   float64
+	modTime time.Time
+}
+
+// sortResults orders results by order; ties are broken by path.
+func sortResults(results []scoredResult, order SortOrder) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		switch order {
+		case SortPath:
+		case SortRecent:
+			if !a.modTime.Equal(b.modTime) {
+				return a.modTime.After(b.modTime)
+			}
+		default:
+			if len(a.result.Terms) != len(b.result.Terms) {
+				return len(a.result.Terms) > len(b.result.Terms)
+			}
+			if a.score != b.score {
+				return a.score > b.score
+			}
+		}
+		return a.result.RelativePath < b.result.RelativePath
+	})
+}
+
+// relevance scores a matching file for SortRelevance. baseScore is its Bleve score, or 1.
+func (e *queryEvaluator) relevance(result *ContentSearchResult, content string, baseScore float64, modTime time.Time) float64 {
+	score := baseScore
+	if e.matchesDefinition(result) {
+		score *= definitionBoost
+	}
+
+	name := path.Base(result.RelativePath)
+	dir := path.Dir(result.RelativePath)
+	switch {
+	case e.termMatches(name):
+		score *= fileNameBoost
+	case dir != "." && e.termMatches(dir):
+		score *= pathBoost
+	}
+
+	if isTestPath(result.RelativePath) != e.options.PreferTests {
+		score *= testFileFactor
+	}
+	if isGeneratedFile(result.RelativePath, content) {
+		score *= generatedFactor
+	}
+
+	lines := strings.Count(content, "\n") + 1
+	score /= 1 + math.Log10(1+float64(lines)/lengthNormLines)
+
+	if !modTime.IsZero() {
+		age := max(time.Since(modTime), 0)
+		score *= 1 + recencyBoost*math.Exp2(-float64(age)/float64(recencyHalfLife))
+	}
+	return score
+}
+
+// matchesDefinition reports whether a matching line of the result defines a symbol whose
+// name contains the text of one of the line's matches.
+func (e *queryEvaluator) matchesDefinition(result *ContentSearchResult) bool {
+	if e.options.FileDefinitions == nil {
+		return false
+	}
+	definitions := e.options.FileDefinitions(result.RelativePath)
+	if len(definitions) == 0 {
+		return false
+	}
+	for _, match := range result.Matches {
+		for _, name := range definitions[match.LineNumber] {
+			lowerName := strings.ToLower(name)
+			for _, span := range match.Spans {
+				text := strings.ToLower(match.LineText[span.Start:span.End])
+				if text != "" && strings.Contains(lowerName, text) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// termMatches reports whether a term of the query that is not negated matches text.
+func (e *queryEvaluator) termMatches(text string) bool {
+	for _, group := range e.groups {
+		for _, term := range group.terms {
+			if term.pattern.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testDirectories are directory names that only hold tests.
+var testDirectories = []string{"test", "tests", "__tests__", "testdata", "spec", "specs"}
+
+// isTestPath reports whether relativePath looks like a test file: foo_test.go, foo.test.ts,
+// foo.spec.js, test_foo.py, FooTest.java, FooTests.cs, or any file below a test directory.
+func isTestPath(relativePath string) bool {
+	dir, name := path.Split(relativePath)
+	for _, part := range strings.Split(strings.ToLower(dir), "/") {
+		for _, testDir := range testDirectories {
+			if part == testDir {
+				return true
+			}
+		}
+	}
+
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	lowerStem := strings.ToLower(stem)
+	return strings.HasSuffix(lowerStem, "_test") ||
+		strings.HasSuffix(lowerStem, ".test") ||
+		strings.HasSuffix(lowerStem, ".spec") ||
+		strings.HasPrefix(lowerStem, "test_") ||
+		strings.HasSuffix(stem, "Test") ||
+		strings.HasSuffix(stem, "Tests")
+}
+
+// vendorDirectories are directory names holding third-party or build output code.
+var vendorDirectories = []string{"vendor", "node_modules", "third_party", "third-party", "bower_components", "dist"}
+
+// generatedSuffixes are file name endings of generated code and lock files.
+var generatedSuffixes = []string{
+	".min.js", ".min.css", ".pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", ".pb.cc", ".pb.h",
+	".g.dart", ".freezed.dart", ".designer.cs", ".generated.ts", ".generated.cs",
+	"go.sum", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "cargo.lock", "poetry.lock", "composer.lock",
+}
+
+// generatedHeaderBytes is how much of the start of a file is checked for a generated code marker.
+const generatedHeaderBytes = 1024
+
+// isGeneratedFile reports whether a file is vendored, generated or a lock file, judged by
+// its path and by a marker such as "Code generated ... DO NOT EDIT." near its start.
+func isGeneratedFile(relativePath string, content string) bool {
+	lowerPath := strings.ToLower(relativePath)
+	dir, name := path.Split(lowerPath)
+	for _, part := range strings.Split(dir, "/") {
+		for _, vendorDir := range vendorDirectories {
+			if part == vendorDir {
+				return true
+			}
+		}
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	if strings.HasPrefix(name, "zz_generated") {
+		return true
+	}
+
+	header := content[:min(len(content), generatedHeaderBytes)]
+	return (strings.Contains(header, "Code generated") && strings.Contains(header, "DO NOT EDIT")) ||
+		strings.Contains(header, "@generated") ||
+		strings.Contains(strings.ToLower(header), "auto-generated") ||
+		strings.Contains(strings.ToLower(header), "autogenerated")
+}
@@ -0,0 +1,55 @@
+package index
+
+import "testing"
+
+func Test_isTestPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"server/server_test.go", true},
+		{"web/app.test.ts", true},
+		{"web/app.spec.js", true},
+		{"pkg/test_parser.py", true},
+		{"pkg/parser_test.py", true},
+		{"src/main/java/OrderServiceTest.java", true},
+		{"Orders/OrderTests.cs", true},
+		{"index/testdata/sample.go", true},
+		{"web/__tests__/app.js", true},
+		{"tests/fixtures.py", true},
+		{"server/server.go", false},
+		{"web/contest.ts", false},
+		{"pkg/latest.py", false},
+		{"docs/Testing.md", false},
+	}
+	for _, tt := range tests {
+		if got := isTestPath(tt.path); got != tt.want {
+			t.Errorf("isTestPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_isGeneratedFile(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    bool
+	}{
+		{"vendor/github.com/pkg/errors/errors.go", "package errors\n", true},
+		{"web/node_modules/react/index.js", "module.exports = {}\n", true},
+		{"api/order.pb.go", "package api\n", true},
+		{"web/app.min.js", "!function(){}\n", true},
+		{"go.sum", "github.com/pkg/errors v0.9.1 h1:abc\n", true},
+		{"web/package-lock.json", "{}\n", true},
+		{"api/zz_generated.deepcopy.go", "package api\n", true},
+		{"api/mock.go", "// Code generated by MockGen. DO NOT EDIT.\npackage api\n", true},
+		{"Parser.java", "// @generated\nclass Parser {}\n", true},
+		{"api/order.go", "package api\n\n// Code generated elsewhere is not marked here\n", false},
+		{"internal/vendors/list.go", "package vendors\n", false},
+	}
+	for _, tt := range tests {
+		if got := isGeneratedFile(tt.path, tt.content); got != tt.want {
+			t.Errorf("isGeneratedFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package index
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring/v2"
+)
+
+// trigramIndex is a trigram posting-list index over file contents, in the style of Zoekt/codesearch.
+// Every file is assigned a numeric document ID; each trigram (3 consecutive bytes of the
+// lowercased content, never spanning a line break) maps to the set of documents containing it.
+// It answers "which files can possibly contain this substring/regex" so that only those files
+// are verified line by line. Not thread-safe: ContentIndex guards it with its own mutex.
+type trigramIndex struct {
+	postings map[uint32]*roaring.Bitmap // key: packed trigram
+	docIDs   map[string]uint32          // key: relative path
+	paths    []string                   // index: document ID, "" for freed IDs
+	freeIDs  []uint32
+}
+
+// newTrigramIndex creates an empty trigram index.
+func newTrigramIndex() *trigramIndex {
+	return &trigramIndex{
+		postings: make(map[uint32]*roaring.Bitmap),
+		docIDs:   make(map[string]uint32),
+	}
+}
+
+// add indexes the content of a file. If the file is already indexed, remove must be called
+// first with its previous content.
+func (ti *trigramIndex) add(relativePath string, content string) {
+	docID, exists := ti.docIDs[relativePath]
+	if !exists {
+		if n := len(ti.freeIDs); n > 0 {
+			docID = ti.freeIDs[n-1]
+			ti.freeIDs = ti.freeIDs[:n-1]
+			ti.paths[docID] = relativePath
+		} else {
+			docID = uint32(len(ti.paths))
+			ti.paths = append(ti.paths, relativePath)
+		}
+		ti.docIDs[relativePath] = docID
+	}
+
+	for tri := range contentTrigrams(content) {
+		bitmap, ok := ti.postings[tri]
+		if !ok {
+			bitmap = roaring.New()
+			ti.postings[tri] = bitmap
+		}
+		bitmap.Add(docID)
+	}
+}
+
+// remove drops a file from the index. content must be the content it was indexed with.
+func (ti *trigramIndex) remove(relativePath string, content string) {
+	docID, exists := ti.docIDs[relativePath]
+	if !exists {
+		return
+	}
+
+	for tri := range contentTrigrams(content) {
+		if bitmap, ok := ti.postings[tri]; ok {
+			bitmap.Remove(docID)
+			if bitmap.IsEmpty() {
+				delete(ti.postings, tri)
+			}
+		}
+	}
+
+	delete(ti.docIDs, relativePath)
+	ti.paths[docID] = ""
+	ti.freeIDs = append(ti.freeIDs, docID)
+}
+
+// candidates returns the sorted relative paths of all files that may satisfy the query.
+func (ti *trigramIndex) candidates(q *trigramQuery) []string {
+	bitmap := ti.evaluate(q)
+
+	var paths []string
+	if bitmap == nil {
+		paths = make([]string, 0, len(ti.docIDs))
+		for path := range ti.docIDs {
+			paths = append(paths, path)
+		}
+	} else {
+		paths = make([]string, 0, bitmap.GetCardinality())
+		iterator := bitmap.Iterator()
+		for iterator.HasNext() {
+			paths = append(paths, ti.paths[iterator.Next()])
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// evaluate resolves a query to a document set. A nil result means "all documents".
+func (ti *trigramIndex) evaluate(q *trigramQuery) *roaring.Bitmap {
+	switch q.op {
+	case trigramOpAnd:
+		var result *roaring.Bitmap
+		for _, tri := range q.trigrams {
+			bitmap, ok := ti.postings[tri]
+			if !ok {
+				return roaring.New()
+			}
+			if result == nil {
+				result = bitmap.Clone()
+			} else {
+				result.And(bitmap)
+			}
+		}
+		for _, sub := range q.subs {
+			subResult := ti.evaluate(sub)
+			if subResult == nil {
+				continue
+			}
+			if result == nil {
+				result = subResult
+			} else {
+				result.And(subResult)
+			}
+		}
+		return result
+
+	case trigramOpOr:
+		bitmaps := make([]*roaring.Bitmap, 0, len(q.subs))
+		for _, sub := range q.subs {
+			subResult := ti.evaluate(sub)
+			if subResult == nil {
+				return nil
+			}
+			bitmaps = append(bitmaps, subResult)
+		}
+		return roaring.FastOr(bitmaps...)
+	}
+
+	return nil
+}
+
+// contentTrigrams returns the set of trigrams in the lowercased content, skipping
+// trigrams that span a line break (matching is always done line by line).
+func contentTrigrams(content string) map[uint32]struct{} {
+	lower := strings.ToLower(content)
+	trigrams := make(map[uint32]struct{})
+	for i := 0; i+3 <= len(lower); i++ {
+		if lower[i] == '\n' || lower[i+1] == '\n' || lower[i+2] == '\n' {
+			continue
+		}
+		trigrams[packTrigram(lower[i], lower[i+1], lower[i+2])] = struct{}{}
+	}
+	return trigrams
+}
+
+func packTrigram(a, b, c byte) uint32 {
+	return uint32(a)<<16 | uint32(b)<<8 | uint32(c)
+}
+
+// trigramOp is the operator of a trigramQuery node.
+type trigramOp int
+
+const (
+	// trigramOpAll matches every document (no usable trigrams).
+	trigramOpAll trigramOp = iota
+	// trigramOpAnd requires all trigrams and all sub-queries.
+	trigramOpAnd
+	// trigramOpOr requires at least one sub-query.
+	trigramOpOr
+)
+
+// trigramQuery is a boolean query over trigrams, evaluated against the posting lists.
+type trigramQuery struct {
+	op       trigramOp
+	trigrams []uint32
+	subs     []*trigramQuery
+}
+
+var matchAllTrigramQuery = &trigramQuery{op: trigramOpAll}
+
+// literalTrigramQuery requires every trigram of the (case-folded) literal.
+// Literals shorter than 3 bytes cannot be filtered and match all documents.
+func literalTrigramQuery(literal string) *trigramQuery {
+	lower := strings.ToLower(literal)
+	if len(lower) < 3 {
+		return matchAllTrigramQuery
+	}
+	seen := make(map[uint32]bool)
+	q := &trigramQuery{op: trigramOpAnd}
+	for i := 0; i+3 <= len(lower); i++ {
+		tri := packTrigram(lower[i], lower[i+1], lower[i+2])
+		if !seen[tri] {
+			seen[tri] = true
+			q.trigrams = append(q.trigrams, tri)
+		}
+	}
+	return q
+}
+
+// regexpTrigramQuery derives a trigram query from a regular expression. The result is a
+// necessary condition: every file with a matching line satisfies it, but not vice versa.
+func regexpTrigramQuery(pattern string) (*trigramQuery, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return regexpNodeQuery(re.Simplify()), nil
+}
+
+// regexpNodeQuery computes the trigram query for one regexp syntax node.
+func regexpNodeQuery(re *syntax.Regexp) *trigramQuery {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return literalTrigramQuery(string(re.Rune))
+
+	case syntax.OpCapture:
+		return regexpNodeQuery(re.Sub[0])
+
+	case syntax.OpPlus:
+		return regexpNodeQuery(re.Sub[0])
+
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return regexpNodeQuery(re.Sub[0])
+		}
+		return matchAllTrigramQuery
+
+	case syntax.OpConcat:
+		return concatTrigramQuery(re.Sub)
+
+	case syntax.OpAlternate:
+		q := &trigramQuery{op: trigramOpOr}
+		for _, sub := range re.Sub {
+			subQuery := regexpNodeQuery(sub)
+			if subQuery.op == trigramOpAll {
+				return matchAllTrigramQuery
+			}
+			q.subs = append(q.subs, subQuery)
+		}
+		return q
+	}
+
+	return matchAllTrigramQuery
+}
+
+// concatTrigramQuery joins adjacent literals of a concatenation into runs (zero-width
+// assertions do not interrupt a run) and requires the trigrams of every run plus the
+// queries of all other parts.
+func concatTrigramQuery(subs []*syntax.Regexp) *trigramQuery {
+	q := &trigramQuery{op: trigramOpAnd}
+	var run strings.Builder
+
+	flushRun := func() {
+		if run.Len() > 0 {
+			addAndOperand(q, literalTrigramQuery(run.String()))
+			run.Reset()
+		}
+	}
+
+	for _, sub := range subs {
+		switch sub.Op {
+		case syntax.OpLiteral:
+			run.WriteString(string(sub.Rune))
+		case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+			syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			// zero-width: the text on both sides is adjacent
+		default:
+			flushRun()
+			addAndOperand(q, regexpNodeQuery(sub))
+		}
+	}
+	flushRun()
+
+	if len(q.trigrams) == 0 && len(q.subs) == 0 {
+		return matchAllTrigramQuery
+	}
+	return q
+}
+
+// addAndOperand adds operand to an AND query, flattening nested ANDs and skipping match-all.
+func addAndOperand(q *trigramQuery, operand *trigramQuery) {
+	switch operand.op {
+	case trigramOpAll:
+		return
+	case trigramOpAnd:
+		q.trigrams = append(q.trigrams, operand.trigrams...)
+		q.subs = append(q.subs, operand.subs...)
+	default:
+		q.subs = append(q.subs, operand)
+	}
+}
@@ -0,0 +1,108 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestTrigramIndex(files map[string]string) *trigramIndex {
+	ti := newTrigramIndex()
+	for path, content := range files {
+		ti.add(path, content)
+	}
+	return ti
+}
+
+func Test_trigramIndex_LiteralCandidates(t *testing.T) {
+	ti := newTestTrigramIndex(map[string]string{
+		"a.go": "if err != nil {\n\treturn err\n}",
+		"b.go": "func main() {}",
+		"c.go": "errors.New(\"x\")",
+	})
+
+	candidates := ti.candidates(literalTrigramQuery("err != nil"))
+	if strings.Join(candidates, ",") != "a.go" {
+		t.Errorf("expected [a.go], got %v", candidates)
+	}
+
+	// Case-insensitive
+	candidates = ti.candidates(literalTrigramQuery("FUNC MAIN"))
+	if strings.Join(candidates, ",") != "b.go" {
+		t.Errorf("expected [b.go], got %v", candidates)
+	}
+}
+
+func Test_trigramIndex_ShortLiteralMatchesAll(t *testing.T) {
+	ti := newTestTrigramIndex(map[string]string{"a.go": "x", "b.go": "y"})
+
+	candidates := ti.candidates(literalTrigramQuery("ab"))
+	if len(candidates) != 2 {
+		t.Errorf("expected all files for a short literal, got %v", candidates)
+	}
+}
+
+func Test_trigramIndex_SkipsTrigramsAcrossLines(t *testing.T) {
+	ti := newTestTrigramIndex(map[string]string{"a.go": "ab\ncd"})
+
+	candidates := ti.candidates(literalTrigramQuery("b\nc"))
+	if len(candidates) != 0 {
+		t.Errorf("expected no candidates for a literal spanning lines, got %v", candidates)
+	}
+}
+
+func Test_trigramIndex_RemoveAndReuseID(t *testing.T) {
+	ti := newTestTrigramIndex(map[string]string{"a.go": "hello world", "b.go": "goodbye"})
+
+	ti.remove("a.go", "hello world")
+	if candidates := ti.candidates(literalTrigramQuery("hello")); len(candidates) != 0 {
+		t.Errorf("expected no candidates after remove, got %v", candidates)
+	}
+
+	ti.add("c.go", "hello again")
+	candidates := ti.candidates(literalTrigramQuery("hello"))
+	if strings.Join(candidates, ",") != "c.go" {
+		t.Errorf("expected [c.go], got %v", candidates)
+	}
+	if len(ti.postings) == 0 {
+		t.Error("expected postings to remain for indexed files")
+	}
+}
+
+func Test_regexpTrigramQuery_Candidates(t *testing.T) {
+	ti := newTestTrigramIndex(map[string]string{
+		"handler.go": "func UserHandler() {}",
+		"service.go": "func UserService() {}",
+		"empty.go":   "package empty",
+	})
+
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{`func\s+\w+Handler`, "handler.go"},
+		{`Handler|Service`, "handler.go,service.go"},
+		{`(?i)userhandler`, "handler.go"},
+		{`^func User(Handler)+`, "handler.go"},
+		{`\w+`, "empty.go,handler.go,service.go"},   // no trigrams, all files
+		{`User(Handler)?`, "handler.go,service.go"}, // optional part is not required
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			q, err := regexpTrigramQuery(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			candidates := ti.candidates(q)
+			if strings.Join(candidates, ",") != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, candidates)
+			}
+		})
+	}
+}
+
+func Test_regexpTrigramQuery_InvalidPattern(t *testing.T) {
+	if _, err := regexpTrigramQuery(`func(`); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
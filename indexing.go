@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,125 +14,298 @@ import (
 	"github.com/lexandro/codeindex-mcp/ignore"
 	"github.com/lexandro/codeindex-mcp/index"
 	"github.com/lexandro/codeindex-mcp/language"
+	"github.com/lexandro/codeindex-mcp/symbols"
 	"github.com/lexandro/codeindex-mcp/watcher"
+	"github.com/lexandro/codeindex-mcp/workspace"
 )
 
+// IndexOptions configures how files are read and written to the indexes.
+type IndexOptions struct {
+	Workers   int // goroutines reading and analyzing files; 0 means DefaultIndexWorkers
+	BatchSize int // files written to the content index at once; 0 means DefaultIndexBatchSize
+	// Progress receives the number of files found and processed; nil if not tracked
+	Progress *index.Progress
+	// VerifyContent makes sync hash every file instead of only those whose size or
+	// modification time changed. reconcileWorkspace always sets it
+	VerifyContent bool
+}
+
+const (
+	// DefaultIndexWorkers is the default number of goroutines reading files.
+	DefaultIndexWorkers = 8
+	// DefaultIndexBatchSize is the default number of files per content index write.
+	DefaultIndexBatchSize = 100
+)
+
+// withDefaults returns the options with unset fields replaced by their defaults.
+func (o IndexOptions) withDefaults() IndexOptions {
+	if o.Workers <= 0 {
+		o.Workers = DefaultIndexWorkers
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultIndexBatchSize
+	}
+	return o
+}
+
+// indexJob is a file to read and index.
+type indexJob struct {
+	path    string
+	relPath string
+	info    os.FileInfo
+}
+
+// preparedFile is a file that was read and analyzed, ready to be written to the indexes.
+type preparedFile struct {
+	indexedFile *index.IndexedFile
+	content     string
+	symbols     []symbols.Symbol
+}
+
 // performIndexing walks the root directory and indexes all eligible files.
-// Returns the number of files indexed and total bytes processed.
+// Returns the number of files indexed and total bytes processed. If ctx is cancelled, it
+// stops early and the indexes hold only part of the files; callers check ctx.Err().
 func performIndexing(
-	rootDir string,
+	ctx context.Context,
+	root workspace.Root,
 	fileIndex *index.FileIndex,
 	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
 	ignoreMatcher *ignore.Matcher,
+	options IndexOptions,
 	logger *slog.Logger,
 ) (int, int64) {
 	var indexedCount int
 	var totalSize int64
-	var mu sync.Mutex
 
-	// Use a bounded worker pool for parallel file reading
-	const workerCount = 8
-	type indexJob struct {
-		path    string
-		relPath string
-		info    os.FileInfo
-	}
+	// Walk directory tree while the files found so far are being indexed
 	jobs := make(chan indexJob, 100)
+	go func() {
+		defer close(jobs)
+		filepath.WalkDir(root.Dir, func(path string, d os.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root.Dir && ignoreMatcher.ShouldIgnoreDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ignoreMatcher.ShouldIgnore(path) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if ignoreMatcher.IsFileTooLarge(info.Size()) {
+				return nil
+			}
+			relPath, _ := filepath.Rel(root.Dir, path)
+			relPath = root.IndexPath(filepath.ToSlash(relPath))
+			options.Progress.AddDiscovered(1)
+			select {
+			case jobs <- indexJob{path: path, relPath: relPath, info: info}:
+			case <-ctx.Done():
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	}()
+
+	indexFiles(ctx, jobs, fileIndex, contentIndex, symbolIndex, options, func(job indexJob, changed bool, err error) {
+		if err != nil {
+			logger.Debug("skipped file", "path", job.relPath, "error", err)
+			return
+		}
+		indexedCount++
+		totalSize += job.info.Size()
+	})
+	return indexedCount, totalSize
+}
+
+// indexFiles indexes the files received from jobs until the channel is closed. Files are
+// read and analyzed by options.Workers goroutines and written to the content index in
+// batches of options.BatchSize, since Bleve serializes writes. report is called on the
+// calling goroutine for every job once it is written: changed reports whether the content
+// was (re-)indexed, err why the file was skipped. Once ctx is cancelled, the remaining jobs
+// are skipped with ctx.Err().
+func indexFiles(
+	ctx context.Context,
+	jobs <-chan indexJob,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	options IndexOptions,
+	report func(job indexJob, changed bool, err error),
+) {
+	options = options.withDefaults()
+
+	type prepareResult struct {
+		job  indexJob
+		file *preparedFile // nil if the content is unchanged
+		err  error
+	}
+	results := make(chan prepareResult, options.BatchSize)
 
 	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
+	for i := 0; i < options.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				if err := indexSingleFile(job.path, job.relPath, job.info, rootDir, fileIndex, contentIndex, ignoreMatcher); err != nil {
-					logger.Debug("skipped file", "path", job.relPath, "error", err)
+				if err := ctx.Err(); err != nil {
+					results <- prepareResult{job: job, err: err}
 					continue
 				}
-				mu.Lock()
-				indexedCount++
-				totalSize += job.info.Size()
-				mu.Unlock()
+				file, err := prepareFile(job.path, job.relPath, job.info, fileIndex)
+				results <- prepareResult{job: job, file: file, err: err}
 			}
 		}()
 	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	// Walk directory tree
-	filepath.WalkDir(rootDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
+	batch := make([]prepareResult, 0, options.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
 		}
-		if d.IsDir() {
-			if path != rootDir && ignoreMatcher.ShouldIgnoreDir(path) {
-				return filepath.SkipDir
-			}
-			return nil
+		files := make([]*preparedFile, len(batch))
+		for i, result := range batch {
+			files[i] = result.file
 		}
-		if ignoreMatcher.ShouldIgnore(path) {
-			return nil
+		err := commitFiles(files, fileIndex, contentIndex, symbolIndex)
+		options.Progress.AddProcessed(len(batch))
+		for _, result := range batch {
+			// One bad file fails its whole batch: retry each file alone, so only that one is skipped
+			fileErr := err
+			if err != nil && len(batch) > 1 {
+				fileErr = commitFiles([]*preparedFile{result.file}, fileIndex, contentIndex, symbolIndex)
+			}
+			report(result.job, fileErr == nil, fileErr)
 		}
-		info, err := d.Info()
-		if err != nil {
-			return nil
+		batch = batch[:0]
+	}
+
+	for result := range results {
+		if result.err != nil || result.file == nil {
+			options.Progress.AddProcessed(1)
+			report(result.job, false, result.err)
+			continue
 		}
-		if ignoreMatcher.IsFileTooLarge(info.Size()) {
-			return nil
+		batch = append(batch, result)
+		if len(batch) >= options.BatchSize {
+			flush()
 		}
-		relPath, _ := filepath.Rel(rootDir, path)
-		relPath = filepath.ToSlash(relPath)
-		jobs <- indexJob{path: path, relPath: relPath, info: info}
-		return nil
-	})
-
-	close(jobs)
-	wg.Wait()
-	return indexedCount, totalSize
+	}
+	flush()
 }
 
-// indexSingleFile reads and indexes one file into both indexes.
+// indexSingleFile reads and indexes one file into the file, content and symbol indexes.
+// If the content hash matches the indexed version, only the file metadata is refreshed.
+// Returns whether the content changed.
 func indexSingleFile(
 	absolutePath string,
 	relativePath string,
 	info os.FileInfo,
-	rootDir string,
 	fileIndex *index.FileIndex,
 	contentIndex *index.ContentIndex,
-	ignoreMatcher *ignore.Matcher,
-) error {
+	symbolIndex *symbols.Index,
+) (bool, error) {
+	file, err := prepareFile(absolutePath, relativePath, info, fileIndex)
+	if err != nil || file == nil {
+		return false, err
+	}
+	if err := commitFiles([]*preparedFile{file}, fileIndex, contentIndex, symbolIndex); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// prepareFile reads a file and extracts what is needed to index it. If the content hash
+// matches the indexed version, only the file metadata is refreshed and nil is returned.
+func prepareFile(
+	absolutePath string,
+	relativePath string,
+	info os.FileInfo,
+	fileIndex *index.FileIndex,
+) (*preparedFile, error) {
 	// Read file content with retry for Windows file locking
 	content, err := readFileWithRetry(absolutePath)
 	if err != nil {
-		return fmt.Errorf("reading file: %w", err)
+		return nil, fmt.Errorf("reading file: %w", err)
 	}
 
 	// Skip binary files
 	if language.IsBinaryContent(content) {
-		return fmt.Errorf("binary file")
+		return nil, fmt.Errorf("binary file")
+	}
+
+	// Identical bytes (e.g. a checkout or formatter rewriting a file) need no re-indexing
+	contentHash := index.ContentHash(content)
+	if indexed := fileIndex.GetFile(relativePath); indexed != nil && indexed.ContentHash == contentHash {
+		refreshed := *indexed
+		refreshed.Path = absolutePath
+		refreshed.SizeBytes = info.Size()
+		refreshed.ModTime = info.ModTime()
+		fileIndex.AddFile(&refreshed)
+		return nil, nil
 	}
 
 	contentStr := string(content)
-	lineCount := strings.Count(contentStr, "\n") + 1
 	lang := language.DetectLanguage(absolutePath)
+	return &preparedFile{
+		indexedFile: &index.IndexedFile{
+			Path:         absolutePath,
+			RelativePath: relativePath,
+			Language:     lang,
+			SizeBytes:    info.Size(),
+			ModTime:      info.ModTime(),
+			LineCount:    strings.Count(contentStr, "\n") + 1,
+			ContentHash:  contentHash,
+		},
+		content: contentStr,
+		symbols: symbols.Extract(relativePath, contentStr, lang),
+	}, nil
+}
 
-	// Add to file index
-	indexedFile := &index.IndexedFile{
-		Path:         absolutePath,
-		RelativePath: relativePath,
-		Language:     lang,
-		SizeBytes:    info.Size(),
-		ModTime:      info.ModTime(),
-		LineCount:    lineCount,
+// commitFiles writes prepared files to the indexes, the content index in a single batch.
+func commitFiles(
+	files []*preparedFile,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+) error {
+	docs := make([]index.Document, len(files))
+	for i, file := range files {
+		docs[i] = index.Document{
+			RelativePath: file.indexedFile.RelativePath,
+			Content:      file.content,
+			Language:     file.indexedFile.Language,
+		}
 	}
-	fileIndex.AddFile(indexedFile)
-
-	// Add to content index
-	if err := contentIndex.IndexFile(relativePath, contentStr, lang); err != nil {
+	if err := indexContent(contentIndex, docs); err != nil {
 		return fmt.Errorf("indexing content: %w", err)
 	}
 
+	// Add to file index last, so that a failed update is retried instead of matching the hash
+	for _, file := range files {
+		symbolIndex.SetFile(file.indexedFile.RelativePath, file.symbols)
+		fileIndex.AddFile(file.indexedFile)
+	}
 	return nil
 }
 
+// indexContent writes documents to the content index; tests replace it to inject failures.
+var indexContent = (*index.ContentIndex).IndexBatch
+
 // readFileWithRetry attempts to read a file, retrying once after a short delay
 // if the file is locked (common on Windows when editors are saving).
 func readFileWithRetry(path string) ([]byte, error) {
@@ -148,31 +322,38 @@ func readFileWithRetry(path string) ([]byte, error) {
 }
 
 // handleWatcherEvents processes debounced file system events and updates the indexes.
+// Each batch of events holds generation for reading, so it is applied to one generation
+// of the indexes and never to some indexes before a rebuild swaps them and others after.
 func handleWatcherEvents(
 	fileWatcher *watcher.Watcher,
-	rootDir string,
+	root workspace.Root,
 	fileIndex *index.FileIndex,
 	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
 	ignoreMatcher *ignore.Matcher,
+	generation *sync.RWMutex,
+	options IndexOptions,
 	logger *slog.Logger,
 ) {
 	for events := range fileWatcher.Events() {
+		generation.RLock()
 		for _, event := range events {
-			relPath, _ := filepath.Rel(rootDir, event.Path)
-			relPath = filepath.ToSlash(relPath)
+			relPath, _ := filepath.Rel(root.Dir, event.Path)
+			relPath = root.IndexPath(filepath.ToSlash(relPath))
 
 			switch event.Op {
 			case watcher.OpRemove, watcher.OpRename:
-				fileIndex.RemoveFile(relPath)
-				contentIndex.RemoveFile(relPath)
-				logger.Debug("removed from index", "path", relPath)
+				if ignore.IsIgnoreFile(filepath.Base(event.Path)) {
+					applyIgnoreChange(filepath.Dir(event.Path), fileWatcher, root, fileIndex, contentIndex, symbolIndex, ignoreMatcher, options, logger)
+				}
+				// The path may have been a directory: drop everything that was indexed below it
+				removed := removeFromIndexes(root, event.Path, fileIndex, contentIndex, symbolIndex)
+				logger.Debug("removed from index", "path", relPath, "files", removed)
 
 			case watcher.OpCreate, watcher.OpWrite:
-				// Check if this is a .gitignore or .claudeignore change
-				baseName := filepath.Base(event.Path)
-				if baseName == ".gitignore" || baseName == ".claudeignore" {
-					ignoreMatcher.Reload()
-					logger.Info("reloaded ignore rules", "trigger", baseName)
+				// A .gitignore or .claudeignore change only affects the rules of its directory
+				if ignore.IsIgnoreFile(filepath.Base(event.Path)) {
+					applyIgnoreChange(filepath.Dir(event.Path), fileWatcher, root, fileIndex, contentIndex, symbolIndex, ignoreMatcher, options, logger)
 					continue
 				}
 
@@ -184,20 +365,87 @@ func handleWatcherEvents(
 				if err != nil {
 					continue
 				}
+				// A new or moved-in directory: index the files it already contains
 				if info.IsDir() {
+					result := syncSubtree(context.Background(), root, event.Path, fileIndex, contentIndex, symbolIndex, ignoreMatcher, options, logger)
+					logger.Debug("indexed new directory", "path", relPath, "files", result.MissingFiles, "duration", result.Duration)
 					continue
 				}
 				if ignoreMatcher.IsFileTooLarge(info.Size()) {
 					continue
 				}
 
-				err = indexSingleFile(event.Path, relPath, info, rootDir, fileIndex, contentIndex, ignoreMatcher)
+				changed, err := indexSingleFile(event.Path, relPath, info, fileIndex, contentIndex, symbolIndex)
 				if err != nil {
 					logger.Debug("skipped file update", "path", relPath, "error", err)
 					continue
 				}
+				if !changed {
+					logger.Debug("content unchanged, skipped re-indexing", "path", relPath)
+					continue
+				}
 				logger.Debug("updated index", "path", relPath)
 			}
 		}
+		generation.RUnlock()
+	}
+}
+
+// removeFromIndexes removes a file, or every file below a directory, of root from the indexes.
+// Returns the number of indexed files removed.
+func removeFromIndexes(
+	root workspace.Root,
+	absolutePath string,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+) int {
+	relPaths := fileIndex.PathsWithPrefix(subtreePrefix(root, absolutePath))
+	if absolutePath != root.Dir {
+		relPath, _ := filepath.Rel(root.Dir, absolutePath)
+		relPaths = append(relPaths, root.IndexPath(filepath.ToSlash(relPath)))
+	}
+
+	removed := 0
+	for _, relPath := range relPaths {
+		if fileIndex.GetFile(relPath) != nil {
+			removed++
+		}
+		fileIndex.RemoveFile(relPath)
+		contentIndex.RemoveFile(relPath)
+		symbolIndex.RemoveFile(relPath)
 	}
+	return removed
+}
+
+// applyIgnoreChange reloads the ignore rules of dir after one of its ignore files changed and
+// re-evaluates the subtree they apply to: newly ignored files are removed from the indexes,
+// newly included files are indexed, and directory watches are added or removed to match.
+func applyIgnoreChange(
+	dir string,
+	fileWatcher *watcher.Watcher,
+	root workspace.Root,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	ignoreMatcher *ignore.Matcher,
+	options IndexOptions,
+	logger *slog.Logger,
+) {
+	ignoreMatcher.ReloadDir(dir)
+
+	var watchesAdded, watchesRemoved int
+	if fileWatcher != nil {
+		watchesAdded, watchesRemoved = fileWatcher.Resync(dir)
+	}
+	result := syncSubtree(context.Background(), root, dir, fileIndex, contentIndex, symbolIndex, ignoreMatcher, options, logger)
+
+	logger.Info("reloaded ignore rules",
+		"dir", dir,
+		"indexed", result.MissingFiles,
+		"removed", result.StaleFiles,
+		"watchesAdded", watchesAdded,
+		"watchesRemoved", watchesRemoved,
+		"duration", result.Duration,
+	)
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+)
+
+// writeSyntheticTree creates fileCount small Go files spread over 50 packages below rootDir
+// and returns their total size in bytes.
+func writeSyntheticTree(tb testing.TB, rootDir string, fileCount int) int64 {
+	tb.Helper()
+	var totalSize int64
+	for i := 0; i < fileCount; i++ {
+		dir := filepath.Join(rootDir, fmt.Sprintf("pkg%d", i%50))
+		os.MkdirAll(dir, 0755)
+		content := fmt.Sprintf("package pkg%d\n\n// Handler%d serves requests.\nfunc Handler%d(ctx context.Context, req *Request) (*Response, error) {\n\treturn process(ctx, req, %d)\n}\n", i%50, i, i, i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", i)), []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+		totalSize += int64(len(content))
+	}
+	return totalSize
+}
+
+func Test_performIndexing_Batches(t *testing.T) {
+	tmpDir := t.TempDir()
+	wantSize := writeSyntheticTree(t, tmpDir, 53)
+	os.WriteFile(filepath.Join(tmpDir, "image.dat"), []byte("PNG\x00\x01"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+
+	// A batch size that does not divide the file count leaves a partial final batch
+	progress := index.NewProgress(index.PhaseIndexing)
+	count, size := performIndexing(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbolIndex, testIgnoreMatcher(tmpDir), IndexOptions{Workers: 3, BatchSize: 10, Progress: progress}, testLogger())
+
+	if count != 53 || size != wantSize {
+		t.Errorf("expected 53 files of %d bytes, got %d files of %d bytes", wantSize, count, size)
+	}
+	if fileIndex.FileCount() != 53 || contentIndex.DocumentCount() != 53 {
+		t.Errorf("expected 53 files in both indexes, got %d and %d", fileIndex.FileCount(), contentIndex.DocumentCount())
+	}
+	if state := progress.State(); state.Processed != 54 || state.Discovered != 54 {
+		t.Errorf("expected 54 files found and processed including the binary one, got %+v", state)
+	}
+	if len(symbolIndex.FileSymbols("pkg2/file52.go")) == 0 {
+		t.Error("expected symbols of the last batch to be recorded")
+	}
+	results, _, err := contentIndex.Search(context.Background(), index.SearchOptions{Query: "Handler52", MaxResults: 10})
+	if err != nil || len(results) != 1 {
+		t.Errorf("expected Handler52 to be searchable, got %d results (err %v)", len(results), err)
+	}
+}
+
+func Test_performIndexing_FailedBatchRetriesFilesAlone(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeSyntheticTree(t, tmpDir, 25)
+
+	// The content index rejects any batch that contains pkg3/file3.go
+	indexContent = func(contentIndex *index.ContentIndex, docs []index.Document) error {
+		for _, doc := range docs {
+			if doc.RelativePath == "pkg3/file3.go" {
+				return errors.New("injected failure")
+			}
+		}
+		return contentIndex.IndexBatch(docs)
+	}
+	defer func() { indexContent = (*index.ContentIndex).IndexBatch }()
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	count, _ := performIndexing(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), testIgnoreMatcher(tmpDir), IndexOptions{Workers: 2, BatchSize: 10}, testLogger())
+
+	if count != 24 || fileIndex.FileCount() != 24 || contentIndex.DocumentCount() != 24 {
+		t.Errorf("expected all files but the failing one indexed, got %d counted, %d and %d in the indexes",
+			count, fileIndex.FileCount(), contentIndex.DocumentCount())
+	}
+	if fileIndex.GetFile("pkg3/file3.go") != nil {
+		t.Error("expected the failing file to be skipped")
+	}
+}
+
+func Test_performIndexing_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeSyntheticTree(t, tmpDir, 20)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	count, _ := performIndexing(ctx, testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), testIgnoreMatcher(tmpDir), IndexOptions{}, testLogger())
+
+	if count != 0 || fileIndex.FileCount() != 0 || contentIndex.DocumentCount() != 0 {
+		t.Errorf("expected nothing indexed after cancellation, got %d files", count)
+	}
+}
+
+// Benchmark_performIndexing measures the initial indexing throughput of a synthetic tree
+// for several worker and batch size settings.
+func Benchmark_performIndexing(b *testing.B) {
+	const fileCount = 5000
+	tmpDir := b.TempDir()
+	totalSize := writeSyntheticTree(b, tmpDir, fileCount)
+	root := testRoot(tmpDir)
+	matcher := testIgnoreMatcher(tmpDir)
+	logger := testLogger()
+
+	for _, options := range []IndexOptions{
+		{Workers: 1, BatchSize: 1},
+		{Workers: 8, BatchSize: 1},
+		{Workers: 8, BatchSize: 100},
+		{Workers: 8, BatchSize: 1000},
+	} {
+		b.Run(fmt.Sprintf("workers=%d/batch=%d", options.Workers, options.BatchSize), func(b *testing.B) {
+			b.SetBytes(totalSize)
+			for b.Loop() {
+				contentIndex, _ := index.NewContentIndex()
+				performIndexing(context.Background(), root, index.NewFileIndex(), contentIndex, symbols.NewIndex(), matcher, options, logger)
+				contentIndex.Close()
+			}
+			b.ReportMetric(float64(fileCount*b.N)/b.Elapsed().Seconds(), "files/s")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// unreadableGracePeriod is how long a lock file that cannot be decoded is assumed to be
+// in the middle of being written by its owner.
+const unreadableGracePeriod = 10 * time.Second
+
+// ErrLocked is returned by Acquire when the lock is held by another live process.
+var ErrLocked = errors.New("lock held by another process")
+
+// owner is the part of every lock file that identifies the holding process.
+type owner struct {
+	PID int `json:"pid"`
+}
+
+// Acquire atomically creates the lock file at path containing data encoded as JSON.
+// data must encode a "pid" field with the current process ID. A lock file left behind by
+// a process that no longer runs is replaced. Returns ErrLocked if another live process holds it.
+func Acquire(path string, data any) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding lock file: %w", err)
+	}
+
+	// Two attempts: the second one after removing a stale lock
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, writeErr := file.Write(encoded)
+			closeErr := file.Close()
+			if writeErr != nil || closeErr != nil {
+				os.Remove(path)
+				return fmt.Errorf("writing lock file %s: %w", path, errors.Join(writeErr, closeErr))
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("creating lock file %s: %w", path, err)
+		}
+
+		var holder owner
+		readErr := Read(path, &holder)
+		switch {
+		case errors.Is(readErr, os.ErrNotExist):
+			// Released in the meantime
+		case readErr != nil:
+			// A lock being written right now is briefly empty; only an old unreadable one is stale
+			if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) < unreadableGracePeriod {
+				return ErrLocked
+			}
+			os.Remove(path)
+		case holder.PID > 0 && ProcessAlive(holder.PID):
+			return ErrLocked
+		default:
+			os.Remove(path)
+		}
+	}
+	return ErrLocked
+}
+
+// Read decodes the lock file at path into v.
+func Read(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding lock file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Release removes the lock file if it is held by the current process.
+func Release(path string) {
+	var holder owner
+	if err := Read(path, &holder); err != nil || holder.PID != os.Getpid() {
+		return
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,88 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testLock struct {
+	PID  int    `json:"pid"`
+	Note string `json:"note"`
+}
+
+func Test_Acquire_CreatesAndReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	if err := Acquire(path, testLock{PID: os.Getpid(), Note: "mine"}); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	var lock testLock
+	if err := Read(path, &lock); err != nil {
+		t.Fatal(err)
+	}
+	if lock.PID != os.Getpid() || lock.Note != "mine" {
+		t.Errorf("unexpected lock contents: %+v", lock)
+	}
+
+	Release(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected lock file to be removed, stat err: %v", err)
+	}
+}
+
+func Test_Acquire_HeldByLiveProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	if err := Acquire(path, testLock{PID: os.Getpid()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Acquire(path, testLock{PID: os.Getpid()}); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+}
+
+func Test_Acquire_ReplacesStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	// No process runs with this PID (above the Linux pid_max limit)
+	os.WriteFile(path, []byte(`{"pid": 2147483647}`), 0600)
+
+	if err := Acquire(path, testLock{PID: os.Getpid(), Note: "new"}); err != nil {
+		t.Fatalf("expected stale lock to be replaced, got %v", err)
+	}
+	var lock testLock
+	Read(path, &lock)
+	if lock.Note != "new" {
+		t.Errorf("expected new lock contents, got %+v", lock)
+	}
+}
+
+func Test_Acquire_UnreadableLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	// A fresh empty lock is being written by its owner
+	os.WriteFile(path, nil, 0600)
+	if err := Acquire(path, testLock{PID: os.Getpid()}); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked for a lock being written, got %v", err)
+	}
+
+	// An old empty lock was left behind by a crash
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(path, old, old)
+	if err := Acquire(path, testLock{PID: os.Getpid()}); err != nil {
+		t.Errorf("expected old unreadable lock to be replaced, got %v", err)
+	}
+}
+
+func Test_Release_IgnoresOtherOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	os.WriteFile(path, []byte(`{"pid": 1}`), 0600)
+
+	Release(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected lock of another process to be kept, stat err: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package lockfile
+
+import "os"
+
+// ProcessAlive reports whether a process with the given PID exists.
+func ProcessAlive(pid int) bool {
+	// On Windows FindProcess opens a handle and fails if the process does not exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
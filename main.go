@@ -2,21 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/lexandro/codeindex-mcp/daemon"
 	"github.com/lexandro/codeindex-mcp/ignore"
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/lockfile"
 	"github.com/lexandro/codeindex-mcp/register"
 	"github.com/lexandro/codeindex-mcp/server"
+	"github.com/lexandro/codeindex-mcp/snapshot"
+	"github.com/lexandro/codeindex-mcp/symbols"
 	"github.com/lexandro/codeindex-mcp/tools"
 	"github.com/lexandro/codeindex-mcp/watcher"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -29,6 +39,15 @@ func (e *excludePatterns) Set(value string) error {
 	return nil
 }
 
+// rootSpecs is a repeatable CLI flag for project roots ("dir" or "name=dir").
+type rootSpecs []string
+
+func (r *rootSpecs) String() string { return strings.Join(*r, ", ") }
+func (r *rootSpecs) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
 // forceIncludePatterns is a repeatable CLI flag for force-include patterns that override all excludes.
 type forceIncludePatterns []string
 
@@ -44,19 +63,38 @@ func main() {
 		register.Run(register.DeriveServerName(os.Args[0]), os.Args[2:])
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "explain-ignore" {
+		os.Exit(runExplainIgnore(os.Args[2:], os.Stdout, os.Stderr))
+	}
 
 	// Parse CLI flags
-	var rootDir string
+	var roots rootSpecs
+	var workspaceFile string
 	var maxFileSizeBytes int64
 	var maxResults int
 	var logLevel string
 	var logFile string
 	var logEnabled bool
 	var syncInterval int
+	var cacheBaseDir string
+	var noCache bool
+	var httpAddr string
+	var unixSocket string
+	var authToken string
+	var shared bool
+	var daemonMode bool
+	var idleTimeout time.Duration
+	var watchMode string
+	var pollInterval time.Duration
+	var indexWorkers int
+	var indexBatchSize int
+	var indexWait time.Duration
+	var verifyContent bool
 	var excludes excludePatterns
 	var forceIncludes forceIncludePatterns
 
-	flag.StringVar(&rootDir, "root", "", "Project root directory (default: current working directory)")
+	flag.Var(&roots, "root", "Project root directory, optionally named as name=dir (repeatable; default: current working directory)")
+	flag.StringVar(&workspaceFile, "workspace", "", "JSON workspace file listing named roots to index together")
 	flag.Var(&excludes, "exclude", "Extra ignore pattern (repeatable)")
 	flag.Var(&forceIncludes, "force-include", "Force-include pattern that overrides all excludes (repeatable)")
 	flag.Int64Var(&maxFileSizeBytes, "max-file-size", 1024*1024, "Maximum file size in bytes (default: 1MB)")
@@ -65,6 +103,20 @@ func main() {
 	flag.StringVar(&logFile, "log-file", "", "Log file path (default: codeindex-mcp.log in root dir)")
 	flag.BoolVar(&logEnabled, "log-enabled", true, "Enable logging (default: true, set to false to disable all logging)")
 	flag.IntVar(&syncInterval, "sync-interval", 0, "Periodic sync interval in seconds (0 = disabled)")
+	flag.StringVar(&watchMode, "watch-mode", "auto", "Change detection: auto (fsnotify, polling where it fails) | fsnotify | poll (for NFS, SSHFS, container volumes, WSL shares)")
+	flag.DurationVar(&pollInterval, "poll-interval", watcher.DefaultPollInterval, "Scan interval of polled directories")
+	flag.IntVar(&indexWorkers, "index-workers", DefaultIndexWorkers, "Number of goroutines reading files during indexing")
+	flag.IntVar(&indexBatchSize, "index-batch-size", DefaultIndexBatchSize, "Number of files written to the search index at once")
+	flag.BoolVar(&verifyContent, "verify-content", false, "Hash every file on each --sync-interval sync to catch edits that keep size and modification time (warm start and reindex always do)")
+	flag.DurationVar(&indexWait, "index-wait", 0, "How long tool calls wait for the startup indexing before answering from the partial index (0 = answer right away)")
+	flag.StringVar(&cacheBaseDir, "cache-dir", "", "Directory for on-disk index snapshots (default: user cache dir/codeindex-mcp)")
+	flag.BoolVar(&noCache, "no-cache", false, "Disable on-disk index snapshots and always index from scratch")
+	flag.StringVar(&httpAddr, "http", "", "Serve MCP over streamable HTTP on this address (e.g. :8080) instead of stdio")
+	flag.StringVar(&unixSocket, "unix-socket", "", "Serve MCP over streamable HTTP on this Unix socket path instead of stdio")
+	flag.StringVar(&authToken, "auth-token", os.Getenv("CODEINDEX_AUTH_TOKEN"), "Bearer token required for HTTP clients (default: $CODEINDEX_AUTH_TOKEN, empty = no auth)")
+	flag.BoolVar(&shared, "shared", false, "Share one background indexer per root between all invocations, starting it if needed")
+	flag.BoolVar(&daemonMode, "daemon", false, "Run as the shared background indexer for the root (started automatically by --shared)")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 10*time.Minute, "Shut down the shared daemon after this long without clients (0 = never)")
 	flag.Parse()
 
 	if syncInterval < 0 {
@@ -72,16 +124,28 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Resolve root directory
-	if rootDir == "" {
-		var err error
-		rootDir, err = os.Getwd()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting working directory: %v\n", err)
-			os.Exit(1)
-		}
+	parsedWatchMode, err := watcher.ParseMode(watchMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --watch-mode: %v\n", err)
+		os.Exit(1)
+	}
+	if pollInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --poll-interval must be > 0\n")
+		os.Exit(1)
+	}
+
+	if indexWorkers <= 0 || indexBatchSize <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --index-workers and --index-batch-size must be > 0\n")
+		os.Exit(1)
+	}
+	indexOptions := IndexOptions{Workers: indexWorkers, BatchSize: indexBatchSize, VerifyContent: verifyContent}
+
+	// Resolve the workspace roots
+	ws, err := resolveWorkspace(roots, workspaceFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	rootDir, _ = filepath.Abs(rootDir)
 
 	// Setup logger (always to file or stderr, never to stdout - stdout is for MCP stdio)
 	var logger *slog.Logger
@@ -89,7 +153,7 @@ func main() {
 		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	} else {
 		if logFile == "" {
-			logFile = filepath.Join(rootDir, "codeindex-mcp.log")
+			logFile = filepath.Join(ws.Roots[0].Dir, "codeindex-mcp.log")
 		}
 		var logFileHandle *os.File
 		logger, logFileHandle = setupLogger(logLevel, logFile)
@@ -101,95 +165,316 @@ func main() {
 	fmt.Fprintf(os.Stderr, "Tip: run \"codeindex-mcp register project .\" to auto-register in Claude Code\n")
 
 	logger.Info("starting codeindex-mcp",
-		"root", rootDir,
+		"roots", ws.Names(),
 		"maxFileSize", maxFileSizeBytes,
 		"maxResults", maxResults,
 		"forceIncludes", []string(forceIncludes),
+		"watchMode", parsedWatchMode,
+		"indexWorkers", indexWorkers,
+		"indexBatchSize", indexBatchSize,
 	)
 
 	startTime := time.Now()
 
-	// Create ignore matcher
-	ignoreMatcher := ignore.NewMatcher(ignore.MatcherOptions{
-		RootDir:              rootDir,
-		CustomPatterns:       excludes,
-		ForceIncludePatterns: forceIncludes,
-		MaxFileSizeBytes:     maxFileSizeBytes,
-	})
+	// Create an ignore matcher per root; ignoreMatchers[i] applies to ws.Roots[i]
+	ignoreMatchers := newIgnoreMatchers(ws, excludes, forceIncludes, maxFileSizeBytes)
+
+	// Resolve the per-root state directory, holding the snapshot cache and the shared daemon files
+	var stateDir string
+	if cacheBaseDir == "" {
+		var err error
+		cacheBaseDir, err = snapshot.DefaultBaseDir()
+		if err != nil {
+			logger.Warn("cannot resolve cache dir, continuing without cache", "error", err)
+		}
+	}
+	if cacheBaseDir != "" {
+		stateDir = snapshot.Dir(cacheBaseDir, ws.Key())
+	}
+
+	// Attach to (or start) the shared daemon and proxy stdio to it
+	if shared && !daemonMode && httpAddr == "" && unixSocket == "" {
+		if stateDir == "" {
+			logger.Warn("shared mode needs a state dir, running standalone")
+		} else if runSharedClient(stateDir, logger) {
+			return
+		}
+	}
+
+	// Register as the shared daemon of the root; its clients reach it over a private Unix socket
+	if daemonMode {
+		if stateDir == "" {
+			logger.Error("daemon mode needs a state dir")
+			os.Exit(1)
+		}
+		info, err := daemon.Acquire(stateDir, ws.Key(), server.Version)
+		if errors.Is(err, lockfile.ErrLocked) {
+			logger.Info("another daemon is already running for this root, exiting")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to register daemon", "error", err)
+			os.Exit(1)
+		}
+		defer daemon.Release(stateDir)
+		httpAddr = ""
+		unixSocket = info.SocketPath
+		authToken = info.Token
+		logger.Info("running as shared daemon", "socket", unixSocket, "idleTimeout", idleTimeout)
+	}
 
-	// Create indexes
+	// Take ownership of the snapshot cache; a second process for the root runs without it
+	var cacheDir string
+	if !noCache && stateDir != "" {
+		cacheDir = lockCacheDir(stateDir, logger)
+		if cacheDir != "" {
+			defer unlockCacheDir(cacheDir)
+		}
+	}
+
+	// Create indexes, warm-starting from the snapshot when available
 	fileIndex := index.NewFileIndex()
-	contentIndex, err := index.NewContentIndex()
+	contentIndex, warmStart, err := openIndexes(cacheDir, ws.Key(), fileIndex, logger)
 	if err != nil {
 		logger.Error("failed to create content index", "error", err)
 		os.Exit(1)
 	}
 	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
 
-	// Perform initial indexing
-	indexedCount, totalSize := performIndexing(rootDir, fileIndex, contentIndex, ignoreMatcher, logger)
-	indexDuration := time.Since(startTime)
-	logger.Info("initial indexing complete",
-		"files", indexedCount,
-		"totalSize", totalSize,
-		"duration", indexDuration,
-	)
-
-	// Start file watcher
-	fileWatcher, err := watcher.NewWatcher(rootDir, ignoreMatcher, logger)
-	if err != nil {
-		logger.Warn("failed to start file watcher, continuing without live updates", "error", err)
-	} else {
-		go fileWatcher.Start()
-		go handleWatcherEvents(fileWatcher, rootDir, fileIndex, contentIndex, ignoreMatcher, logger)
-		defer fileWatcher.Close()
+	// Build the index in the background so the MCP handshake is not delayed on big
+	// repositories; tools answer from the partial index meanwhile (see IndexingMiddleware)
+	progress := index.NewProgress(index.PhaseIndexing)
+	if warmStart {
+		progress = index.NewProgress(index.PhaseSyncing)
 	}
+	initialOptions := indexOptions
+	initialOptions.Progress = progress
 
-	// Start periodic sync if configured
+	// fileWatchers[i] is nil if watching ws.Roots[i] failed; set before progress is ready
+	fileWatchers := make([]*watcher.Watcher, len(ws.Roots))
+	// Tool calls and index updates work on one generation of the indexes; a rebuild swaps them all at once
+	var generation sync.RWMutex
 	var syncStop chan struct{}
 	if syncInterval > 0 {
 		syncStop = make(chan struct{})
-		go runPeriodicSync(syncInterval, rootDir, fileIndex, contentIndex, ignoreMatcher, logger, syncStop)
-		defer close(syncStop)
 	}
+	// Cancelled on shutdown, so an unfinished initial indexing stops reading files
+	indexCtx, cancelIndexing := context.WithCancel(context.Background())
+	go func() {
+		if warmStart {
+			// Reconcile the snapshot with the filesystem: only changed files are re-indexed
+			rebuildSymbols(fileIndex, contentIndex, symbolIndex)
+			result := reconcileWorkspace(indexCtx, ws.Roots, ignoreMatchers, fileIndex, contentIndex, symbolIndex, initialOptions, logger)
+			if indexCtx.Err() != nil {
+				return
+			}
+			logger.Info("warm start complete",
+				"files", fileIndex.FileCount(),
+				"missing", result.MissingFiles,
+				"stale", result.StaleFiles,
+				"modified", result.ModifiedFiles,
+				"duration", time.Since(startTime),
+			)
+		} else {
+			// Perform initial indexing
+			var indexedCount int
+			var totalSize int64
+			for i, root := range ws.Roots {
+				count, size := performIndexing(indexCtx, root, fileIndex, contentIndex, symbolIndex, ignoreMatchers[i], initialOptions, logger)
+				indexedCount += count
+				totalSize += size
+			}
+			if indexCtx.Err() != nil {
+				return
+			}
+			indexDuration := time.Since(startTime)
+			logger.Info("initial indexing complete",
+				"files", indexedCount,
+				"totalSize", totalSize,
+				"duration", indexDuration,
+			)
+		}
+
+		// Start a file watcher per root
+		for i, root := range ws.Roots {
+			fileWatcher, err := watcher.NewWatcher(root.Dir, ignoreMatchers[i], watcher.Options{Mode: parsedWatchMode, PollInterval: pollInterval}, logger)
+			if err != nil {
+				logger.Warn("failed to start file watcher, continuing without live updates", "root", root.Name, "error", err)
+				continue
+			}
+			fileWatchers[i] = fileWatcher
+			go fileWatcher.Start()
+			go handleWatcherEvents(fileWatcher, root, fileIndex, contentIndex, symbolIndex, ignoreMatchers[i], &generation, indexOptions, logger)
+		}
+
+		// Start periodic sync if configured
+		if syncStop != nil {
+			go runPeriodicSync(syncInterval, ws.Roots, ignoreMatchers, fileIndex, contentIndex, symbolIndex, &generation, indexOptions, logger, syncStop)
+		}
+		progress.Finish()
+	}()
 
 	// Create tool handlers
-	searchHandler := &tools.SearchHandler{ContentIndex: contentIndex, Logger: logger}
-	filesHandler := &tools.FilesHandler{FileIndex: fileIndex, Logger: logger}
+	searchHandler := &tools.SearchHandler{ContentIndex: contentIndex, FileIndex: fileIndex, SymbolIndex: symbolIndex, Workspace: ws, Logger: logger}
+	filesHandler := &tools.FilesHandler{FileIndex: fileIndex, Workspace: ws, Logger: logger}
 	statusHandler := &tools.StatusHandler{
 		FileIndex:    fileIndex,
 		ContentIndex: contentIndex,
 		StartTime:    startTime,
-		RootDir:      rootDir,
+		Progress:     progress,
+		Workspace:    ws,
 		Logger:       logger,
 	}
-	readHandler := &tools.ReadHandler{ContentIndex: contentIndex, Logger: logger}
+	readHandler := &tools.ReadHandler{ContentIndex: contentIndex, Workspace: ws, Logger: logger}
+	symbolsHandler := &tools.SymbolsHandler{SymbolIndex: symbolIndex, Logger: logger}
+	outlineHandler := &tools.OutlineHandler{FileIndex: fileIndex, ContentIndex: contentIndex, Logger: logger}
+	referencesHandler := &tools.ReferencesHandler{FileIndex: fileIndex, ContentIndex: contentIndex, Logger: logger}
+	explainIgnoreHandler := &tools.ExplainIgnoreHandler{
+		FileIndex:      fileIndex,
+		Workspace:      ws,
+		IgnoreMatchers: ignoreMatchers,
+		Logger:         logger,
+	}
+	indexRebuilder := newReindexer(func(ctx context.Context, rebuildProgress *index.Progress) reindexResult {
+		return rebuildIndexes(ctx, rebuildProgress, ws.Roots, ignoreMatchers, fileWatchers, fileIndex, contentIndex, symbolIndex, &generation, indexOptions, logger)
+	})
 	reindexHandler := &tools.ReindexHandler{
 		Logger: logger,
-		DoReindex: func() (int, int64, string, error) {
-			start := time.Now()
-			fileIndex.Clear()
-			if err := contentIndex.Clear(); err != nil {
-				return 0, 0, "", fmt.Errorf("clearing content index: %w", err)
+		DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
+			// A rebuild replaces the index the startup indexing is still filling
+			select {
+			case <-progress.Ready():
+			case <-ctx.Done():
+				return 0, 0, "", ctx.Err()
+			}
+			result := indexRebuilder.Reindex(ctx, onProgress)
+			if result.Err != nil {
+				return 0, 0, "", result.Err
 			}
-			// Reload ignore rules in case .gitignore or .claudeignore changed
-			ignoreMatcher.Reload()
-			count, size := performIndexing(rootDir, fileIndex, contentIndex, ignoreMatcher, logger)
-			elapsed := time.Since(start).Round(time.Millisecond).String()
-			return count, size, elapsed, nil
+			return result.Files, result.SizeBytes, result.Duration.Round(time.Millisecond).String(), nil
 		},
 	}
 
-	// Setup and run MCP server on stdio
-	mcpServer := server.Setup(searchHandler, filesHandler, statusHandler, reindexHandler, readHandler)
+	// Setup and run MCP server on stdio, or over HTTP when requested
+	mcpServer := server.Setup(searchHandler, filesHandler, statusHandler, reindexHandler, readHandler, symbolsHandler, outlineHandler, referencesHandler, explainIgnoreHandler)
+	mcpServer.AddReceivingMiddleware(server.IndexingMiddleware(progress, indexWait), server.GenerationMiddleware(&generation))
+
+	// Stop on SIGINT/SIGTERM as well as on stdin EOF, so the snapshot is saved in both cases
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	var middleware func(http.Handler) http.Handler
+	if daemonMode && idleTimeout > 0 {
+		idleTracker := daemon.NewIdleTracker()
+		middleware = idleTracker.Middleware
+		idleCtx, stopOnIdle := context.WithCancel(ctx)
+		ctx = idleCtx
+		defer stopOnIdle()
+		go func() {
+			if idleTracker.WaitIdle(idleCtx, idleTimeout) {
+				logger.Info("shared daemon idle, shutting down", "idleTimeout", idleTimeout)
+				stopOnIdle()
+			}
+		}()
+	}
+
+	var runErr error
+	if httpAddr != "" || unixSocket != "" {
+		runErr = server.RunHTTP(ctx, mcpServer, server.HTTPOptions{
+			Addr:        httpAddr,
+			UnixSocket:  unixSocket,
+			BearerToken: authToken,
+			HealthInfo: func() map[string]any {
+				return map[string]any{
+					"roots":         ws.Names(),
+					"files":         fileIndex.FileCount(),
+					"phase":         progress.State().Phase,
+					"uptimeSeconds": int(time.Since(startTime).Seconds()),
+				}
+			},
+			Middleware: middleware,
+			Logger:     logger,
+		})
+	} else {
+		logger.Info("MCP server starting on stdio")
+		runErr = mcpServer.Run(ctx, &mcp.StdioTransport{})
+	}
+	if runErr != nil && ctx.Err() == nil {
+		logger.Error("MCP server error", "error", runErr)
+	}
 
-	logger.Info("MCP server starting on stdio")
-	if err := mcpServer.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		logger.Error("MCP server error", "error", err)
+	cancelIndexing()
+	if progress.IsReady() {
+		for _, fileWatcher := range fileWatchers {
+			if fileWatcher != nil {
+				fileWatcher.Close()
+			}
+		}
+		if syncStop != nil {
+			close(syncStop)
+		}
+		if cacheDir != "" {
+			saveSnapshot(cacheDir, ws.Key(), fileIndex, contentIndex, logger)
+		}
+	} else {
+		// A partial index must not be saved as a complete snapshot
+		logger.Info("shutting down before indexing completed, snapshot not saved", "phase", progress.State().Phase)
+	}
+	if runErr != nil && ctx.Err() == nil {
+		contentIndex.Close()
+		if cacheDir != "" {
+			unlockCacheDir(cacheDir)
+		}
+		if daemonMode {
+			daemon.Release(stateDir)
+		}
 		os.Exit(1)
 	}
 }
 
+// resolveWorkspace builds the workspace from the --workspace file and --root flags.
+// Without either, the current working directory is the only root.
+func resolveWorkspace(rootFlags []string, workspaceFile string) (*workspace.Workspace, error) {
+	var roots []workspace.Root
+	if workspaceFile != "" {
+		fileRoots, err := workspace.LoadFile(workspaceFile)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, fileRoots...)
+	}
+	for _, spec := range rootFlags {
+		root, err := workspace.ParseRootSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 {
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("getting working directory: %w", err)
+		}
+		roots = append(roots, workspace.Root{Dir: workingDir})
+	}
+	return workspace.New(roots)
+}
+
+// newIgnoreMatchers creates an ignore matcher per root; the result's [i] applies to ws.Roots[i].
+func newIgnoreMatchers(ws *workspace.Workspace, excludes []string, forceIncludes []string, maxFileSizeBytes int64) []*ignore.Matcher {
+	ignoreMatchers := make([]*ignore.Matcher, len(ws.Roots))
+	for i, root := range ws.Roots {
+		ignoreMatchers[i] = ignore.NewMatcher(ignore.MatcherOptions{
+			RootDir:              root.Dir,
+			CustomPatterns:       excludes,
+			ForceIncludePatterns: forceIncludes,
+			MaxFileSizeBytes:     maxFileSizeBytes,
+		})
+	}
+	return ignoreMatchers
+}
+
 // setupLogger creates an slog.Logger writing to stderr or a file.
 // Returns the logger and the opened file (nil if using stderr), so the caller can defer Close().
 func setupLogger(level string, logFile string) (*slog.Logger, *os.File) {
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/ignore"
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/lexandro/codeindex-mcp/watcher"
+	"github.com/lexandro/codeindex-mcp/workspace"
+)
+
+// reindexResult is the outcome of one full rebuild of the indexes.
+type reindexResult struct {
+	Files     int
+	SizeBytes int64
+	Duration  time.Duration
+	Err       error
+}
+
+// reindexProgressInterval is how often Reindex passes the progress of the rebuild to its caller.
+const reindexProgressInterval = 250 * time.Millisecond
+
+// reindexRun is a rebuild shared by every request that waits for it.
+type reindexRun struct {
+	done     chan struct{}
+	result   reindexResult
+	progress *index.Progress
+	ctx      context.Context
+	cancel   context.CancelFunc
+	waiters  int // requests waiting for the run; guarded by reindexer.mu
+}
+
+// reindexer runs rebuilds one at a time in the background. Requests arriving while a
+// rebuild is in progress coalesce into a single follow-up rebuild, which starts after the
+// current one so that it sees every change made before the requests. A rebuild is
+// cancelled once every request waiting for it has been cancelled.
+type reindexer struct {
+	rebuild func(ctx context.Context, progress *index.Progress) reindexResult
+
+	mu      sync.Mutex
+	running bool
+	pending *reindexRun // next rebuild to start; nil if none was requested
+}
+
+func newReindexer(rebuild func(ctx context.Context, progress *index.Progress) reindexResult) *reindexer {
+	return &reindexer{rebuild: rebuild}
+}
+
+// Reindex requests a rebuild and waits until a rebuild started after the request completes.
+// While waiting, onProgress (if not nil) periodically receives the progress of the rebuild.
+// If ctx is done first, Reindex returns ctx.Err() without waiting for the rebuild.
+func (r *reindexer) Reindex(ctx context.Context, onProgress func(index.ProgressState)) reindexResult {
+	r.mu.Lock()
+	if r.pending == nil {
+		runCtx, cancel := context.WithCancel(context.Background())
+		r.pending = &reindexRun{
+			done:     make(chan struct{}),
+			progress: index.NewProgress(index.PhaseIndexing),
+			ctx:      runCtx,
+			cancel:   cancel,
+		}
+	}
+	run := r.pending
+	run.waiters++
+	if !r.running {
+		r.running = true
+		go r.loop()
+	}
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(reindexProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-run.done:
+			return run.result
+		case <-ticker.C:
+			if onProgress != nil {
+				onProgress(run.progress.State())
+			}
+		case <-ctx.Done():
+			r.leave(run)
+			return reindexResult{Err: ctx.Err()}
+		}
+	}
+}
+
+// leave removes a request that stopped waiting for run, and cancels the run if it was the
+// last one: nobody is left to use the result.
+func (r *reindexer) leave(run *reindexRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.waiters--
+	if run.waiters > 0 {
+		return
+	}
+	run.cancel()
+	if r.pending == run {
+		r.pending = nil
+	}
+}
+
+// loop runs pending rebuilds until none is left.
+func (r *reindexer) loop() {
+	for {
+		r.mu.Lock()
+		run := r.pending
+		r.pending = nil
+		if run == nil {
+			r.running = false
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+
+		run.result = r.rebuild(run.ctx, run.progress)
+		run.progress.Finish()
+		run.cancel()
+		close(run.done)
+	}
+}
+
+// rebuildIndexes indexes every root from scratch into a new generation of the indexes and
+// swaps it in when complete, so queries are served from the previous generation until then.
+// ignoreMatchers[i] and fileWatchers[i] (nil if not watching) belong to roots[i]. progress
+// (may be nil) tracks the files of the new generation. If ctx is cancelled, the new
+// generation is discarded and the indexes are left as they were. The three indexes are
+// swapped while holding generation for writing (see server.GenerationMiddleware).
+func rebuildIndexes(
+	ctx context.Context,
+	progress *index.Progress,
+	roots []workspace.Root,
+	ignoreMatchers []*ignore.Matcher,
+	fileWatchers []*watcher.Watcher,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	generation *sync.RWMutex,
+	options IndexOptions,
+	logger *slog.Logger,
+) reindexResult {
+	start := time.Now()
+	nextContentIndex, err := contentIndex.NewGeneration()
+	if err != nil {
+		return reindexResult{Err: fmt.Errorf("creating content index: %w", err)}
+	}
+	nextFileIndex := index.NewFileIndex()
+	nextSymbolIndex := symbols.NewIndex()
+
+	options.Progress = progress
+	var result reindexResult
+	for i, root := range roots {
+		// Reload ignore rules in case .gitignore or .claudeignore changed
+		ignoreMatchers[i].Reload()
+		if fileWatchers[i] != nil {
+			fileWatchers[i].Resync(root.Dir)
+		}
+		count, size := performIndexing(ctx, root, nextFileIndex, nextContentIndex, nextSymbolIndex, ignoreMatchers[i], options, logger)
+		result.Files += count
+		result.SizeBytes += size
+	}
+	if err := ctx.Err(); err != nil {
+		if discardErr := nextContentIndex.Discard(); discardErr != nil {
+			logger.Warn("failed to discard cancelled content index", "error", discardErr)
+		}
+		return reindexResult{Err: err}
+	}
+
+	generation.Lock()
+	fileIndex.Swap(nextFileIndex)
+	previousContentIndex := contentIndex.Swap(nextContentIndex)
+	symbolIndex.Swap(nextSymbolIndex)
+	generation.Unlock()
+	// Closing and removing the previous Bleve index can be slow; tool calls need not wait for it
+	if err := previousContentIndex.Discard(); err != nil {
+		logger.Warn("failed to release previous content index", "error", err)
+	}
+
+	// The watcher kept updating the previous generation during the rebuild; pick up those changes.
+	// The new generation is live, so this runs to the end even if ctx is cancelled now
+	catchUp := reconcileWorkspace(context.Background(), roots, ignoreMatchers, fileIndex, contentIndex, symbolIndex, options, logger)
+	logger.Debug("reindex caught up with changes made during the rebuild",
+		"missing", catchUp.MissingFiles,
+		"stale", catchUp.StaleFiles,
+		"modified", catchUp.ModifiedFiles,
+	)
+
+	result.Duration = time.Since(start)
+	return result
+}
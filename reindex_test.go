@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/ignore"
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/lexandro/codeindex-mcp/watcher"
+	"github.com/lexandro/codeindex-mcp/workspace"
+)
+
+func Test_reindexer_CoalescesConcurrentRequests(t *testing.T) {
+	var rebuilds atomic.Int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	r := newReindexer(func(ctx context.Context, progress *index.Progress) reindexResult {
+		n := rebuilds.Add(1)
+		started <- struct{}{}
+		<-release
+		return reindexResult{Files: int(n)}
+	})
+
+	results := make(chan reindexResult, 4)
+	go func() { results <- r.Reindex(context.Background(), nil) }()
+	<-started
+
+	// Requests arriving during the first rebuild share one follow-up rebuild
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- r.Reindex(context.Background(), nil)
+		}()
+	}
+	waitForIndex(t, "the follow-up requests are queued", func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.pending != nil
+	})
+	time.Sleep(50 * time.Millisecond) // let the remaining requests join the pending rebuild
+
+	close(release)
+	wg.Wait()
+
+	if got := rebuilds.Load(); got != 2 {
+		t.Fatalf("expected 2 rebuilds, got %d", got)
+	}
+	counts := map[int]int{}
+	for i := 0; i < 4; i++ {
+		counts[(<-results).Files]++
+	}
+	if counts[1] != 1 || counts[2] != 3 {
+		t.Errorf("expected one request served by the first rebuild and three by the second, got %v", counts)
+	}
+}
+
+func Test_reindexer_CancelsRunWithoutWaiters(t *testing.T) {
+	var rebuilds atomic.Int32
+	started := make(chan struct{}, 10)
+	r := newReindexer(func(ctx context.Context, progress *index.Progress) reindexResult {
+		rebuilds.Add(1)
+		progress.AddDiscovered(2)
+		progress.AddProcessed(1)
+		started <- struct{}{}
+		<-ctx.Done()
+		return reindexResult{Err: ctx.Err()}
+	})
+	pendingWaiters := func() int {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.pending == nil {
+			return 0
+		}
+		return r.pending.waiters
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstResult := make(chan reindexResult, 1)
+	progressStates := make(chan index.ProgressState, 100)
+	go func() {
+		firstResult <- r.Reindex(firstCtx, func(state index.ProgressState) {
+			select {
+			case progressStates <- state:
+			default:
+			}
+		})
+	}()
+	<-started
+	if state := <-progressStates; state.Processed != 1 || state.Discovered != 2 {
+		t.Errorf("expected the progress of the running rebuild, got %+v", state)
+	}
+
+	// Two requests share the follow-up rebuild, which is dropped once both are cancelled
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	thirdCtx, cancelThird := context.WithCancel(context.Background())
+	followUpResults := make(chan reindexResult, 2)
+	go func() { followUpResults <- r.Reindex(secondCtx, nil) }()
+	go func() { followUpResults <- r.Reindex(thirdCtx, nil) }()
+	waitForIndex(t, "the follow-up requests are queued", func() bool { return pendingWaiters() == 2 })
+
+	cancelSecond()
+	if result := <-followUpResults; !errors.Is(result.Err, context.Canceled) {
+		t.Fatalf("expected the cancelled request to return context.Canceled, got %+v", result)
+	}
+	if pendingWaiters() != 1 {
+		t.Fatal("expected the follow-up rebuild to stay queued for the remaining request")
+	}
+	cancelThird()
+	<-followUpResults
+	waitForIndex(t, "the follow-up rebuild is dropped", func() bool { return pendingWaiters() == 0 })
+
+	// Cancelling the only request of the running rebuild stops it
+	cancelFirst()
+	if result := <-firstResult; !errors.Is(result.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %+v", result)
+	}
+	waitForIndex(t, "the rebuild stops", func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return !r.running
+	})
+	if got := rebuilds.Load(); got != 1 {
+		t.Errorf("expected the dropped follow-up not to run, got %d rebuilds", got)
+	}
+}
+
+func Test_rebuildIndexes_CancelledKeepsPreviousGeneration(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	root := testRoot(tmpDir)
+	matcher := testIgnoreMatcher(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "kept.go"), []byte("package main\n\nfunc Kept() {}\n"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	storagePath := filepath.Join(t.TempDir(), "bleve")
+	contentIndex, err := index.NewPersistentContentIndex(storagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	os.WriteFile(filepath.Join(tmpDir, "added.go"), []byte("package main\n\nfunc Added() {}\n"), 0644)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := rebuildIndexes(ctx, nil, []workspace.Root{root}, []*ignore.Matcher{matcher}, []*watcher.Watcher{nil}, fileIndex, contentIndex, symbolIndex, &sync.RWMutex{}, IndexOptions{}, logger)
+
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %+v", result)
+	}
+	if fileIndex.FileCount() != 1 || contentIndex.DocumentCount() != 1 || fileIndex.GetFile("added.go") != nil {
+		t.Errorf("expected the previous generation to stay in place, got %d files and %d documents", fileIndex.FileCount(), contentIndex.DocumentCount())
+	}
+	if _, err := os.Stat(storagePath + ".next"); !os.IsNotExist(err) {
+		t.Errorf("expected the cancelled generation to be deleted, got %v", err)
+	}
+}
+
+func Test_rebuildIndexes_SwapsInNewGeneration(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	root := testRoot(tmpDir)
+	matcher := testIgnoreMatcher(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "kept.go"), []byte("package main\n\nfunc Kept() {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "deleted.go"), []byte("package main\n\nfunc Deleted() {}\n"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewPersistentContentIndex(filepath.Join(t.TempDir(), "bleve"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	// Changes the watcher has not delivered yet
+	os.Remove(filepath.Join(tmpDir, "deleted.go"))
+	os.WriteFile(filepath.Join(tmpDir, "added.go"), []byte("package main\n\nfunc Added() {}\n"), 0644)
+
+	result := rebuildIndexes(context.Background(), nil, []workspace.Root{root}, []*ignore.Matcher{matcher}, []*watcher.Watcher{nil}, fileIndex, contentIndex, symbolIndex, &sync.RWMutex{}, IndexOptions{}, logger)
+
+	if result.Err != nil || result.Files != 2 {
+		t.Fatalf("expected 2 files reindexed, got %+v", result)
+	}
+	if fileIndex.GetFile("deleted.go") != nil || fileIndex.GetFile("added.go") == nil {
+		t.Error("expected the file index to reflect the filesystem")
+	}
+	if _, ok := contentIndex.GetFileContent("deleted.go"); ok || contentIndex.DocumentCount() != 2 {
+		t.Errorf("expected 2 documents without deleted.go, got %d", contentIndex.DocumentCount())
+	}
+	if len(symbolIndex.FileSymbols("deleted.go")) != 0 || len(symbolIndex.FileSymbols("added.go")) != 1 {
+		t.Error("expected the symbol index to reflect the filesystem")
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// GenerationMiddleware holds generation for reading during every tool call, so that a
+// rebuild swapping in a new generation of the file, content and symbol indexes (while
+// holding it for writing) cannot be observed halfway: a call sees the file metadata,
+// contents and symbols of one generation. codeindex_reindex is exempt, as it waits for
+// the swap.
+func GenerationMiddleware(generation *sync.RWMutex) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callRequest, ok := req.(*mcp.CallToolRequest)
+			if !ok || callRequest.Params.Name == "codeindex_reindex" {
+				return next(ctx, method, req)
+			}
+			generation.RLock()
+			defer generation.RUnlock()
+			return next(ctx, method, req)
+		}
+	}
+}
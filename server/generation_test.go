@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func Test_GenerationMiddleware_WaitsForSwap(t *testing.T) {
+	var generation sync.RWMutex
+	mcpServer := newTestMCPServer()
+	mcp.AddTool(mcpServer, &mcp.Tool{Name: "codeindex_reindex"}, func(ctx context.Context, req *mcp.CallToolRequest, args pingArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "reindexed"}}}, nil, nil
+	})
+	mcpServer.AddReceivingMiddleware(GenerationMiddleware(&generation))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	if _, err := mcpServer.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	// A swap in progress: codeindex_reindex is answered, other tools wait for it
+	generation.Lock()
+	if texts := callText(t, session, "codeindex_reindex"); len(texts) != 1 || texts[0] != "reindexed" {
+		t.Errorf("expected codeindex_reindex to run during the swap, got %q", texts)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "ping", Arguments: map[string]any{}})
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected ping to wait for the swap")
+	case <-time.After(50 * time.Millisecond):
+	}
+	generation.Unlock()
+	if err := <-done; err != nil {
+		t.Errorf("expected ping to succeed after the swap, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// MCPPath is the endpoint serving the streamable HTTP transport.
+	MCPPath = "/mcp"
+	// HealthPath is the unauthenticated health check endpoint.
+	HealthPath = "/healthz"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// HTTPOptions configures serving the MCP server over streamable HTTP.
+type HTTPOptions struct {
+	Addr        string // TCP listen address (e.g. ":8080"), empty to disable
+	UnixSocket  string // Unix domain socket path, empty to disable
+	BearerToken string // Required bearer token for MCPPath, empty disables authentication
+	// HealthInfo returns extra fields for the health endpoint (e.g. indexed file count). May be nil.
+	HealthInfo func() map[string]any
+	// Middleware wraps the complete handler (e.g. to track activity). May be nil.
+	Middleware func(http.Handler) http.Handler
+	Logger     *slog.Logger
+}
+
+// NewHTTPHandler returns the HTTP handler serving mcpServer at MCPPath and the health
+// check at HealthPath. All clients share the same server, and therefore the same index.
+func NewHTTPHandler(mcpServer *mcp.Server, options HTTPOptions) http.Handler {
+	var mcpHandler http.Handler = mcp.NewStreamableHTTPHandler(
+		func(*http.Request) *mcp.Server { return mcpServer },
+		&mcp.StreamableHTTPOptions{Logger: options.Logger},
+	)
+	if options.BearerToken != "" {
+		mcpHandler = auth.RequireBearerToken(staticTokenVerifier(options.BearerToken), nil)(mcpHandler)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(MCPPath, mcpHandler)
+	mux.HandleFunc("GET "+HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		health := map[string]any{"status": "ok", "version": Version}
+		if options.HealthInfo != nil {
+			for key, value := range options.HealthInfo() {
+				health[key] = value
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(health)
+	})
+	return mux
+}
+
+// staticTokenVerifier accepts exactly one pre-shared token.
+func staticTokenVerifier(expected string) auth.TokenVerifier {
+	return func(ctx context.Context, token string, req *http.Request) (*auth.TokenInfo, error) {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return nil, auth.ErrInvalidToken
+		}
+		// The SDK rejects tokens without expiration; a static token is valid for as long as the server runs
+		return &auth.TokenInfo{Expiration: time.Now().Add(time.Hour)}, nil
+	}
+}
+
+// RunHTTP serves mcpServer on the configured TCP address and/or Unix socket until ctx is
+// cancelled, then shuts down gracefully. Returns the first listener error, if any.
+func RunHTTP(ctx context.Context, mcpServer *mcp.Server, options HTTPOptions) error {
+	if options.Addr == "" && options.UnixSocket == "" {
+		return errors.New("no HTTP address or Unix socket configured")
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+
+	if options.Addr != "" {
+		if options.BearerToken == "" && !isLoopbackAddr(options.Addr) {
+			options.Logger.Warn("serving MCP over HTTP without authentication on a non-loopback address", "addr", options.Addr)
+		}
+		listener, err := net.Listen("tcp", options.Addr)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %w", options.Addr, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	if options.UnixSocket != "" {
+		listener, err := listenUnix(options.UnixSocket)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	handler := NewHTTPHandler(mcpServer, options)
+	if options.Middleware != nil {
+		handler = options.Middleware(handler)
+	}
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErrors := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		options.Logger.Info("MCP server listening", "network", listener.Addr().Network(), "address", listener.Addr().String())
+		go func(listener net.Listener) {
+			serveErrors <- httpServer.Serve(listener)
+		}(listener)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-serveErrors:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		// Long-lived SSE streams keep connections open; drop them
+		options.Logger.Warn("HTTP server shutdown incomplete, closing remaining connections", "error", err)
+		httpServer.Close()
+	}
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return nil
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections.
+// An empty host (":8080") listens on all interfaces.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenUnix listens on a Unix socket readable only by the current user, replacing a
+// stale socket file left behind by a previous run.
+func listenUnix(socketPath string) (net.Listener, error) {
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socketPath)
+		}
+		if conn, dialErr := net.Dial("unix", socketPath); dialErr == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", socketPath)
+		}
+		os.Remove(socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("restricting permissions of %s: %w", socketPath, err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type pingArgs struct{}
+
+func newTestMCPServer() *mcp.Server {
+	mcpServer := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "0.0.1"}, nil)
+	mcp.AddTool(mcpServer, &mcp.Tool{Name: "ping"}, func(ctx context.Context, req *mcp.CallToolRequest, args pingArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "pong"}}}, nil, nil
+	})
+	return mcpServer
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// bearerTransport adds an Authorization header to every request.
+type bearerTransport struct {
+	token string
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_NewHTTPHandler_Health(t *testing.T) {
+	handler := NewHTTPHandler(newTestMCPServer(), HTTPOptions{
+		BearerToken: "secret",
+		HealthInfo:  func() map[string]any { return map[string]any{"files": 3} },
+		Logger:      testLogger(),
+	})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	// Health must be reachable without the token
+	resp, err := http.Get(httpServer.URL + HealthPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var health map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health["status"] != "ok" || health["version"] != Version || health["files"] != float64(3) {
+		t.Errorf("unexpected health response: %v", health)
+	}
+}
+
+func Test_NewHTTPHandler_RequiresBearerToken(t *testing.T) {
+	handler := NewHTTPHandler(newTestMCPServer(), HTTPOptions{BearerToken: "secret", Logger: testLogger()})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	for _, token := range []string{"", "wrong"} {
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL+MCPPath, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, resp.StatusCode)
+		}
+	}
+}
+
+func Test_NewHTTPHandler_ServesTools(t *testing.T) {
+	handler := NewHTTPHandler(newTestMCPServer(), HTTPOptions{BearerToken: "secret", Logger: testLogger()})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Two clients share the same server
+	for i := 0; i < 2; i++ {
+		client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, nil)
+		session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+			Endpoint:             httpServer.URL + MCPPath,
+			HTTPClient:           &http.Client{Transport: &bearerTransport{token: "secret"}},
+			DisableStandaloneSSE: true,
+		}, nil)
+		if err != nil {
+			t.Fatalf("client %d: connect failed: %v", i, err)
+		}
+
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "ping"})
+		if err != nil {
+			t.Fatalf("client %d: call failed: %v", i, err)
+		}
+		if text := result.Content[0].(*mcp.TextContent).Text; text != "pong" {
+			t.Errorf("client %d: expected pong, got %q", i, text)
+		}
+		session.Close()
+	}
+}
+
+func Test_RunHTTP_UnixSocket(t *testing.T) {
+	// Unix socket paths are limited to ~100 bytes, so avoid the long t.TempDir() path
+	dir, err := os.MkdirTemp("", "cim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "s.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunHTTP(ctx, newTestMCPServer(), HTTPOptions{UnixSocket: socketPath, Logger: testLogger()})
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		},
+	}}
+	var resp *http.Response
+	for attempt := 0; attempt < 50; attempt++ {
+		resp, err = client.Get("http://unix" + HealthPath)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("health check over unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected socket permissions 0600, got %o", perm)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunHTTP did not return after cancel")
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("expected socket to be removed on shutdown, stat err: %v", err)
+	}
+}
+
+func Test_isLoopbackAddr(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+	}
+	for addr, expected := range tests {
+		if got := isLoopbackAddr(addr); got != expected {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, expected)
+		}
+	}
+}
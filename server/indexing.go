@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/tools"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// IndexingMiddleware handles tool calls that arrive while the startup indexing is still
+// running: they wait up to waitTimeout for it to complete, and are then answered from the
+// partially built index with a notice of the progress prepended to the result.
+// codeindex_status is answered right away, as it reports the progress itself.
+func IndexingMiddleware(progress *index.Progress, waitTimeout time.Duration) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callRequest, ok := req.(*mcp.CallToolRequest)
+			if !ok || progress.IsReady() || callRequest.Params.Name == "codeindex_status" {
+				return next(ctx, method, req)
+			}
+
+			if waitTimeout > 0 {
+				timer := time.NewTimer(waitTimeout)
+				defer timer.Stop()
+				select {
+				case <-progress.Ready():
+				case <-timer.C:
+				case <-ctx.Done():
+				}
+			}
+
+			result, err := next(ctx, method, req)
+			if toolResult, ok := result.(*mcp.CallToolResult); ok && err == nil && !progress.IsReady() {
+				notice := &mcp.TextContent{Text: tools.IndexingNotice(progress.State())}
+				toolResult.Content = append([]mcp.Content{notice}, toolResult.Content...)
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectWithIndexing returns a client session of the test server with IndexingMiddleware.
+func connectWithIndexing(t *testing.T, progress *index.Progress, waitTimeout time.Duration) *mcp.ClientSession {
+	t.Helper()
+	mcpServer := newTestMCPServer()
+	mcp.AddTool(mcpServer, &mcp.Tool{Name: "codeindex_status"}, func(ctx context.Context, req *mcp.CallToolRequest, args pingArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "status"}}}, nil, nil
+	})
+	mcpServer.AddReceivingMiddleware(IndexingMiddleware(progress, waitTimeout))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	if _, err := mcpServer.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// callText calls a tool and returns its text contents.
+func callText(t *testing.T, session *mcp.ClientSession, name string) []string {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, len(result.Content))
+	for i, content := range result.Content {
+		texts[i] = content.(*mcp.TextContent).Text
+	}
+	return texts
+}
+
+func Test_IndexingMiddleware_NoticeWhileIndexing(t *testing.T) {
+	progress := index.NewProgress(index.PhaseIndexing)
+	progress.AddDiscovered(30)
+	progress.AddProcessed(12)
+	session := connectWithIndexing(t, progress, 0)
+
+	texts := callText(t, session, "ping")
+	if len(texts) != 2 || !strings.Contains(texts[0], "indexing in progress: 12/30 files") || texts[1] != "pong" {
+		t.Errorf("expected a progress notice before the result, got %q", texts)
+	}
+	if texts := callText(t, session, "codeindex_status"); len(texts) != 1 {
+		t.Errorf("expected codeindex_status without notice, got %q", texts)
+	}
+
+	progress.Finish()
+	if texts := callText(t, session, "ping"); len(texts) != 1 || texts[0] != "pong" {
+		t.Errorf("expected no notice once ready, got %q", texts)
+	}
+}
+
+func Test_IndexingMiddleware_WaitsForIndexing(t *testing.T) {
+	progress := index.NewProgress(index.PhaseIndexing)
+	session := connectWithIndexing(t, progress, time.Minute)
+
+	time.AfterFunc(20*time.Millisecond, progress.Finish)
+	start := time.Now()
+	texts := callText(t, session, "ping")
+
+	if len(texts) != 1 || texts[0] != "pong" {
+		t.Errorf("expected the complete result without notice, got %q", texts)
+	}
+	if time.Since(start) > 30*time.Second {
+		t.Error("expected the call to return once indexing finished")
+	}
+}
+
+func Test_IndexingMiddleware_WaitTimeout(t *testing.T) {
+	progress := index.NewProgress(index.PhaseSyncing)
+	session := connectWithIndexing(t, progress, 10*time.Millisecond)
+
+	texts := callText(t, session, "ping")
+	if len(texts) != 2 || !strings.Contains(texts[0], "syncing snapshot in progress") {
+		t.Errorf("expected a progress notice after the wait timed out, got %q", texts)
+	}
+}
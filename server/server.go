@@ -12,11 +12,15 @@ func Setup(
 	statusHandler *tools.StatusHandler,
 	reindexHandler *tools.ReindexHandler,
 	readHandler *tools.ReadHandler,
+	symbolsHandler *tools.SymbolsHandler,
+	outlineHandler *tools.OutlineHandler,
+	referencesHandler *tools.ReferencesHandler,
+	explainIgnoreHandler *tools.ExplainIgnoreHandler,
 ) *mcp.Server {
 	mcpServer := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    "codeindex-mcp",
-			Version: "0.5.1",
+			Version: Version,
 		},
 		&mcp.ServerOptions{
 			Instructions: `This server provides in-memory indexed code search. Its tools are ALWAYS faster than built-in Grep, Search, Glob, Read, and find because they use a pre-built in-memory index instead of scanning the filesystem on every call.
@@ -26,7 +30,13 @@ ALWAYS prefer these tools over built-in alternatives:
 - Use codeindex_search with filePath to search within a specific file (instead of Read + manual search)
 - Use codeindex_read instead of Read to read file contents (zero disk I/O, served from memory)
 - Use codeindex_files instead of Glob or find for file search
-- The index updates automatically when files change (via filesystem watcher)`,
+- Use codeindex_outline to see the structure of a file (imports, types, methods, functions with line ranges) before reading it
+- Use codeindex_symbols to find where a function, type, class or constant is defined (instead of searching for its name)
+- Use codeindex_references to find the usages of a function or type (call sites, type references, imports), classified so comments and strings are not mistaken for code
+- Use codeindex_explain_ignore to find out why a file is missing from the index (which ignore rule, size limit or binary detection excluded it)
+- In a multi-root workspace paths start with the root name; pass root to search, files or read to restrict a call to one root
+- The index updates automatically when files change (via filesystem watcher)
+- Right after startup the index may still be building: results then start with "Note: indexing in progress" and may be incomplete; codeindex_status shows the progress`,
 		},
 	)
 
@@ -37,12 +47,25 @@ ALWAYS prefer these tools over built-in alternatives:
 
 Query formats:
   - Plain text: word-level matching (e.g., "handleRequest")
-  - "quoted text": exact phrase matching (e.g., "\"func main\"")
-  - /regex/: regular expression matching (e.g., "/func\s+\w+Handler/")
+  - "quoted text": exact substring matching, case-insensitive (e.g., "\"err != nil {\"")
+  - /regex/: regular expression matching, line by line (e.g., "/func\s+\w+Handler/")
+
+Combine terms with AND (default), OR, NOT or -term and parentheses, e.g. "(timeout OR deadline) -\"TODO\"".
+Qualifiers: lang:go, path:internal/, file:*_test.go, -path:vendor, sym:Handler (files defining the symbol; matches its definition lines), case:yes (case-sensitive).
 
 Filtering:
   - filePath: exact relative path to search in a single file (e.g., "src/main.go"). Overrides fileGlob.
-  - fileGlob: glob pattern to filter by file type (e.g., "**/*.go").`,
+  - fileGlob: glob pattern to filter by file type (e.g., "**/*.go").
+  - root: in a multi-root workspace, only search this root (filePath and fileGlob become root-relative).
+
+Matching:
+  - caseSensitive: match words, phrases and symbol names with their case (e.g., "Config" but not "config").
+  - wholeWord: only match whole words (e.g., "id" but not "valid" or "id_token").
+  - terms: how the words of plain text must occur: "any" (default), "all" (every word in the file) or "line" (every word on one line). Files with more distinct terms are listed first.
+
+Ordering:
+  - sort: "relevance" (default: definitions, file name matches, short and recently modified files first; tests, generated and vendored code last), "path" or "recent" (last modified first).
+  - preferTests: rank test files above other files.`,
 	}, searchHandler.Handle)
 
 	// Register codeindex_files tool
@@ -54,7 +77,9 @@ Pattern examples:
   - "**/*.go" - all Go files
   - "src/**/*.ts" - TypeScript files under src/
   - "**/test_*.py" - Python test files
-  - "*.json" - JSON files in root only`,
+  - "*.json" - JSON files in root only
+
+In a multi-root workspace paths start with the root name; pass root to match only one root with a root-relative pattern.`,
 	}, filesHandler.Handle)
 
 	// Register codeindex_read tool
@@ -63,6 +88,49 @@ Pattern examples:
 		Description: `Read a file's contents from the in-memory index. Zero disk I/O — faster than the built-in Read tool. Returns numbered lines (format: "N: content"). Use this instead of Read for any indexed file. By default reads up to 2000 lines. Optionally specify a line offset and limit (especially handy for long files).`,
 	}, readHandler.Handle)
 
+	// Register codeindex_outline tool
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name: "codeindex_outline",
+		Description: `Get the structure of an indexed file without reading it: package, imports, and a hierarchical list of types, their methods and top-level functions with line ranges and signatures. Go is parsed precisely; other languages (TypeScript/JavaScript, Python, Rust, Java, C#, C/C++, Ruby, PHP, ...) are outlined heuristically.
+
+Output: a header line, then one line per symbol: "start-end kind Name | signature", with members indented under their type. Use the line ranges with codeindex_read (offset/limit) to read only the parts you need.`,
+	}, outlineHandler.Handle)
+
+	// Register codeindex_references tool
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name: "codeindex_references",
+		Description: `Find all usages of an identifier across the indexed files. Matches whole identifiers only (case-sensitive) and classifies each hit as definition, call, type, import, reference (any other use), comment or string. Go files are analyzed with go/ast; other languages use identifier-boundary matching that recognizes comments and string literals.
+
+Optionally pass definitionPath/definitionLine (e.g. from codeindex_symbols) to mark the definition and, for unexported Go identifiers, restrict results to the defining package. Use kind (e.g. "call,type") to drop noise.
+
+Output: a summary with counts per kind, then one line per hit: "path:line:column kind | source line", definitions first.`,
+	}, referencesHandler.Handle)
+
+	// Register codeindex_symbols tool
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name: "codeindex_symbols",
+		Description: `Find symbol definitions (functions, methods, types, interfaces, classes, constants) by name. Returns only definition sites, not usages.
+
+Matching modes:
+  - exact (default): case-insensitive name match (e.g., "NewServer")
+  - prefix: names starting with the query (e.g., "handle")
+  - fuzzy: subsequence match (e.g., "gubi" finds "getUserByID")
+
+Filtering:
+  - kind: comma-separated kinds (e.g., "function,method")
+  - language: language name (e.g., "Go", "TypeScript")
+
+Output format: "path:startLine-endLine kind Container.Name"`,
+	}, symbolsHandler.Handle)
+
+	// Register codeindex_explain_ignore tool
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name: "codeindex_explain_ignore",
+		Description: `Explain why a file or directory is or is not indexed. Reports the deciding rule: a default pattern, a --exclude pattern, or the ignore file and line (.gitignore, .claudeignore, .git/info/exclude, core.excludesFile) — including when a parent directory is skipped. Also reports the size limit, binary detection, whether a --force-include overrides the rule, and whether the file is currently in the index.
+
+Path: absolute, or relative to the project root (in a multi-root workspace prefixed with the root name, or pass root).`,
+	}, explainIgnoreHandler.Handle)
+
 	// Register codeindex_status tool
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "codeindex_status",
@@ -72,7 +140,7 @@ Pattern examples:
 	// Register codeindex_reindex tool
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "codeindex_reindex",
-		Description: "Force a full re-index of the project. Clears existing index and rebuilds from scratch.",
+		Description: "Force a full re-index of the project. Rebuilds the index from scratch in the background; searches keep using the current index until the rebuild completes.",
 	}, reindexHandler.Handle)
 
 	return mcpServer
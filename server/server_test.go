@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/tools"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectToSetup returns a client session of the server built by Setup. Only the search
+// and reindex handlers are usable.
+func connectToSetup(t *testing.T) *mcp.ClientSession {
+	t.Helper()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { contentIndex.Close() })
+	contentIndex.IndexFile("main.go", "package main\n\nfunc main() {}\n", "Go")
+
+	mcpServer := Setup(
+		&tools.SearchHandler{ContentIndex: contentIndex, Logger: testLogger()},
+		&tools.FilesHandler{},
+		&tools.StatusHandler{},
+		&tools.ReindexHandler{
+			DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
+				return 3, 300, "1s", nil
+			},
+			Logger: testLogger(),
+		},
+		&tools.ReadHandler{},
+		&tools.SymbolsHandler{},
+		&tools.OutlineHandler{},
+		&tools.ReferencesHandler{},
+		&tools.ExplainIgnoreHandler{},
+	)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	if _, err := mcpServer.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func Test_Setup_DeclaresOutputSchemas(t *testing.T) {
+	session := connectToSetup(t)
+
+	count := 0
+	for tool, err := range session.Tools(context.Background(), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		schema, _ := tool.OutputSchema.(map[string]any)
+		if schema["type"] != "object" {
+			t.Errorf("%s: expected an object output schema, got %v", tool.Name, tool.OutputSchema)
+		}
+	}
+	if count != 9 {
+		t.Errorf("expected 9 tools, got %d", count)
+	}
+}
+
+func Test_Setup_StructuredContent(t *testing.T) {
+	session := connectToSetup(t)
+	ctx := context.Background()
+
+	// The text content is kept next to the structured content
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "codeindex_search", Arguments: map[string]any{"query": "main"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var search tools.SearchOutput
+	if err := remarshal(result.StructuredContent, &search); err != nil {
+		t.Fatal(err)
+	}
+	if len(search.Files) != 1 || search.Files[0].Path != "main.go" || search.Files[0].Matches[0].Line != 1 {
+		t.Errorf("unexpected structured content: %+v", search)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; text == "" || text[0] == '{' {
+		t.Errorf("expected the text output, got %q", text)
+	}
+
+	result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "codeindex_reindex", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reindex tools.ReindexOutput
+	if err := remarshal(result.StructuredContent, &reindex); err != nil || reindex.Files != 3 {
+		t.Errorf("unexpected structured content: %+v (err %v)", result.StructuredContent, err)
+	}
+
+	// Error results are still valid against the output schema
+	result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "codeindex_search", Arguments: map[string]any{"query": ""}})
+	if err != nil {
+		t.Fatalf("expected an error result, got %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected IsError=true for an empty query")
+	}
+}
+
+// remarshal decodes structured content received by a client into target.
+func remarshal(content any, target any) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
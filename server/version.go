@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"runtime/debug"
+)
+
+// version is set at build time by release builds:
+//
+//	go build -ldflags "-X github.com/lexandro/codeindex-mcp/server.version=1.2.3"
+var version string
+
+// Version is the server version reported to MCP clients and on the health endpoint.
+// A shared daemon only accepts clients of the same version, so builds without an
+// injected version are told apart by their executable (see buildVersion).
+var Version = buildVersion(version)
+
+// buildVersion returns injected if it is set, else the module version recorded by
+// go install module@version, else a development version derived from a hash of the
+// running executable, so that any two different builds disagree.
+func buildVersion(injected string) string {
+	if injected != "" {
+		return injected
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	if hash := executableHash(); hash != "" {
+		return "devel+" + hash
+	}
+	return "devel"
+}
+
+// executableHash returns the first 12 hex digits of the SHA-256 of the running
+// executable, or "" if it cannot be read.
+func executableHash() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
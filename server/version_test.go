@@ -0,0 +1,21 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_buildVersion(t *testing.T) {
+	if got := buildVersion("1.2.3"); got != "1.2.3" {
+		t.Errorf("expected the injected version, got %q", got)
+	}
+
+	// The test binary has no module version, so the executable hash identifies it
+	got := buildVersion("")
+	if !strings.HasPrefix(got, "devel+") || len(got) != len("devel+")+12 {
+		t.Errorf("expected devel+ and 12 hex digits, got %q", got)
+	}
+	if got != buildVersion("") {
+		t.Errorf("expected the same version for the same executable")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/daemon"
+	"github.com/lexandro/codeindex-mcp/server"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// daemonStartTimeout bounds how long a client waits for a daemon to start serving.
+const daemonStartTimeout = 5 * time.Minute
+
+// runSharedClient attaches to the shared daemon of the workspace whose state lives in
+// stateDir, starting it if needed, and proxies stdio to it. Returns false if no daemon could be used before stdio was touched,
+// in which case the caller runs a standalone server instead.
+func runSharedClient(stateDir string, logger *slog.Logger) bool {
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// The daemon gets the same flags and working directory, so it resolves the same roots
+	spawnArgs := append(append([]string{}, os.Args[1:]...), "--daemon")
+
+	info, err := daemon.Attach(ctx, daemon.AttachOptions{
+		Dir:        stateDir,
+		Version:    server.Version,
+		HealthPath: server.HealthPath,
+		SpawnArgs:  spawnArgs,
+		Timeout:    daemonStartTimeout,
+		Logger:     logger,
+	})
+	if err != nil {
+		logger.Warn("cannot use shared daemon, running standalone", "error", err)
+		return false
+	}
+
+	proxy, err := daemon.NewProxy(ctx, info, server.MCPPath)
+	if err != nil {
+		logger.Warn("cannot connect to shared daemon, running standalone", "error", err)
+		return false
+	}
+	defer proxy.Close()
+
+	logger.Info("proxying MCP on stdio to shared daemon", "pid", info.PID, "socket", info.SocketPath, "tools", proxy.ToolCount)
+	if err := proxy.Serve(ctx, &mcp.StdioTransport{}); err != nil && ctx.Err() == nil {
+		logger.Error("MCP proxy error", "error", err)
+	}
+	return true
+}
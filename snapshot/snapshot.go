@@ -0,0 +1,121 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+)
+
+// FormatVersion identifies the on-disk snapshot layout and Bleve mapping.
+// Bump it whenever IndexedFile, the snapshot struct, or the Bleve index mapping changes
+// so that stale caches are discarded instead of being loaded.
+const FormatVersion = 6
+
+const (
+	snapshotFileName = "snapshot.gob"
+	bleveDirName     = "bleve"
+)
+
+// Snapshot is the persisted state of the file and content indexes for a single root.
+type Snapshot struct {
+	Version   int
+	RootDir   string
+	CreatedAt time.Time
+	Files     []*index.IndexedFile
+	Contents  map[string]string // key: relative path, value: file content
+}
+
+// DefaultBaseDir returns the default cache base directory (e.g. ~/.cache/codeindex-mcp on Linux).
+func DefaultBaseDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolving user cache dir: %w", err)
+	}
+	return filepath.Join(userCacheDir, "codeindex-mcp"), nil
+}
+
+// Dir returns the cache directory for a project root inside baseDir.
+// The directory name is derived from a hash of the absolute root path so
+// that different projects never share a cache.
+func Dir(baseDir string, rootDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(rootDir)))
+	return filepath.Join(baseDir, hex.EncodeToString(sum[:8]))
+}
+
+// BleveDir returns the location of the on-disk Bleve index inside a cache directory.
+func BleveDir(cacheDir string) string {
+	return filepath.Join(cacheDir, bleveDirName)
+}
+
+// Save writes the snapshot to cacheDir atomically (temp file + rename).
+func Save(cacheDir string, snap *Snapshot) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(cacheDir, snapshotFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp snapshot: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	snap.Version = FormatVersion
+	if err := gob.NewEncoder(tmpFile).Encode(snap); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("closing temp snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(cacheDir, snapshotFileName)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("renaming snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads the snapshot from cacheDir and validates its version and root.
+// Returns an error if no snapshot exists or it cannot be used.
+func Load(cacheDir string, rootDir string) (*Snapshot, error) {
+	f, err := os.Open(filepath.Join(cacheDir, snapshotFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snap Snapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if snap.Version != FormatVersion {
+		return nil, fmt.Errorf("snapshot version %d does not match current version %d", snap.Version, FormatVersion)
+	}
+	if filepath.Clean(snap.RootDir) != filepath.Clean(rootDir) {
+		return nil, fmt.Errorf("snapshot root %s does not match %s", snap.RootDir, rootDir)
+	}
+	if snap.Contents == nil {
+		snap.Contents = make(map[string]string)
+	}
+	return &snap, nil
+}
+
+// Invalidate removes the snapshot file so that it cannot be loaded again.
+// Called right after a successful load: the on-disk Bleve index is modified
+// while the server runs, so the snapshot is only valid again after the next Save.
+// A crash therefore leads to a cold start instead of loading inconsistent state.
+func Invalidate(cacheDir string) error {
+	err := os.Remove(filepath.Join(cacheDir, snapshotFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+)
+
+func Test_Dir_DiffersPerRoot(t *testing.T) {
+	a := Dir("/cache", "/projects/a")
+	b := Dir("/cache", "/projects/b")
+	if a == b {
+		t.Errorf("expected different cache dirs for different roots, both got %s", a)
+	}
+	if Dir("/cache", "/projects/a/") != a {
+		t.Error("expected trailing slash to be ignored")
+	}
+}
+
+func Test_SaveAndLoad_RoundTrip(t *testing.T) {
+	cacheDir := t.TempDir()
+	modTime := time.Now().Truncate(time.Second)
+
+	err := Save(cacheDir, &Snapshot{
+		RootDir:   "/project",
+		CreatedAt: time.Now(),
+		Files: []*index.IndexedFile{
+			{Path: "/project/main.go", RelativePath: "main.go", Language: "Go", SizeBytes: 13, ModTime: modTime, LineCount: 2},
+		},
+		Contents: map[string]string{"main.go": "package main\n"},
+	})
+	if err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+
+	snap, err := Load(cacheDir, "/project")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if len(snap.Files) != 1 || snap.Files[0].RelativePath != "main.go" {
+		t.Fatalf("unexpected files: %+v", snap.Files)
+	}
+	if !snap.Files[0].ModTime.Equal(modTime) {
+		t.Errorf("expected ModTime %v, got %v", modTime, snap.Files[0].ModTime)
+	}
+	if snap.Contents["main.go"] != "package main\n" {
+		t.Errorf("unexpected content: %q", snap.Contents["main.go"])
+	}
+}
+
+func Test_Load_RejectsOtherRoot(t *testing.T) {
+	cacheDir := t.TempDir()
+	Save(cacheDir, &Snapshot{RootDir: "/project"})
+
+	if _, err := Load(cacheDir, "/other"); err == nil {
+		t.Error("expected error when loading snapshot for a different root")
+	}
+}
+
+func Test_Load_RejectsOldVersion(t *testing.T) {
+	cacheDir := t.TempDir()
+	Save(cacheDir, &Snapshot{RootDir: "/project"})
+
+	// Rewrite with an outdated version number
+	snap, _ := Load(cacheDir, "/project")
+	snap.Version = FormatVersion - 1
+	f, _ := os.Create(filepath.Join(cacheDir, snapshotFileName))
+	encodeRaw(t, f, snap)
+	f.Close()
+
+	if _, err := Load(cacheDir, "/project"); err == nil {
+		t.Error("expected error when loading snapshot with old version")
+	}
+}
+
+func Test_Invalidate(t *testing.T) {
+	cacheDir := t.TempDir()
+	Save(cacheDir, &Snapshot{RootDir: "/project"})
+
+	if err := Invalidate(cacheDir); err != nil {
+		t.Fatalf("invalidate error: %v", err)
+	}
+	if _, err := Load(cacheDir, "/project"); err == nil {
+		t.Error("expected load to fail after invalidate")
+	}
+	// Invalidating again is not an error
+	if err := Invalidate(cacheDir); err != nil {
+		t.Errorf("expected no error on second invalidate, got %v", err)
+	}
+}
+
+func encodeRaw(t *testing.T, f *os.File, snap *Snapshot) {
+	t.Helper()
+	if err := gob.NewEncoder(f).Encode(snap); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+}
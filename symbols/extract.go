@@ -0,0 +1,28 @@
+package symbols
+
+import "sort"
+
+// Extract returns the symbol definitions in a file's content, sorted by start line.
+// Go is parsed with go/parser; other languages use line-based heuristics.
+// Languages without extraction rules yield no symbols.
+func Extract(relativePath string, content string, language string) []Symbol {
+	var result []Symbol
+
+	if language == "Go" {
+		goSymbols, ok := extractGo(content)
+		if ok {
+			result = goSymbols
+		}
+	} else if rules, ok := languageRules[language]; ok {
+		result = extractHeuristic(content, rules)
+	}
+
+	for i := range result {
+		result[i].RelativePath = relativePath
+		result[i].Language = language
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartLine < result[j].StartLine
+	})
+	return result
+}
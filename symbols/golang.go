@@ -0,0 +1,153 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// extractGo extracts symbols from Go source using go/parser.
+// Files with syntax errors still yield the declarations the parser could recover.
+// Returns false if the file could not be parsed at all.
+func extractGo(content string) ([]Symbol, bool) {
+	fset := token.NewFileSet()
+	// On syntax errors the parser still returns the partial AST, which is worth using
+	file, _ := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if file == nil {
+		return nil, false
+	}
+
+	lineOf := func(pos token.Pos) int { return fset.Position(pos).Line }
+	sourceOf := func(from, to token.Pos) string {
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		// Recovered nodes of a broken file may carry unusable positions
+		if start < 0 || end > len(content) || start > end {
+			return ""
+		}
+		return content[start:end]
+	}
+
+	var result []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			symbol := Symbol{
+				Name:      d.Name.Name,
+				Kind:      KindFunction,
+				StartLine: lineOf(d.Pos()),
+				EndLine:   lineOf(d.End()),
+				Signature: oneLine(sourceOf(d.Pos(), d.Type.End())),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				symbol.Kind = KindMethod
+				symbol.Container = receiverTypeName(d.Recv.List[0].Type)
+			}
+			result = append(result, symbol)
+
+		case *ast.GenDecl:
+			result = append(result, extractGoGenDecl(d, lineOf, sourceOf)...)
+		}
+	}
+	return result, true
+}
+
+// extractGoGenDecl extracts type, const and var declarations (including grouped ones).
+func extractGoGenDecl(decl *ast.GenDecl, lineOf func(token.Pos) int, sourceOf func(from, to token.Pos) string) []Symbol {
+	var result []Symbol
+	for _, spec := range decl.Specs {
+		// A single ungrouped spec spans the whole declaration including the keyword
+		startPos, endPos := spec.Pos(), spec.End()
+		if !decl.Lparen.IsValid() {
+			startPos, endPos = decl.Pos(), decl.End()
+		}
+
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			kind := KindType
+			// Struct and interface bodies are listed as members, not repeated in the signature
+			signature := "type " + oneLine(sourceOf(s.Pos(), s.End()))
+			switch t := s.Type.(type) {
+			case *ast.StructType:
+				kind = KindStruct
+				signature = "type " + oneLine(sourceOf(s.Pos(), t.Struct)) + " struct"
+			case *ast.InterfaceType:
+				kind = KindInterface
+				signature = "type " + oneLine(sourceOf(s.Pos(), t.Interface)) + " interface"
+				result = append(result, extractGoInterfaceMethods(s.Name.Name, t, lineOf, sourceOf)...)
+			}
+			result = append(result, Symbol{
+				Name:      s.Name.Name,
+				Kind:      kind,
+				StartLine: lineOf(startPos),
+				EndLine:   lineOf(endPos),
+				Signature: signature,
+			})
+
+		case *ast.ValueSpec:
+			kind := KindVariable
+			if decl.Tok == token.CONST {
+				kind = KindConstant
+			}
+			signature := decl.Tok.String() + " " + oneLine(sourceOf(s.Pos(), s.End()))
+			for _, name := range s.Names {
+				if name.Name == "_" {
+					continue
+				}
+				result = append(result, Symbol{
+					Name:      name.Name,
+					Kind:      kind,
+					StartLine: lineOf(startPos),
+					EndLine:   lineOf(endPos),
+					Signature: signature,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// extractGoInterfaceMethods returns the methods declared in an interface type.
+func extractGoInterfaceMethods(
+	interfaceName string,
+	iface *ast.InterfaceType,
+	lineOf func(token.Pos) int,
+	sourceOf func(from, to token.Pos) string,
+) []Symbol {
+	var result []Symbol
+	for _, field := range iface.Methods.List {
+		if _, isFunc := field.Type.(*ast.FuncType); !isFunc {
+			continue // embedded interface or type constraint
+		}
+		for _, name := range field.Names {
+			result = append(result, Symbol{
+				Name:      name.Name,
+				Kind:      KindMethod,
+				Container: interfaceName,
+				StartLine: lineOf(field.Pos()),
+				EndLine:   lineOf(field.End()),
+				Signature: name.Name + oneLine(sourceOf(field.Type.Pos(), field.Type.End())),
+			})
+		}
+	}
+	return result
+}
+
+// receiverTypeName returns the base type name of a method receiver (e.g. *Server[T] -> Server).
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
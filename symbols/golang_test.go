@@ -0,0 +1,103 @@
+package symbols
+
+import "testing"
+
+const goSample = `package server
+
+import "net/http"
+
+// MaxConnections limits concurrent clients.
+const MaxConnections = 100
+
+const (
+	modeA = iota
+	modeB
+)
+
+var defaultServer *Server
+
+type Server struct {
+	addr string
+}
+
+type Handler interface {
+	Serve(w http.ResponseWriter, r *http.Request)
+	Close() error
+}
+
+type ID string
+
+func NewServer(addr string) *Server {
+	return &Server{addr: addr}
+}
+
+func (s *Server) Start() error {
+	return nil
+}
+
+func (l List[T]) Len() int { return 0 }
+`
+
+func findSymbol(symbols []Symbol, name string) *Symbol {
+	for i := range symbols {
+		if symbols[i].Name == name {
+			return &symbols[i]
+		}
+	}
+	return nil
+}
+
+func Test_extractGo_Declarations(t *testing.T) {
+	symbols, ok := extractGo(goSample)
+	if !ok {
+		t.Fatal("expected Go source to parse")
+	}
+
+	tests := []struct {
+		name      string
+		kind      Kind
+		container string
+		startLine int
+		endLine   int
+	}{
+		{"MaxConnections", KindConstant, "", 6, 6},
+		{"modeA", KindConstant, "", 9, 9},
+		{"modeB", KindConstant, "", 10, 10},
+		{"defaultServer", KindVariable, "", 13, 13},
+		{"Server", KindStruct, "", 15, 17},
+		{"Handler", KindInterface, "", 19, 22},
+		{"Serve", KindMethod, "Handler", 20, 20},
+		{"ID", KindType, "", 24, 24},
+		{"NewServer", KindFunction, "", 26, 28},
+		{"Start", KindMethod, "Server", 30, 32},
+		{"Len", KindMethod, "List", 34, 34},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol := findSymbol(symbols, tt.name)
+			if symbol == nil {
+				t.Fatalf("symbol %s not found", tt.name)
+			}
+			if symbol.Kind != tt.kind {
+				t.Errorf("expected kind %s, got %s", tt.kind, symbol.Kind)
+			}
+			if symbol.Container != tt.container {
+				t.Errorf("expected container %q, got %q", tt.container, symbol.Container)
+			}
+			if symbol.StartLine != tt.startLine || symbol.EndLine != tt.endLine {
+				t.Errorf("expected lines %d-%d, got %d-%d", tt.startLine, tt.endLine, symbol.StartLine, symbol.EndLine)
+			}
+		})
+	}
+}
+
+func Test_extractGo_SyntaxErrorKeepsRecoveredDecls(t *testing.T) {
+	symbols, ok := extractGo("package main\n\nfunc good() {}\n\nfunc broken( {\n")
+	if !ok {
+		t.Fatal("expected partial parse to succeed")
+	}
+	if findSymbol(symbols, "good") == nil {
+		t.Error("expected declaration before the syntax error to be recovered")
+	}
+}
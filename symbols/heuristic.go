@@ -0,0 +1,421 @@
+package symbols
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rule is a line-based pattern recognizing one kind of definition.
+// The pattern must contain a named group "name".
+type rule struct {
+	pattern *regexp.Regexp
+	kind    Kind
+	// containerOnly marks blocks that enclose methods but are not symbols themselves (e.g. Rust impl)
+	containerOnly bool
+	// memberOnly restricts the rule to lines inside a container (e.g. un-keyworded method syntax)
+	memberOnly bool
+}
+
+func newRule(pattern string, kind Kind) rule {
+	return rule{pattern: regexp.MustCompile(pattern), kind: kind}
+}
+
+func newContainerRule(pattern string) rule {
+	return rule{pattern: regexp.MustCompile(pattern), kind: KindClass, containerOnly: true}
+}
+
+func newMemberRule(pattern string, kind Kind) rule {
+	return rule{pattern: regexp.MustCompile(pattern), kind: kind, memberOnly: true}
+}
+
+// Shared fragments for C-family modifiers.
+const (
+	jsExport       = `^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?`
+	javaModifiers  = `^\s*(?:@\w+(?:\([^)]*\))?\s+)*(?:(?:public|private|protected|internal|static|final|abstract|sealed|open|data|partial|readonly|unsafe|override|virtual|async|synchronized|native|new|extern|default|inline|suspend|private\[\w+\]|protected\[\w+\]|implicit|lazy|case)\s+)*`
+	rustVisibility = `^\s*(?:pub(?:\([^)]*\))?\s+)?`
+)
+
+var jsRules = []rule{
+	newRule(jsExport+`(?:async\s+)?function\s*\*?\s*(?P<name>[A-Za-z_$][\w$]*)`, KindFunction),
+	newRule(jsExport+`(?:abstract\s+)?class\s+(?P<name>[A-Za-z_$][\w$]*)`, KindClass),
+	newRule(jsExport+`interface\s+(?P<name>[A-Za-z_$][\w$]*)`, KindInterface),
+	newRule(jsExport+`type\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`, KindType),
+	newRule(jsExport+`(?:const\s+)?enum\s+(?P<name>[A-Za-z_$][\w$]*)`, KindEnum),
+	newRule(jsExport+`namespace\s+(?P<name>[A-Za-z_$][\w$.]*)`, KindModule),
+	newRule(jsExport+`(?:const|let|var)\s+(?P<name>[A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`, KindFunction),
+	newRule(jsExport+`const\s+(?P<name>[A-Z][A-Z0-9_]*)\s*(?::[^=]+)?=`, KindConstant),
+	newMemberRule(`^\s*(?:(?:public|private|protected|static|async|readonly|override|abstract|get|set)\s+)*\*?(?P<name>[A-Za-z_$#][\w$]*)\s*(?:<[^>]*>)?\s*\([^)]*\)?\s*(?::[^{;]*)?\{?\s*$`, KindMethod),
+}
+
+var pythonRules = []rule{
+	newRule(`^\s*(?:async\s+)?def\s+(?P<name>\w+)`, KindFunction),
+	newRule(`^\s*class\s+(?P<name>\w+)`, KindClass),
+	newRule(`^(?P<name>[A-Z][A-Z0-9_]*)\s*(?::[^=]+)?=[^=]`, KindConstant),
+}
+
+var rustRules = []rule{
+	newRule(rustVisibility+`(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+(?P<name>\w+)`, KindFunction),
+	newRule(rustVisibility+`struct\s+(?P<name>\w+)`, KindStruct),
+	newRule(rustVisibility+`enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(rustVisibility+`(?:unsafe\s+)?trait\s+(?P<name>\w+)`, KindInterface),
+	newRule(rustVisibility+`type\s+(?P<name>\w+)`, KindType),
+	newRule(rustVisibility+`(?:const|static(?:\s+mut)?)\s+(?P<name>\w+)\s*:`, KindConstant),
+	newRule(rustVisibility+`mod\s+(?P<name>\w+)`, KindModule),
+	newContainerRule(`^\s*(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?(?P<name>\w+)`),
+}
+
+var javaRules = []rule{
+	newRule(javaModifiers+`(?:class|record)\s+(?P<name>\w+)`, KindClass),
+	newRule(javaModifiers+`(?:@interface|interface)\s+(?P<name>\w+)`, KindInterface),
+	newRule(javaModifiers+`enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(javaModifiers+`static\s+final\s+[\w<>\[\], ]+\s+(?P<name>[A-Z][A-Z0-9_]*)\s*=`, KindConstant),
+	newRule(javaModifiers+`(?:<[^>]+>\s+)?[\w<>\[\],.? ]+\s+(?P<name>\w+)\s*\([^;]*$`, KindMethod),
+}
+
+var kotlinRules = []rule{
+	newRule(javaModifiers+`(?:enum\s+class)\s+(?P<name>\w+)`, KindEnum),
+	newRule(javaModifiers+`(?:class|object)\s+(?P<name>\w+)`, KindClass),
+	newRule(javaModifiers+`interface\s+(?P<name>\w+)`, KindInterface),
+	newRule(javaModifiers+`fun\s+(?:<[^>]*>\s*)?(?:[\w.<>]+\.)?(?P<name>\w+)\s*\(`, KindFunction),
+	newRule(javaModifiers+`const\s+val\s+(?P<name>\w+)`, KindConstant),
+	newRule(javaModifiers+`typealias\s+(?P<name>\w+)`, KindType),
+}
+
+var scalaRules = []rule{
+	newRule(javaModifiers+`(?:class|object)\s+(?P<name>\w+)`, KindClass),
+	newRule(javaModifiers+`trait\s+(?P<name>\w+)`, KindInterface),
+	newRule(javaModifiers+`def\s+(?P<name>\w+)`, KindFunction),
+	newRule(javaModifiers+`type\s+(?P<name>\w+)`, KindType),
+}
+
+var csharpRules = []rule{
+	newRule(javaModifiers+`(?:class|record)\s+(?P<name>\w+)`, KindClass),
+	newRule(javaModifiers+`struct\s+(?P<name>\w+)`, KindStruct),
+	newRule(javaModifiers+`interface\s+(?P<name>\w+)`, KindInterface),
+	newRule(javaModifiers+`enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(javaModifiers+`namespace\s+(?P<name>[\w.]+)`, KindModule),
+	newRule(javaModifiers+`const\s+[\w<>\[\]?]+\s+(?P<name>\w+)\s*=`, KindConstant),
+	newRule(javaModifiers+`(?:<[^>]+>\s+)?[\w<>\[\],.? ]+\s+(?P<name>\w+)\s*(?:<[^>]*>)?\s*\([^;]*$`, KindMethod),
+}
+
+var swiftRules = []rule{
+	newRule(javaModifiers+`(?:final\s+)?class\s+(?P<name>\w+)`, KindClass),
+	newRule(javaModifiers+`struct\s+(?P<name>\w+)`, KindStruct),
+	newRule(javaModifiers+`protocol\s+(?P<name>\w+)`, KindInterface),
+	newRule(javaModifiers+`enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(javaModifiers+`(?:mutating\s+)?func\s+(?P<name>\w+)`, KindFunction),
+	newRule(javaModifiers+`typealias\s+(?P<name>\w+)`, KindType),
+	newContainerRule(`^\s*(?:\w+\s+)*extension\s+(?P<name>\w+)`),
+}
+
+var dartRules = []rule{
+	newRule(`^\s*(?:abstract\s+)?(?:class|mixin)\s+(?P<name>\w+)`, KindClass),
+	newRule(`^\s*enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(`^\s*typedef\s+(?P<name>\w+)`, KindType),
+	newRule(`^\s*(?:static\s+)?const\s+(?:[\w<>]+\s+)?(?P<name>\w+)\s*=`, KindConstant),
+	newRule(`^\s*(?:static\s+)?(?:Future<[^>]*>|[\w<>?]+)\s+(?P<name>\w+)\s*\([^;]*$`, KindFunction),
+}
+
+var cRules = []rule{
+	newRule(`^\s*(?:typedef\s+)?struct\s+(?P<name>\w+)\s*\{?\s*$`, KindStruct),
+	newRule(`^\s*(?:typedef\s+)?union\s+(?P<name>\w+)\s*\{?\s*$`, KindStruct),
+	newRule(`^\s*(?:typedef\s+)?enum\s+(?:class\s+)?(?P<name>\w+)`, KindEnum),
+	newRule(`^\s*(?:template\s*<[^>]*>\s*)?class\s+(?P<name>\w+)\s*(?:final\s*)?(?::[^{;]*)?\{?\s*$`, KindClass),
+	newRule(`^\s*namespace\s+(?P<name>\w+)`, KindModule),
+	newRule(`^\s*#\s*define\s+(?P<name>\w+)`, KindConstant),
+	newRule(`^(?:[\w*&:<>,]+\s+)+[*&]*(?P<name>[A-Za-z_][\w:~]*)\s*\([^;]*\)\s*(?:const\s*)?(?:noexcept\s*)?(?:override\s*)?\{?\s*$`, KindFunction),
+}
+
+var rubyRules = []rule{
+	newRule(`^\s*def\s+(?:self\.)?(?P<name>\w+[?!=]?)`, KindFunction),
+	newRule(`^\s*class\s+(?P<name>[\w:]+)`, KindClass),
+	newRule(`^\s*module\s+(?P<name>[\w:]+)`, KindModule),
+	newRule(`^\s*(?P<name>[A-Z][A-Z0-9_]*)\s*=[^=]`, KindConstant),
+}
+
+var phpRules = []rule{
+	newRule(`^\s*(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+&?(?P<name>\w+)`, KindFunction),
+	newRule(`^\s*(?:abstract\s+|final\s+|readonly\s+)*class\s+(?P<name>\w+)`, KindClass),
+	newRule(`^\s*interface\s+(?P<name>\w+)`, KindInterface),
+	newRule(`^\s*trait\s+(?P<name>\w+)`, KindClass),
+	newRule(`^\s*enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(`^\s*(?:(?:public|private|protected)\s+)?const\s+(?P<name>\w+)\s*=`, KindConstant),
+}
+
+var shellRules = []rule{
+	newRule(`^\s*function\s+(?P<name>[\w.:-]+)`, KindFunction),
+	newRule(`^\s*(?P<name>[\w.:-]+)\s*\(\)\s*\{?`, KindFunction),
+}
+
+var luaRules = []rule{
+	newRule(`^\s*(?:local\s+)?function\s+(?P<name>[\w.:]+)`, KindFunction),
+	newRule(`^\s*(?:local\s+)?(?P<name>[\w.]+)\s*=\s*function\b`, KindFunction),
+}
+
+var elixirRules = []rule{
+	newRule(`^\s*defmodule\s+(?P<name>[\w.]+)`, KindModule),
+	newRule(`^\s*defprotocol\s+(?P<name>[\w.]+)`, KindInterface),
+	newRule(`^\s*(?:def|defp|defmacro|defmacrop)\s+(?P<name>\w+[?!]?)`, KindFunction),
+}
+
+var erlangRules = []rule{
+	newRule(`^(?P<name>[a-z]\w*)\s*\([^)]*\)\s*(?:when\s+.*)?->`, KindFunction),
+	newRule(`^-record\((?P<name>\w+)`, KindStruct),
+	newRule(`^-type\s+(?P<name>\w+)`, KindType),
+}
+
+var haskellRules = []rule{
+	newRule(`^data\s+(?P<name>\w+)`, KindType),
+	newRule(`^newtype\s+(?P<name>\w+)`, KindType),
+	newRule(`^type\s+(?P<name>\w+)`, KindType),
+	newRule(`^class\s+(?:\([^)]*\)\s*=>\s*)?(?P<name>\w+)`, KindInterface),
+	newRule(`^(?P<name>[a-z_]\w*)\s*::`, KindFunction),
+}
+
+var zigRules = []rule{
+	newRule(`^\s*(?:pub\s+)?(?:export\s+)?(?:inline\s+)?fn\s+(?P<name>\w+)`, KindFunction),
+	newRule(`^\s*(?:pub\s+)?const\s+(?P<name>\w+)\s*=\s*(?:extern\s+|packed\s+)?struct\b`, KindStruct),
+	newRule(`^\s*(?:pub\s+)?const\s+(?P<name>\w+)\s*=\s*(?:union|enum)\b`, KindEnum),
+	newRule(`^\s*(?:pub\s+)?const\s+(?P<name>\w+)\s*=`, KindConstant),
+}
+
+var sqlRules = []rule{
+	newRule(`(?i)^\s*create\s+(?:or\s+replace\s+)?(?:temporary\s+|temp\s+)?table\s+(?:if\s+not\s+exists\s+)?(?P<name>[\w."]+)`, KindType),
+	newRule(`(?i)^\s*create\s+(?:or\s+replace\s+)?(?:materialized\s+)?view\s+(?:if\s+not\s+exists\s+)?(?P<name>[\w."]+)`, KindType),
+	newRule(`(?i)^\s*create\s+(?:or\s+replace\s+)?(?:function|procedure)\s+(?P<name>[\w."]+)`, KindFunction),
+}
+
+var graphqlRules = []rule{
+	newRule(`^\s*(?:extend\s+)?(?:type|input)\s+(?P<name>\w+)`, KindType),
+	newRule(`^\s*interface\s+(?P<name>\w+)`, KindInterface),
+	newRule(`^\s*enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(`^\s*(?:union|scalar)\s+(?P<name>\w+)`, KindType),
+}
+
+var protobufRules = []rule{
+	newRule(`^\s*message\s+(?P<name>\w+)`, KindStruct),
+	newRule(`^\s*enum\s+(?P<name>\w+)`, KindEnum),
+	newRule(`^\s*service\s+(?P<name>\w+)`, KindInterface),
+	newRule(`^\s*rpc\s+(?P<name>\w+)`, KindMethod),
+}
+
+var terraformRules = []rule{
+	newRule(`^\s*(?:resource|data)\s+"\w+"\s+"(?P<name>[\w-]+)"`, KindType),
+	newRule(`^\s*module\s+"(?P<name>[\w-]+)"`, KindModule),
+	newRule(`^\s*variable\s+"(?P<name>[\w-]+)"`, KindVariable),
+	newRule(`^\s*output\s+"(?P<name>[\w-]+)"`, KindVariable),
+}
+
+var rRules = []rule{
+	newRule(`^\s*(?P<name>[\w.]+)\s*(?:<-|=)\s*function\b`, KindFunction),
+}
+
+var powershellRules = []rule{
+	newRule(`(?i)^\s*function\s+(?P<name>[\w-]+)`, KindFunction),
+	newRule(`(?i)^\s*class\s+(?P<name>\w+)`, KindClass),
+	newRule(`(?i)^\s*enum\s+(?P<name>\w+)`, KindEnum),
+}
+
+var makefileRules = []rule{
+	newRule(`^(?P<name>[\w./-]+)\s*:(?:[^=]|$)`, KindFunction),
+}
+
+// languageRules maps language names (as produced by language.DetectLanguage) to their rules.
+var languageRules = map[string][]rule{
+	"JavaScript": jsRules,
+	"TypeScript": jsRules,
+	"Vue":        jsRules,
+	"Svelte":     jsRules,
+	"Python":     pythonRules,
+	"Rust":       rustRules,
+	"Java":       javaRules,
+	"Kotlin":     kotlinRules,
+	"Scala":      scalaRules,
+	"C#":         csharpRules,
+	"Swift":      swiftRules,
+	"Dart":       dartRules,
+	"C":          cRules,
+	"C++":        cRules,
+	"Ruby":       rubyRules,
+	"PHP":        phpRules,
+	"Shell":      shellRules,
+	"PowerShell": powershellRules,
+	"Lua":        luaRules,
+	"Elixir":     elixirRules,
+	"Erlang":     erlangRules,
+	"Haskell":    haskellRules,
+	"Zig":        zigRules,
+	"SQL":        sqlRules,
+	"GraphQL":    graphqlRules,
+	"Protobuf":   protobufRules,
+	"Terraform":  terraformRules,
+	"R":          rRules,
+	"Makefile":   makefileRules,
+}
+
+// controlKeywords are words that look like calls/definitions in C-like syntax but are not symbols.
+var controlKeywords = map[string]bool{
+	"if": true, "else": true, "for": true, "foreach": true, "while": true, "do": true,
+	"switch": true, "case": true, "catch": true, "return": true, "new": true, "throw": true,
+	"function": true, "typeof": true, "sizeof": true, "await": true, "yield": true,
+	"using": true, "lock": true, "synchronized": true, "with": true, "elif": true,
+}
+
+// openContainer tracks a container block while scanning lines.
+type openContainer struct {
+	name    string
+	endLine int
+}
+
+// extractHeuristic extracts symbols line by line using the language's rules.
+// Line ranges are estimated from indentation and brace structure (see findBlockEnd).
+func extractHeuristic(content string, rules []rule) []Symbol {
+	lines := strings.Split(content, "\n")
+	var result []Symbol
+	var containers []openContainer
+
+	for lineIdx, line := range lines {
+		lineNumber := lineIdx + 1
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Drop containers that ended before this line
+		for len(containers) > 0 && containers[len(containers)-1].endLine < lineNumber {
+			containers = containers[:len(containers)-1]
+		}
+
+		for _, r := range rules {
+			if r.memberOnly && len(containers) == 0 {
+				continue
+			}
+			match := r.pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			name := match[r.pattern.SubexpIndex("name")]
+			if name == "" || controlKeywords[name] {
+				continue
+			}
+
+			endLine := lineNumber
+			if r.kind != KindConstant && r.kind != KindVariable {
+				endLine = findBlockEnd(lines, lineIdx) + 1
+			}
+
+			if r.containerOnly {
+				containers = append(containers, openContainer{name: name, endLine: endLine})
+				break
+			}
+
+			symbol := Symbol{
+				Name:      name,
+				Kind:      r.kind,
+				StartLine: lineNumber,
+				EndLine:   endLine,
+				Signature: heuristicSignature(line),
+			}
+			if len(containers) > 0 {
+				symbol.Container = containers[len(containers)-1].name
+				if symbol.Kind == KindFunction {
+					symbol.Kind = KindMethod
+				}
+			}
+			splitQualifiedName(&symbol)
+			result = append(result, symbol)
+
+			if isContainerKind(symbol.Kind) && endLine > lineNumber {
+				containers = append(containers, openContainer{name: symbol.Name, endLine: endLine})
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+// heuristicSignature derives a signature from a definition line by dropping the
+// block opener ("{", Python's ":", Ruby/Elixir "do") that follows the header.
+func heuristicSignature(line string) string {
+	signature := strings.TrimSpace(line)
+	for _, opener := range []string{"{", ":", " do"} {
+		signature = strings.TrimSpace(strings.TrimSuffix(signature, opener))
+	}
+	return oneLine(signature)
+}
+
+// splitQualifiedName turns "Table.method" / "Table:method" (Lua) into a method with a container.
+func splitQualifiedName(symbol *Symbol) {
+	if symbol.Kind != KindFunction {
+		return
+	}
+	separator := strings.LastIndexAny(symbol.Name, ".:")
+	if separator <= 0 || separator == len(symbol.Name)-1 {
+		return
+	}
+	symbol.Container = symbol.Name[:separator]
+	symbol.Name = symbol.Name[separator+1:]
+	symbol.Kind = KindMethod
+}
+
+// findBlockEnd estimates the last line (0-based) of a definition starting at startIdx.
+// A definition whose braces balance on its own line, or that ends with ';', is a single line.
+// Otherwise the block extends until the next non-blank line indented no deeper than the
+// definition; that line is included if it is a closing token (}, end, ), ]) at the same indent.
+func findBlockEnd(lines []string, startIdx int) int {
+	startLine := lines[startIdx]
+	openBraces := strings.Count(startLine, "{")
+	if openBraces > 0 && openBraces == strings.Count(startLine, "}") {
+		return startIdx
+	}
+	if strings.HasSuffix(strings.TrimSpace(startLine), ";") {
+		return startIdx
+	}
+
+	startIndent := indentWidth(startLine)
+	lastNonBlank := startIdx
+	for i := startIdx + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if indent := indentWidth(lines[i]); indent <= startIndent {
+			if indent == startIndent && isClosingLine(trimmed) {
+				return i
+			}
+			// Opening brace on its own line (Allman style) belongs to the definition
+			if trimmed == "{" && i == lastNonBlank+1 {
+				lastNonBlank = i
+				continue
+			}
+			return lastNonBlank
+		}
+		lastNonBlank = i
+	}
+	return lastNonBlank
+}
+
+// isClosingLine reports whether a trimmed line closes a block.
+func isClosingLine(trimmed string) bool {
+	switch trimmed[0] {
+	case '}', ')', ']':
+		return true
+	}
+	return trimmed == "end" || strings.HasPrefix(trimmed, "end ") || strings.HasPrefix(trimmed, "end.")
+}
+
+// indentWidth returns the indentation width of a line, counting a tab as 4 columns.
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,80 @@
+package symbols
+
+import "testing"
+
+func Test_Extract_Heuristic(t *testing.T) {
+	tests := []struct {
+		language  string
+		content   string
+		name      string
+		kind      Kind
+		container string
+		startLine int
+		endLine   int
+	}{
+		{"Python", "class UserStore:\n    def load(self, id):\n        return id\n\n    def save(self):\n        pass\n", "load", KindMethod, "UserStore", 2, 3},
+		{"Python", "class UserStore:\n    pass\n\ndef helper():\n    return 1\n", "helper", KindFunction, "", 4, 5},
+		{"Python", "MAX_SIZE = 10\n", "MAX_SIZE", KindConstant, "", 1, 1},
+		{"TypeScript", "export class SessionStore {\n  get(id: string): Session {\n    return this.map[id];\n  }\n}\n", "SessionStore", KindClass, "", 1, 5},
+		{"TypeScript", "export class SessionStore {\n  get(id: string): Session {\n    return this.map[id];\n  }\n}\n", "get", KindMethod, "SessionStore", 2, 4},
+		{"TypeScript", "export interface Options {\n  name: string;\n}\n", "Options", KindInterface, "", 1, 3},
+		{"TypeScript", "export const handleClick = async (e) => {\n  go();\n};\n", "handleClick", KindFunction, "", 1, 3},
+		{"JavaScript", "function render() {\n  return 1;\n}\n", "render", KindFunction, "", 1, 3},
+		{"Rust", "pub struct Repo {\n    db: Db,\n}\n\nimpl Repo {\n    pub fn find_by_email(&self) -> User {\n        todo!()\n    }\n}\n", "find_by_email", KindMethod, "Repo", 6, 8},
+		{"Rust", "pub trait Store {\n    fn get(&self);\n}\n", "Store", KindInterface, "", 1, 3},
+		{"Java", "public class OrderService {\n    public Order findOrder(long id) {\n        return null;\n    }\n}\n", "findOrder", KindMethod, "OrderService", 2, 4},
+		{"Java", "public interface Repository<T> {\n}\n", "Repository", KindInterface, "", 1, 2},
+		{"C", "static int parse_args(int argc, char **argv)\n{\n    return 0;\n}\n", "parse_args", KindFunction, "", 1, 4},
+		{"C", "#define BUFFER_SIZE 4096\n", "BUFFER_SIZE", KindConstant, "", 1, 1},
+		{"Ruby", "class Account\n  def balance\n    @balance\n  end\nend\n", "balance", KindMethod, "Account", 2, 4},
+		{"Lua", "function M.setup(opts)\n  return opts\nend\n", "setup", KindMethod, "M", 1, 3},
+		{"Kotlin", "data class User(val id: Int)\n", "User", KindClass, "", 1, 1},
+		{"Shell", "deploy() {\n  echo hi\n}\n", "deploy", KindFunction, "", 1, 3},
+		{"SQL", "CREATE TABLE IF NOT EXISTS users (\n  id INT\n);\n", "users", KindType, "", 1, 3},
+		{"Protobuf", "service Greeter {\n  rpc SayHello (HelloRequest) returns (HelloReply);\n}\n", "SayHello", KindMethod, "Greeter", 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language+"/"+tt.name, func(t *testing.T) {
+			symbols := Extract("file", tt.content, tt.language)
+			symbol := findSymbol(symbols, tt.name)
+			if symbol == nil {
+				t.Fatalf("symbol %s not found in %+v", tt.name, symbols)
+			}
+			if symbol.Kind != tt.kind {
+				t.Errorf("expected kind %s, got %s", tt.kind, symbol.Kind)
+			}
+			if symbol.Container != tt.container {
+				t.Errorf("expected container %q, got %q", tt.container, symbol.Container)
+			}
+			if symbol.StartLine != tt.startLine || symbol.EndLine != tt.endLine {
+				t.Errorf("expected lines %d-%d, got %d-%d", tt.startLine, tt.endLine, symbol.StartLine, symbol.EndLine)
+			}
+		})
+	}
+}
+
+func Test_Extract_IgnoresControlFlow(t *testing.T) {
+	content := "public class A {\n    void run() {\n        if (ready) {\n        }\n        while (x) {\n        }\n    }\n}\n"
+	for _, symbol := range Extract("A.java", content, "Java") {
+		if symbol.Name == "if" || symbol.Name == "while" {
+			t.Errorf("control keyword extracted as symbol: %+v", symbol)
+		}
+	}
+}
+
+func Test_Extract_UnknownLanguage(t *testing.T) {
+	if symbols := Extract("notes.txt", "function foo() {}", "Text"); len(symbols) != 0 {
+		t.Errorf("expected no symbols for unsupported language, got %+v", symbols)
+	}
+}
+
+func Test_Extract_SetsPathAndLanguage(t *testing.T) {
+	symbols := Extract("pkg/a.go", "package a\n\nfunc A() {}\n", "Go")
+	if len(symbols) != 1 {
+		t.Fatalf("expected 1 symbol, got %d", len(symbols))
+	}
+	if symbols[0].RelativePath != "pkg/a.go" || symbols[0].Language != "Go" {
+		t.Errorf("unexpected path/language: %+v", symbols[0])
+	}
+}
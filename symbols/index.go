@@ -0,0 +1,214 @@
+package symbols
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MatchMode selects how LookupOptions.Query is compared with symbol names.
+type MatchMode string
+
+const (
+	MatchExact  MatchMode = "exact"  // case-insensitive equality
+	MatchPrefix MatchMode = "prefix" // case-insensitive prefix
+	MatchFuzzy  MatchMode = "fuzzy"  // case-insensitive subsequence (e.g. "gubi" -> getUserByID)
+)
+
+// LookupOptions configures a symbol lookup.
+type LookupOptions struct {
+	Query      string
+	Mode       MatchMode // default: MatchExact
+	Kinds      []Kind    // empty = all kinds
+	Language   string    // empty = all languages (case-insensitive)
+	MaxResults int
+}
+
+// Index is an in-memory index of symbol definitions, keyed by file.
+// Thread-safe: all methods may be called concurrently.
+type Index struct {
+	mu     sync.RWMutex
+	byPath map[string][]Symbol // key: relative path (forward slashes)
+	count  int
+}
+
+// NewIndex creates an empty symbol index.
+func NewIndex() *Index {
+	return &Index{
+		byPath: make(map[string][]Symbol),
+	}
+}
+
+// SetFile replaces all symbols of a file. An empty slice removes the file.
+func (idx *Index) SetFile(relativePath string, fileSymbols []Symbol) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.count -= len(idx.byPath[relativePath])
+	if len(fileSymbols) == 0 {
+		delete(idx.byPath, relativePath)
+		return
+	}
+	idx.byPath[relativePath] = fileSymbols
+	idx.count += len(fileSymbols)
+}
+
+// RemoveFile removes all symbols of a file.
+func (idx *Index) RemoveFile(relativePath string) {
+	idx.SetFile(relativePath, nil)
+}
+
+// FileSymbols returns the symbols of a single file, sorted by start line.
+func (idx *Index) FileSymbols(relativePath string) []Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.byPath[relativePath]
+}
+
+// SymbolCount returns the total number of indexed symbols.
+func (idx *Index) SymbolCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.count
+}
+
+// Clear removes all symbols.
+func (idx *Index) Clear() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.byPath = make(map[string][]Symbol)
+	idx.count = 0
+}
+
+// Swap atomically replaces all symbols with those of next. next must not be used afterwards.
+func (idx *Index) Swap(next *Index) {
+	next.mu.Lock()
+	byPath, count := next.byPath, next.count
+	next.mu.Unlock()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.byPath = byPath
+	idx.count = count
+}
+
+// Lookup finds symbols matching the options. Results are ordered by match quality
+// (exact name before prefix before fuzzy, shorter names first), then by path and line.
+func (idx *Index) Lookup(options LookupOptions) ([]Symbol, error) {
+	if options.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if options.Mode == "" {
+		options.Mode = MatchExact
+	}
+	if options.Mode != MatchExact && options.Mode != MatchPrefix && options.Mode != MatchFuzzy {
+		return nil, fmt.Errorf("unknown match mode %q (expected exact, prefix or fuzzy)", options.Mode)
+	}
+	if options.MaxResults <= 0 {
+		options.MaxResults = 50
+	}
+
+	kindFilter := make(map[Kind]bool, len(options.Kinds))
+	for _, kind := range options.Kinds {
+		kindFilter[kind] = true
+	}
+	queryLower := strings.ToLower(options.Query)
+
+	type scoredSymbol struct {
+		symbol Symbol
+		score  int
+	}
+	var matches []scoredSymbol
+
+	idx.mu.RLock()
+	for _, fileSymbols := range idx.byPath {
+		for _, symbol := range fileSymbols {
+			if len(kindFilter) > 0 && !kindFilter[symbol.Kind] {
+				continue
+			}
+			if options.Language != "" && !strings.EqualFold(symbol.Language, options.Language) {
+				continue
+			}
+			score, ok := matchScore(symbol.Name, options.Query, queryLower, options.Mode)
+			if !ok {
+				continue
+			}
+			matches = append(matches, scoredSymbol{symbol: symbol, score: score})
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].symbol.RelativePath != matches[j].symbol.RelativePath {
+			return matches[i].symbol.RelativePath < matches[j].symbol.RelativePath
+		}
+		return matches[i].symbol.StartLine < matches[j].symbol.StartLine
+	})
+
+	if len(matches) > options.MaxResults {
+		matches = matches[:options.MaxResults]
+	}
+	result := make([]Symbol, len(matches))
+	for i, match := range matches {
+		result[i] = match.symbol
+	}
+	return result, nil
+}
+
+// matchScore reports whether name matches the query in the given mode and how well.
+// Higher scores are better; an exact case-sensitive match always ranks first.
+func matchScore(name string, query string, queryLower string, mode MatchMode) (int, bool) {
+	nameLower := strings.ToLower(name)
+
+	if nameLower == queryLower {
+		if name == query {
+			return 3000, true
+		}
+		return 2900, true
+	}
+	if mode == MatchExact {
+		return 0, false
+	}
+
+	if strings.HasPrefix(nameLower, queryLower) {
+		return 2000 - len(name), true
+	}
+	if mode == MatchPrefix {
+		return 0, false
+	}
+
+	// Fuzzy: substring beats scattered subsequence
+	if strings.Contains(nameLower, queryLower) {
+		return 1000 - len(name), true
+	}
+	if isSubsequence(queryLower, nameLower) {
+		return 500 - len(name), true
+	}
+	return 0, false
+}
+
+// isSubsequence reports whether every byte of needle appears in haystack in order.
+func isSubsequence(needle string, haystack string) bool {
+	pos := 0
+	for i := 0; i < len(haystack) && pos < len(needle); i++ {
+		if haystack[i] == needle[pos] {
+			pos++
+		}
+	}
+	return pos == len(needle)
+}
+
+// ParseKind converts a kind name to a Kind, returning false if unknown.
+func ParseKind(name string) (Kind, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, kind := range AllKinds {
+		if string(kind) == name {
+			return kind, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,122 @@
+package symbols
+
+import "testing"
+
+func newTestIndex() *Index {
+	idx := NewIndex()
+	idx.SetFile("server.go", []Symbol{
+		{Name: "NewServer", Kind: KindFunction, RelativePath: "server.go", Language: "Go", StartLine: 10},
+		{Name: "Server", Kind: KindStruct, RelativePath: "server.go", Language: "Go", StartLine: 3},
+		{Name: "serverCount", Kind: KindVariable, RelativePath: "server.go", Language: "Go", StartLine: 1},
+	})
+	idx.SetFile("user.ts", []Symbol{
+		{Name: "getUserByID", Kind: KindFunction, RelativePath: "user.ts", Language: "TypeScript", StartLine: 5},
+		{Name: "Server", Kind: KindClass, RelativePath: "user.ts", Language: "TypeScript", StartLine: 1},
+	})
+	return idx
+}
+
+func symbolNames(symbols []Symbol) []string {
+	names := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		names[i] = symbol.RelativePath + ":" + symbol.Name
+	}
+	return names
+}
+
+func Test_Index_LookupExact(t *testing.T) {
+	idx := newTestIndex()
+
+	results, err := idx.Lookup(LookupOptions{Query: "server"})
+	if err != nil {
+		t.Fatalf("lookup error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %v", symbolNames(results))
+	}
+	if results[0].RelativePath != "server.go" {
+		t.Errorf("expected results sorted by path, got %v", symbolNames(results))
+	}
+}
+
+func Test_Index_LookupPrefix(t *testing.T) {
+	idx := newTestIndex()
+
+	results, _ := idx.Lookup(LookupOptions{Query: "Server", Mode: MatchPrefix})
+	names := symbolNames(results)
+	if len(names) != 3 {
+		t.Fatalf("expected 3 results, got %v", names)
+	}
+	// Exact case-sensitive matches rank before prefix matches
+	if results[2].Name != "serverCount" {
+		t.Errorf("expected prefix match last, got %v", names)
+	}
+}
+
+func Test_Index_LookupFuzzy(t *testing.T) {
+	idx := newTestIndex()
+
+	results, _ := idx.Lookup(LookupOptions{Query: "gubi", Mode: MatchFuzzy})
+	if len(results) != 1 || results[0].Name != "getUserByID" {
+		t.Errorf("expected getUserByID, got %v", symbolNames(results))
+	}
+}
+
+func Test_Index_LookupFilters(t *testing.T) {
+	idx := newTestIndex()
+
+	results, _ := idx.Lookup(LookupOptions{Query: "Server", Kinds: []Kind{KindClass}})
+	if len(results) != 1 || results[0].RelativePath != "user.ts" {
+		t.Errorf("expected only the TypeScript class, got %v", symbolNames(results))
+	}
+
+	results, _ = idx.Lookup(LookupOptions{Query: "Server", Language: "go"})
+	if len(results) != 1 || results[0].RelativePath != "server.go" {
+		t.Errorf("expected only the Go struct, got %v", symbolNames(results))
+	}
+}
+
+func Test_Index_LookupErrors(t *testing.T) {
+	idx := newTestIndex()
+
+	if _, err := idx.Lookup(LookupOptions{}); err == nil {
+		t.Error("expected error for empty query")
+	}
+	if _, err := idx.Lookup(LookupOptions{Query: "x", Mode: "regex"}); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func Test_Index_SetRemoveClear(t *testing.T) {
+	idx := newTestIndex()
+	if idx.SymbolCount() != 5 {
+		t.Fatalf("expected 5 symbols, got %d", idx.SymbolCount())
+	}
+
+	idx.SetFile("server.go", []Symbol{{Name: "Only", Kind: KindFunction, RelativePath: "server.go"}})
+	if idx.SymbolCount() != 3 {
+		t.Errorf("expected 3 symbols after replace, got %d", idx.SymbolCount())
+	}
+
+	idx.RemoveFile("user.ts")
+	if idx.SymbolCount() != 1 || len(idx.FileSymbols("user.ts")) != 0 {
+		t.Errorf("expected user.ts removed, count %d", idx.SymbolCount())
+	}
+
+	idx.Clear()
+	if idx.SymbolCount() != 0 {
+		t.Errorf("expected 0 symbols after clear, got %d", idx.SymbolCount())
+	}
+}
+
+func Test_Index_Swap(t *testing.T) {
+	idx := newTestIndex()
+	next := NewIndex()
+	next.SetFile("main.go", []Symbol{{Name: "main", Kind: KindFunction, RelativePath: "main.go"}})
+
+	idx.Swap(next)
+
+	if idx.SymbolCount() != 1 || len(idx.FileSymbols("server.go")) != 0 || len(idx.FileSymbols("main.go")) != 1 {
+		t.Errorf("expected only the new generation's symbols, count %d", idx.SymbolCount())
+	}
+}
@@ -0,0 +1,220 @@
+package symbols
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Outline is a structural summary of a single file.
+type Outline struct {
+	RelativePath string
+	Language     string
+	LineCount    int
+	Package      string // Package/module declaration, empty if the file has none
+	PackageLine  int
+	Imports      []Import
+	Items        []OutlineItem // Top-level symbols in source order
+}
+
+// Import is an imported package, module or header.
+type Import struct {
+	Path string
+	Line int
+}
+
+// OutlineItem is a symbol together with the symbols it encloses (e.g. a type and its methods).
+type OutlineItem struct {
+	Symbol   Symbol
+	Children []OutlineItem
+}
+
+// BuildOutline builds the outline of a file's content.
+// Go is parsed precisely; other languages use the same heuristics as Extract.
+func BuildOutline(relativePath string, content string, language string) *Outline {
+	outline := &Outline{
+		RelativePath: relativePath,
+		Language:     language,
+		LineCount:    strings.Count(content, "\n") + 1,
+	}
+	if strings.HasSuffix(content, "\n") {
+		outline.LineCount--
+	}
+
+	if language == "Go" {
+		goHeader(content, outline)
+	} else if header, ok := languageHeaders[language]; ok {
+		heuristicHeader(content, header, outline)
+	}
+
+	outline.Items = buildOutlineTree(Extract(relativePath, content, language))
+	return outline
+}
+
+// goHeader fills in the package clause and imports of a Go file.
+func goHeader(content string, outline *Outline) {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", content, parser.ImportsOnly)
+	if file == nil || file.Name == nil {
+		return
+	}
+	outline.Package = file.Name.Name
+	outline.PackageLine = fset.Position(file.Package).Line
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil {
+			path = spec.Name.Name + " " + path
+		}
+		outline.Imports = append(outline.Imports, Import{Path: path, Line: fset.Position(spec.Pos()).Line})
+	}
+}
+
+// headerRules recognize the package declaration and imports of a language.
+// Each pattern captures the name or path in its first group.
+type headerRules struct {
+	packagePattern *regexp.Regexp // nil if the language has no package declaration
+	importPatterns []*regexp.Regexp
+}
+
+func newHeaderRules(packagePattern string, importPatterns ...string) headerRules {
+	header := headerRules{}
+	if packagePattern != "" {
+		header.packagePattern = regexp.MustCompile(packagePattern)
+	}
+	for _, pattern := range importPatterns {
+		header.importPatterns = append(header.importPatterns, regexp.MustCompile(pattern))
+	}
+	return header
+}
+
+var (
+	jsHeader = newHeaderRules("",
+		`^\s*import\s+(?:[^'"]*\s+from\s+)?['"]([^'"]+)['"]`,
+		`^\s*export\s+[^'"]*\s+from\s+['"]([^'"]+)['"]`,
+		`\brequire\(\s*['"]([^'"]+)['"]\s*\)`,
+	)
+	pythonHeader = newHeaderRules("",
+		`^\s*import\s+([\w.]+)`,
+		`^\s*from\s+([\w.]+)\s+import\b`,
+	)
+	javaHeader = newHeaderRules(`^\s*package\s+([\w.]+)`,
+		`^\s*import\s+(?:static\s+)?([\w.*]+)`,
+	)
+	cHeader = newHeaderRules("",
+		`^\s*#\s*include\s*[<"]([^>"]+)[>"]`,
+	)
+)
+
+// languageHeaders maps language names to their header rules. Languages missing here
+// get an outline without package and imports.
+var languageHeaders = map[string]headerRules{
+	"JavaScript": jsHeader,
+	"TypeScript": jsHeader,
+	"Vue":        jsHeader,
+	"Svelte":     jsHeader,
+	"Python":     pythonHeader,
+	"Rust":       newHeaderRules("", `^\s*(?:pub\s+)?use\s+([^;]+);`),
+	"Java":       javaHeader,
+	"Kotlin":     javaHeader,
+	"Scala":      javaHeader,
+	"C#":         newHeaderRules(`^\s*namespace\s+([\w.]+)\s*;`, `^\s*using\s+(?:static\s+)?([\w.]+)\s*;`),
+	"Swift":      newHeaderRules("", `^\s*import\s+(?:\w+\s+)?([\w.]+)`),
+	"Dart":       newHeaderRules(`^\s*library\s+([\w.]+)\s*;`, `^\s*(?:import|export)\s+['"]([^'"]+)['"]`),
+	"C":          cHeader,
+	"C++":        cHeader,
+	"Ruby":       newHeaderRules("", `^\s*require(?:_relative)?\s*\(?\s*['"]([^'"]+)['"]`),
+	"PHP":        newHeaderRules(`^\s*namespace\s+([\w\\]+)\s*;`, `^\s*use\s+(?:function\s+|const\s+)?([\w\\]+)`),
+	"Lua":        newHeaderRules("", `\brequire\s*\(?\s*['"]([^'"]+)['"]`),
+	"Elixir":     newHeaderRules("", `^\s*(?:alias|import|use|require)\s+([\w.]+)`),
+	"Erlang":     newHeaderRules(`^-module\((\w+)\)`, `^-include(?:_lib)?\("([^"]+)"\)`),
+	"Haskell":    newHeaderRules(`^module\s+([\w.]+)`, `^import\s+(?:qualified\s+)?([\w.]+)`),
+	"Zig":        newHeaderRules("", `@import\("([^"]+)"\)`),
+	"Protobuf":   newHeaderRules(`^\s*package\s+([\w.]+)\s*;`, `^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"`),
+	"R":          newHeaderRules("", `^\s*(?:library|require)\(\s*['"]?([\w.]+)['"]?\s*\)`),
+}
+
+// heuristicHeader fills in the package declaration and imports using line patterns.
+func heuristicHeader(content string, header headerRules, outline *Outline) {
+	for lineIdx, line := range strings.Split(content, "\n") {
+		if header.packagePattern != nil && outline.Package == "" {
+			if match := header.packagePattern.FindStringSubmatch(line); match != nil {
+				outline.Package = match[1]
+				outline.PackageLine = lineIdx + 1
+				continue
+			}
+		}
+		for _, pattern := range header.importPatterns {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				outline.Imports = append(outline.Imports, Import{Path: strings.TrimSpace(match[1]), Line: lineIdx + 1})
+				break
+			}
+		}
+	}
+}
+
+// canEnclose reports whether symbols of this kind are listed with their members in an outline.
+// Unlike isContainerKind this includes plain types, which in Go can have methods.
+func canEnclose(kind Kind) bool {
+	return isContainerKind(kind) || kind == KindType
+}
+
+// buildOutlineTree nests symbols (sorted by start line) under their containers.
+// A symbol is placed under the container of the same name that encloses its lines,
+// falling back to the first one in the file (Go methods live outside their type's block).
+// Symbols whose container is not defined in the file stay at the top level.
+func buildOutlineTree(fileSymbols []Symbol) []OutlineItem {
+	type node struct {
+		symbol   Symbol
+		children []*node
+	}
+
+	nodes := make([]*node, len(fileSymbols))
+	containersByName := make(map[string][]int)
+	for i, symbol := range fileSymbols {
+		nodes[i] = &node{symbol: symbol}
+		if canEnclose(symbol.Kind) {
+			containersByName[symbol.Name] = append(containersByName[symbol.Name], i)
+		}
+	}
+
+	var roots []*node
+	for i, symbol := range fileSymbols {
+		parent := -1
+		for _, candidate := range containersByName[symbol.Container] {
+			if candidate == i {
+				continue
+			}
+			container := fileSymbols[candidate]
+			if container.StartLine <= symbol.StartLine && symbol.EndLine <= container.EndLine {
+				parent = candidate
+				break
+			}
+			if parent < 0 {
+				parent = candidate
+			}
+		}
+		if symbol.Container == "" || parent < 0 {
+			roots = append(roots, nodes[i])
+		} else {
+			nodes[parent].children = append(nodes[parent].children, nodes[i])
+		}
+	}
+
+	var convert func(list []*node) []OutlineItem
+	convert = func(list []*node) []OutlineItem {
+		if len(list) == 0 {
+			return nil
+		}
+		items := make([]OutlineItem, len(list))
+		for i, n := range list {
+			items[i] = OutlineItem{Symbol: n.symbol, Children: convert(n.children)}
+		}
+		return items
+	}
+	return convert(roots)
+}
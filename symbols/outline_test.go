@@ -0,0 +1,157 @@
+package symbols
+
+import "testing"
+
+func Test_BuildOutline_Go(t *testing.T) {
+	content := `package server
+
+import (
+	"context"
+	nethttp "net/http"
+)
+
+type Server struct {
+	addr string
+}
+
+func NewServer(addr string) *Server {
+	return &Server{addr: addr}
+}
+
+func (s *Server) Start(
+	ctx context.Context,
+	mux *nethttp.ServeMux,
+) error {
+	return nil
+}
+
+type Handler interface {
+	Serve(ctx context.Context) error
+}
+`
+	outline := BuildOutline("server.go", content, "Go")
+
+	if outline.Package != "server" || outline.PackageLine != 1 {
+		t.Errorf("expected package server on line 1, got %q on line %d", outline.Package, outline.PackageLine)
+	}
+	if outline.LineCount != 25 {
+		t.Errorf("expected 25 lines, got %d", outline.LineCount)
+	}
+	if len(outline.Imports) != 2 || outline.Imports[0] != (Import{Path: "context", Line: 4}) ||
+		outline.Imports[1] != (Import{Path: "nethttp net/http", Line: 5}) {
+		t.Errorf("unexpected imports: %+v", outline.Imports)
+	}
+
+	if len(outline.Items) != 3 {
+		t.Fatalf("expected 3 top-level items, got %+v", outline.Items)
+	}
+
+	server := outline.Items[0]
+	if server.Symbol.Name != "Server" || server.Symbol.Signature != "type Server struct" {
+		t.Errorf("unexpected first item: %+v", server.Symbol)
+	}
+	if len(server.Children) != 1 {
+		t.Fatalf("expected Start nested under Server, got %+v", server.Children)
+	}
+	start := server.Children[0].Symbol
+	if start.Name != "Start" || start.StartLine != 16 || start.EndLine != 21 {
+		t.Errorf("unexpected method: %+v", start)
+	}
+	if start.Signature != "func (s *Server) Start(ctx context.Context, mux *nethttp.ServeMux) error" {
+		t.Errorf("unexpected method signature: %q", start.Signature)
+	}
+
+	if outline.Items[1].Symbol.Signature != "func NewServer(addr string) *Server" {
+		t.Errorf("unexpected function signature: %q", outline.Items[1].Symbol.Signature)
+	}
+
+	handler := outline.Items[2]
+	if handler.Symbol.Signature != "type Handler interface" || len(handler.Children) != 1 {
+		t.Fatalf("unexpected interface item: %+v", handler)
+	}
+	if handler.Children[0].Symbol.Signature != "Serve(ctx context.Context) error" {
+		t.Errorf("unexpected interface method signature: %q", handler.Children[0].Symbol.Signature)
+	}
+}
+
+func Test_BuildOutline_GoMethodOnUndeclaredType(t *testing.T) {
+	content := "package server\n\nfunc (s *Server) Stop() {}\n"
+
+	outline := BuildOutline("stop.go", content, "Go")
+
+	if len(outline.Items) != 1 {
+		t.Fatalf("expected 1 top-level item, got %+v", outline.Items)
+	}
+	if symbol := outline.Items[0].Symbol; symbol.Container != "Server" || symbol.Kind != KindMethod {
+		t.Errorf("expected method with Server container at top level, got %+v", symbol)
+	}
+}
+
+func Test_BuildOutline_Heuristic(t *testing.T) {
+	content := `package com.example.orders;
+
+import java.util.List;
+import static java.util.Objects.requireNonNull;
+
+public class OrderService {
+    public List<Order> findOrders(long customerId) {
+        return null;
+    }
+
+    static class Cache {
+        void clear() {
+        }
+    }
+}
+`
+	outline := BuildOutline("OrderService.java", content, "Java")
+
+	if outline.Package != "com.example.orders" {
+		t.Errorf("expected package com.example.orders, got %q", outline.Package)
+	}
+	if len(outline.Imports) != 2 || outline.Imports[1].Path != "java.util.Objects.requireNonNull" {
+		t.Errorf("unexpected imports: %+v", outline.Imports)
+	}
+
+	if len(outline.Items) != 1 {
+		t.Fatalf("expected 1 top-level item, got %+v", outline.Items)
+	}
+	service := outline.Items[0]
+	if service.Symbol.Signature != "public class OrderService" {
+		t.Errorf("unexpected class signature: %q", service.Symbol.Signature)
+	}
+	if len(service.Children) != 2 {
+		t.Fatalf("expected 2 members, got %+v", service.Children)
+	}
+	if got := service.Children[0].Symbol.Signature; got != "public List<Order> findOrders(long customerId)" {
+		t.Errorf("unexpected method signature: %q", got)
+	}
+	cache := service.Children[1]
+	if cache.Symbol.Name != "Cache" || len(cache.Children) != 1 || cache.Children[0].Symbol.Name != "clear" {
+		t.Errorf("expected clear nested under Cache, got %+v", cache)
+	}
+}
+
+func Test_BuildOutline_PythonImports(t *testing.T) {
+	content := "import os\nfrom typing import List\n\n\ndef main():\n    pass\n"
+
+	outline := BuildOutline("main.py", content, "Python")
+
+	if len(outline.Imports) != 2 || outline.Imports[0].Path != "os" || outline.Imports[1].Path != "typing" {
+		t.Errorf("unexpected imports: %+v", outline.Imports)
+	}
+	if len(outline.Items) != 1 || outline.Items[0].Symbol.Signature != "def main()" {
+		t.Errorf("unexpected items: %+v", outline.Items)
+	}
+}
+
+func Test_BuildOutline_UnsupportedLanguage(t *testing.T) {
+	outline := BuildOutline("README.md", "# Title\n\nText\n", "Markdown")
+
+	if outline.Package != "" || len(outline.Imports) != 0 || len(outline.Items) != 0 {
+		t.Errorf("expected empty outline, got %+v", outline)
+	}
+	if outline.LineCount != 3 {
+		t.Errorf("expected 3 lines, got %d", outline.LineCount)
+	}
+}
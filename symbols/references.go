@@ -0,0 +1,101 @@
+package symbols
+
+import (
+	"sort"
+	"strings"
+)
+
+// ReferenceKind classifies an occurrence of an identifier.
+type ReferenceKind string
+
+const (
+	RefDefinition ReferenceKind = "definition"
+	RefCall       ReferenceKind = "call"
+	RefType       ReferenceKind = "type"
+	RefImport     ReferenceKind = "import"
+	RefComment    ReferenceKind = "comment"
+	RefString     ReferenceKind = "string"
+	RefOther      ReferenceKind = "reference" // Any other use (read, assignment, argument, ...)
+)
+
+// AllReferenceKinds lists every reference kind, in display order.
+var AllReferenceKinds = []ReferenceKind{
+	RefDefinition, RefCall, RefType, RefImport, RefOther, RefComment, RefString,
+}
+
+// Reference is a whole-identifier occurrence of a name in a file.
+type Reference struct {
+	RelativePath string
+	Line         int // 1-based
+	Column       int // 1-based, in bytes
+	Kind         ReferenceKind
+	LineText     string // The full source line, trimmed
+}
+
+// FindReferences returns every whole-identifier occurrence of name in a file's content,
+// sorted by position. Go is classified from its AST; other languages use identifier-boundary
+// matching with a lexer that recognizes comments and string literals.
+func FindReferences(relativePath string, content string, language string, name string) []Reference {
+	if name == "" || !strings.Contains(content, name) {
+		return nil
+	}
+
+	var result []Reference
+	parsed := false
+	if language == "Go" {
+		result, parsed = findGoReferences(content, name)
+	}
+	if !parsed {
+		result = findHeuristicReferences(relativePath, content, language, name)
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := range result {
+		result[i].RelativePath = relativePath
+		if result[i].Line >= 1 && result[i].Line <= len(lines) {
+			result[i].LineText = strings.TrimSpace(lines[result[i].Line-1])
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Line != result[j].Line {
+			return result[i].Line < result[j].Line
+		}
+		return result[i].Column < result[j].Column
+	})
+	return result
+}
+
+// ParseReferenceKind converts a reference kind name to a ReferenceKind, returning false if unknown.
+func ParseReferenceKind(name string) (ReferenceKind, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, kind := range AllReferenceKinds {
+		if string(kind) == name {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// isIdentifierByte reports whether c can be part of an identifier. Non-ASCII bytes are
+// treated as identifier characters so that names are never matched inside Unicode identifiers.
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// identifierOccurrences returns the byte offsets of whole-identifier occurrences of name in text.
+func identifierOccurrences(text string, name string) []int {
+	var offsets []int
+	for from := 0; ; {
+		idx := strings.Index(text[from:], name)
+		if idx < 0 {
+			return offsets
+		}
+		start := from + idx
+		end := start + len(name)
+		if (start == 0 || !isIdentifierByte(text[start-1])) && (end == len(text) || !isIdentifierByte(text[end])) {
+			offsets = append(offsets, start)
+		}
+		from = start + 1
+	}
+}
@@ -0,0 +1,166 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strconv"
+)
+
+// findGoReferences classifies the occurrences of name in Go source using the AST.
+// Returns false if the file has syntax errors; positions in a recovered AST are unreliable.
+func findGoReferences(content string, name string) ([]Reference, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+
+	var result []Reference
+	add := func(pos token.Pos, kind ReferenceKind) {
+		position := fset.Position(pos)
+		result = append(result, Reference{Line: position.Line, Column: position.Column, Kind: kind})
+	}
+
+	// Parents are visited before their children, so the role of an identifier is
+	// recorded by the enclosing node before the identifier itself is reached.
+	definitions := make(map[*ast.Ident]bool)
+	calls := make(map[*ast.Ident]bool)
+	types := make(map[*ast.Ident]bool)
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.ImportSpec:
+			importPath, _ := strconv.Unquote(n.Path.Value)
+			if n.Name != nil && n.Name.Name == name {
+				add(n.Name.Pos(), RefImport)
+			} else if path.Base(importPath) == name {
+				add(n.Path.Pos(), RefImport)
+			}
+			return false
+
+		case *ast.FuncDecl:
+			definitions[n.Name] = true
+		case *ast.TypeSpec:
+			definitions[n.Name] = true
+			markGoTypeExpr(n.Type, types)
+		case *ast.ValueSpec:
+			for _, ident := range n.Names {
+				definitions[ident] = true
+			}
+			markGoTypeExpr(n.Type, types)
+		case *ast.Field:
+			for _, ident := range n.Names {
+				definitions[ident] = true
+			}
+			markGoTypeExpr(n.Type, types)
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, expr := range n.Lhs {
+					if ident, ok := expr.(*ast.Ident); ok {
+						definitions[ident] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, expr := range []ast.Expr{n.Key, n.Value} {
+					if ident, ok := expr.(*ast.Ident); ok {
+						definitions[ident] = true
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			markGoTypeExpr(n.Type, types)
+		case *ast.TypeAssertExpr:
+			markGoTypeExpr(n.Type, types)
+		case *ast.CallExpr:
+			markGoCallee(n.Fun, calls)
+			// The first argument of make and new is a type
+			if builtin, ok := n.Fun.(*ast.Ident); ok && (builtin.Name == "make" || builtin.Name == "new") && len(n.Args) > 0 {
+				markGoTypeExpr(n.Args[0], types)
+			}
+
+		case *ast.BasicLit:
+			if n.Kind == token.STRING {
+				for _, offset := range identifierOccurrences(n.Value, name) {
+					add(n.Pos()+token.Pos(offset), RefString)
+				}
+			}
+		case *ast.Ident:
+			if n.Name != name {
+				return true
+			}
+			switch {
+			case definitions[n]:
+				add(n.Pos(), RefDefinition)
+			case calls[n]:
+				add(n.Pos(), RefCall)
+			case types[n]:
+				add(n.Pos(), RefType)
+			default:
+				add(n.Pos(), RefOther)
+			}
+		}
+		return true
+	})
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			for _, offset := range identifierOccurrences(comment.Text, name) {
+				add(comment.Pos()+token.Pos(offset), RefComment)
+			}
+		}
+	}
+
+	return result, true
+}
+
+// markGoCallee records the identifier naming the called function of a call expression.
+func markGoCallee(fun ast.Expr, calls map[*ast.Ident]bool) {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		calls[f] = true
+	case *ast.SelectorExpr:
+		calls[f.Sel] = true
+	case *ast.IndexExpr: // generic instantiation: f[T](x)
+		markGoCallee(f.X, calls)
+	case *ast.IndexListExpr:
+		markGoCallee(f.X, calls)
+	case *ast.ParenExpr:
+		markGoCallee(f.X, calls)
+	}
+}
+
+// markGoTypeExpr records the identifiers naming types in a type expression.
+// Struct, interface and func types are not descended into: their fields are visited separately.
+func markGoTypeExpr(expr ast.Expr, types map[*ast.Ident]bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		types[e] = true
+	case *ast.SelectorExpr: // pkg.Type: only the type name is a type reference
+		types[e.Sel] = true
+	case *ast.StarExpr:
+		markGoTypeExpr(e.X, types)
+	case *ast.ParenExpr:
+		markGoTypeExpr(e.X, types)
+	case *ast.ArrayType:
+		markGoTypeExpr(e.Elt, types)
+	case *ast.MapType:
+		markGoTypeExpr(e.Key, types)
+		markGoTypeExpr(e.Value, types)
+	case *ast.ChanType:
+		markGoTypeExpr(e.Value, types)
+	case *ast.Ellipsis:
+		markGoTypeExpr(e.Elt, types)
+	case *ast.IndexExpr:
+		markGoTypeExpr(e.X, types)
+		markGoTypeExpr(e.Index, types)
+	case *ast.IndexListExpr:
+		markGoTypeExpr(e.X, types)
+		for _, index := range e.Indices {
+			markGoTypeExpr(index, types)
+		}
+	}
+}
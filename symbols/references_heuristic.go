@@ -0,0 +1,245 @@
+package symbols
+
+import "strings"
+
+// lexStyle describes the comment and string literal syntax of a language.
+type lexStyle struct {
+	lineComments []string
+	blockStart   string // empty if the language has no block comments
+	blockEnd     string
+	quotes       string // characters that delimit string literals
+	tripleQuotes bool   // """ and ''' delimit multi-line strings (Python)
+}
+
+var (
+	cLexStyle      = &lexStyle{lineComments: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"}
+	rustLexStyle   = &lexStyle{lineComments: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: `"`} // ' also starts lifetimes
+	hashLexStyle   = &lexStyle{lineComments: []string{"#"}, quotes: `"'`}
+	pythonLexStyle = &lexStyle{lineComments: []string{"#"}, quotes: `"'`, tripleQuotes: true}
+)
+
+// languageLexStyles maps language names to their lexical syntax. Occurrences in files of
+// other languages are never classified as comments or strings.
+var languageLexStyles = map[string]*lexStyle{
+	"Go":         cLexStyle,
+	"JavaScript": cLexStyle,
+	"TypeScript": cLexStyle,
+	"Vue":        cLexStyle,
+	"Svelte":     cLexStyle,
+	"Java":       cLexStyle,
+	"Kotlin":     cLexStyle,
+	"Scala":      cLexStyle,
+	"C#":         cLexStyle,
+	"Swift":      cLexStyle,
+	"Dart":       cLexStyle,
+	"C":          cLexStyle,
+	"C++":        cLexStyle,
+	"Protobuf":   cLexStyle,
+	"Zig":        &lexStyle{lineComments: []string{"//"}, quotes: `"'`},
+	"Rust":       rustLexStyle,
+	"Python":     pythonLexStyle,
+	"Ruby":       hashLexStyle,
+	"Shell":      hashLexStyle,
+	"R":          hashLexStyle,
+	"Elixir":     hashLexStyle,
+	"Makefile":   hashLexStyle,
+	"GraphQL":    &lexStyle{lineComments: []string{"#"}, quotes: `"`},
+	"PowerShell": &lexStyle{lineComments: []string{"#"}, blockStart: "<#", blockEnd: "#>", quotes: `"'`},
+	"PHP":        &lexStyle{lineComments: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/", quotes: `"'`},
+	"Terraform":  &lexStyle{lineComments: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/", quotes: `"`},
+	"Lua":        &lexStyle{lineComments: []string{"--"}, blockStart: "--[[", blockEnd: "]]", quotes: `"'`},
+	"SQL":        &lexStyle{lineComments: []string{"--"}, blockStart: "/*", blockEnd: "*/", quotes: `"'`},
+	"Haskell":    &lexStyle{lineComments: []string{"--"}, blockStart: "{-", blockEnd: "-}", quotes: `"`},
+	"Erlang":     &lexStyle{lineComments: []string{"%"}, quotes: `"`},
+}
+
+// Lexical regions of a file, one value per byte.
+const (
+	regionCode byte = iota
+	regionComment
+	regionString
+)
+
+// typedDeclarationLanguages put the type before the declared name ("Order order"),
+// so a name directly followed by another identifier is used as a type.
+var typedDeclarationLanguages = map[string]bool{
+	"Java": true, "C#": true, "C": true, "C++": true, "Dart": true,
+}
+
+// typeKeywords precede a type name.
+var typeKeywords = map[string]bool{
+	"extends": true, "implements": true, "instanceof": true, "as": true, "satisfies": true,
+}
+
+// findHeuristicReferences classifies the whole-identifier occurrences of name in a
+// non-Go file (or a Go file that does not parse).
+func findHeuristicReferences(relativePath string, content string, language string, name string) []Reference {
+	regions := lexRegions(content, languageLexStyles[language])
+
+	definitionLines := make(map[int]bool)
+	for _, symbol := range Extract(relativePath, content, language) {
+		if symbol.Name == name {
+			definitionLines[symbol.StartLine] = true
+		}
+	}
+	header, hasHeader := languageHeaders[language]
+
+	var result []Reference
+	line, previousOffset := 1, 0
+	for _, offset := range identifierOccurrences(content, name) {
+		line += strings.Count(content[previousOffset:offset], "\n")
+		previousOffset = offset
+		lineStart := strings.LastIndexByte(content[:offset], '\n') + 1
+		lineEnd := strings.IndexByte(content[offset:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += offset
+		}
+
+		reference := Reference{Line: line, Column: offset - lineStart + 1}
+		switch {
+		case regions[offset] == regionComment:
+			reference.Kind = RefComment
+		case regions[offset] == regionString:
+			reference.Kind = RefString
+		case definitionLines[line]:
+			reference.Kind = RefDefinition
+			delete(definitionLines, line) // only the first occurrence on the line is the definition
+		case hasHeader && matchesImport(header, content[lineStart:lineEnd]):
+			reference.Kind = RefImport
+		default:
+			reference.Kind = classifyUsage(content[lineStart:offset], content[offset+len(name):lineEnd], language)
+		}
+		result = append(result, reference)
+	}
+	return result
+}
+
+// matchesImport reports whether a line is an import statement.
+func matchesImport(header headerRules, line string) bool {
+	for _, pattern := range header.importPatterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyUsage classifies a code occurrence from the text before and after it on its line.
+func classifyUsage(before string, after string, language string) ReferenceKind {
+	after = strings.TrimLeft(after, " \t")
+	before = strings.TrimRight(before, " \t")
+
+	if strings.HasPrefix(after, "(") {
+		return RefCall
+	}
+
+	// Type annotations (name: Type, fn() -> Type), but not paths (pkg::Type)
+	if strings.HasSuffix(before, ":") && !strings.HasSuffix(before, "::") || strings.HasSuffix(before, "->") {
+		return RefType
+	}
+	if typeKeywords[lastWord(before)] {
+		return RefType
+	}
+	if lastWord(before) == "new" {
+		return RefCall
+	}
+	if typedDeclarationLanguages[language] && after != "" && isIdentifierByte(after[0]) && lastWord(before) != "return" {
+		return RefType
+	}
+	return RefOther
+}
+
+// lastWord returns the identifier at the end of text, or "" if text does not end with one.
+func lastWord(text string) string {
+	start := len(text)
+	for start > 0 && isIdentifierByte(text[start-1]) {
+		start--
+	}
+	return text[start:]
+}
+
+// lexRegions marks every byte of content as code, comment or string literal.
+// Unterminated strings end at the end of their line, except backtick strings.
+func lexRegions(content string, style *lexStyle) []byte {
+	regions := make([]byte, len(content))
+	if style == nil {
+		return regions
+	}
+	fill := func(start, end int, region byte) {
+		for i := start; i < end; i++ {
+			regions[i] = region
+		}
+	}
+
+	for i := 0; i < len(content); {
+		rest := content[i:]
+
+		if style.blockStart != "" && strings.HasPrefix(rest, style.blockStart) {
+			end := len(content)
+			if idx := strings.Index(rest[len(style.blockStart):], style.blockEnd); idx >= 0 {
+				end = i + len(style.blockStart) + idx + len(style.blockEnd)
+			}
+			fill(i, end, regionComment)
+			i = end
+			continue
+		}
+
+		isLineComment := false
+		for _, marker := range style.lineComments {
+			if strings.HasPrefix(rest, marker) {
+				isLineComment = true
+				break
+			}
+		}
+		if isLineComment {
+			end := len(content)
+			if idx := strings.IndexByte(rest, '\n'); idx >= 0 {
+				end = i + idx
+			}
+			fill(i, end, regionComment)
+			i = end
+			continue
+		}
+
+		quote := content[i]
+		if strings.IndexByte(style.quotes, quote) < 0 {
+			i++
+			continue
+		}
+
+		if style.tripleQuotes && len(rest) >= 3 && rest[1] == quote && rest[2] == quote {
+			end := len(content)
+			if idx := strings.Index(rest[3:], rest[:3]); idx >= 0 {
+				end = i + 3 + idx + 3
+			}
+			fill(i, end, regionString)
+			i = end
+			continue
+		}
+
+		end := i + 1
+		for end < len(content) {
+			c := content[end]
+			if c == '\\' {
+				end += 2
+				continue
+			}
+			if c == quote {
+				end++
+				break
+			}
+			if c == '\n' && quote != '`' {
+				break
+			}
+			end++
+		}
+		if end > len(content) {
+			end = len(content)
+		}
+		fill(i, end, regionString)
+		i = end
+	}
+	return regions
+}
@@ -0,0 +1,164 @@
+package symbols
+
+import (
+	"fmt"
+	"testing"
+)
+
+// referenceKinds maps "line:column" to the kind of each reference, for compact assertions.
+func referenceKinds(references []Reference) map[string]ReferenceKind {
+	kinds := make(map[string]ReferenceKind, len(references))
+	for _, reference := range references {
+		kinds[fmt.Sprintf("%d:%d", reference.Line, reference.Column)] = reference.Kind
+	}
+	return kinds
+}
+
+func assertReferenceKinds(t *testing.T, references []Reference, expected map[string]ReferenceKind) {
+	t.Helper()
+	got := referenceKinds(references)
+	if len(got) != len(expected) {
+		t.Errorf("expected %d references, got %d: %v", len(expected), len(got), got)
+	}
+	for position, kind := range expected {
+		if got[position] != kind {
+			t.Errorf("at %s: expected %s, got %q (all: %v)", position, kind, got[position], got)
+		}
+	}
+}
+
+func Test_FindReferences_Go(t *testing.T) {
+	content := `package server
+
+// Server serves requests.
+type Server struct {
+	next *Server
+}
+
+func NewServer() *Server {
+	s := &Server{}
+	_ = Server.Start
+	_ = make([]Server, 0)
+	return s
+}
+
+func (s *Server) Start() error {
+	log("Server started")
+	return nil
+}
+`
+	references := FindReferences("server.go", content, "Go", "Server")
+
+	assertReferenceKinds(t, references, map[string]ReferenceKind{
+		"3:4":   RefComment,
+		"4:6":   RefDefinition,
+		"5:8":   RefType,
+		"8:19":  RefType,
+		"9:8":   RefType,
+		"10:6":  RefOther,
+		"11:13": RefType,
+		"15:10": RefType,
+		"16:7":  RefString,
+	})
+	if references[0].LineText != "// Server serves requests." || references[0].RelativePath != "server.go" {
+		t.Errorf("unexpected first reference: %+v", references[0])
+	}
+}
+
+func Test_FindReferences_GoCallsAndImports(t *testing.T) {
+	content := `package main
+
+import (
+	"net/http"
+	srv "example.com/server"
+)
+
+func main() {
+	s := srv.NewServer()
+	s.Start()
+	Start := 1
+	_ = Start
+	http.ListenAndServe(":80", nil)
+}
+`
+	assertReferenceKinds(t, FindReferences("main.go", content, "Go", "Start"), map[string]ReferenceKind{
+		"10:4": RefCall,
+		"11:2": RefDefinition,
+		"12:6": RefOther,
+	})
+	assertReferenceKinds(t, FindReferences("main.go", content, "Go", "http"), map[string]ReferenceKind{
+		"4:2":  RefImport,
+		"13:2": RefOther,
+	})
+	assertReferenceKinds(t, FindReferences("main.go", content, "Go", "srv"), map[string]ReferenceKind{
+		"5:2": RefImport,
+		"9:7": RefOther,
+	})
+}
+
+func Test_FindReferences_GoWholeIdentifierOnly(t *testing.T) {
+	content := "package main\n\nfunc StartAll() { Restart() }\n"
+
+	if references := FindReferences("main.go", content, "Go", "Start"); len(references) != 0 {
+		t.Errorf("expected no references for partial identifiers, got %+v", references)
+	}
+}
+
+func Test_FindReferences_GoSyntaxErrorFallsBack(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tStart( // unfinished\n"
+
+	assertReferenceKinds(t, FindReferences("main.go", content, "Go", "Start"), map[string]ReferenceKind{
+		"4:2": RefCall,
+	})
+}
+
+func Test_FindReferences_TypeScript(t *testing.T) {
+	content := `import { UserStore } from "./store";
+
+// UserStore caches users
+export class UserStore {
+  load(): UserStore {
+    return new UserStore();
+  }
+}
+
+const store: UserStore = createStore("UserStore");
+const other = UserStore.load();
+const plain = UserStores;
+`
+	assertReferenceKinds(t, FindReferences("store.ts", content, "TypeScript", "UserStore"), map[string]ReferenceKind{
+		"1:10":  RefImport,
+		"3:4":   RefComment,
+		"4:14":  RefDefinition,
+		"5:11":  RefType,
+		"6:16":  RefCall,
+		"10:14": RefType,
+		"10:39": RefString,
+		"11:15": RefOther,
+	})
+}
+
+func Test_FindReferences_Python(t *testing.T) {
+	content := `from app.store import load_user
+
+def load_user(user_id):
+    """Load a user; see load_user docs."""
+    return load_user(user_id)  # recursion: load_user
+`
+	assertReferenceKinds(t, FindReferences("store.py", content, "Python", "load_user"), map[string]ReferenceKind{
+		"1:23": RefImport,
+		"3:5":  RefDefinition,
+		"4:25": RefString,
+		"5:12": RefCall,
+		"5:45": RefComment,
+	})
+}
+
+func Test_FindReferences_Java(t *testing.T) {
+	content := "class Shop {\n    Order latest(Order previous) {\n        return previous;\n    }\n}\n"
+
+	assertReferenceKinds(t, FindReferences("Shop.java", content, "Java", "Order"), map[string]ReferenceKind{
+		"2:5":  RefType,
+		"2:18": RefType,
+	})
+}
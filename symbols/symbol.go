@@ -0,0 +1,70 @@
+package symbols
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSignatureLength caps signatures so one huge declaration cannot flood an outline.
+const maxSignatureLength = 200
+
+// Kind is the category of a symbol definition.
+type Kind string
+
+const (
+	KindFunction  Kind = "function"
+	KindMethod    Kind = "method"
+	KindClass     Kind = "class"
+	KindStruct    Kind = "struct"
+	KindInterface Kind = "interface"
+	KindType      Kind = "type"
+	KindEnum      Kind = "enum"
+	KindConstant  Kind = "constant"
+	KindVariable  Kind = "variable"
+	KindModule    Kind = "module"
+)
+
+// AllKinds lists every symbol kind, in display order.
+var AllKinds = []Kind{
+	KindFunction, KindMethod, KindClass, KindStruct, KindInterface,
+	KindType, KindEnum, KindConstant, KindVariable, KindModule,
+}
+
+// Symbol is a definition found in a source file.
+type Symbol struct {
+	Name         string
+	Kind         Kind
+	Container    string // Enclosing type/class/module name, empty for top-level symbols
+	RelativePath string // Path relative to project root (forward slashes)
+	Language     string
+	StartLine    int    // 1-based, inclusive
+	EndLine      int    // 1-based, inclusive
+	Signature    string // Declaration header on one line (e.g. "func (s *Server) Start() error"), may be empty
+}
+
+// isContainerKind reports whether symbols of this kind can enclose other symbols.
+func isContainerKind(kind Kind) bool {
+	switch kind {
+	case KindClass, KindStruct, KindInterface, KindEnum, KindModule:
+		return true
+	}
+	return false
+}
+
+// oneLine collapses a declaration fragment to a single line of normalized whitespace,
+// truncated to maxSignatureLength bytes.
+func oneLine(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	// Multi-line parameter lists leave a space inside the parentheses
+	text = strings.ReplaceAll(text, "( ", "(")
+	text = strings.ReplaceAll(text, ", )", ")")
+	text = strings.ReplaceAll(text, " )", ")")
+	if len(text) > maxSignatureLength {
+		cut := maxSignatureLength
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	return text
+}
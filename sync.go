@@ -1,31 +1,38 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/ignore"
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/lexandro/codeindex-mcp/workspace"
 )
 
 // SyncResult holds the outcome of a single sync verification run.
 type SyncResult struct {
 	MissingFiles  int // files on disk but not in index
 	StaleFiles    int // files in index but not on disk
-	ModifiedFiles int // files where ModTime differs
+	ModifiedFiles int // files whose content changed
 	Duration      time.Duration
 }
 
 // runPeriodicSync starts a background loop that verifies index consistency at the given interval.
-// It runs until the provided stop channel is closed.
+// It runs until the provided stop channel is closed. ignoreMatchers[i] applies to roots[i].
 func runPeriodicSync(
 	intervalSeconds int,
-	rootDir string,
+	roots []workspace.Root,
+	ignoreMatchers []*ignore.Matcher,
 	fileIndex *index.FileIndex,
 	contentIndex *index.ContentIndex,
-	ignoreMatcher *ignore.Matcher,
+	symbolIndex *symbols.Index,
+	generation *sync.RWMutex,
+	options IndexOptions,
 	logger *slog.Logger,
 	stop <-chan struct{},
 ) {
@@ -41,7 +48,9 @@ func runPeriodicSync(
 			logger.Info("periodic sync stopped")
 			return
 		case <-ticker.C:
-			result := performSyncVerification(rootDir, fileIndex, contentIndex, ignoreMatcher, logger)
+			generation.RLock()
+			result := syncWorkspace(context.Background(), roots, ignoreMatchers, fileIndex, contentIndex, symbolIndex, options, logger)
+			generation.RUnlock()
 			totalDiscrepancies := result.MissingFiles + result.StaleFiles + result.ModifiedFiles
 			if totalDiscrepancies > 0 {
 				logger.Info("sync verification complete",
@@ -57,13 +66,88 @@ func runPeriodicSync(
 	}
 }
 
-// performSyncVerification compares the filesystem with the current index state
-// and re-indexes any out-of-sync files.
+// syncWorkspace runs performSyncVerification for every root and sums the results.
+// ignoreMatchers[i] applies to roots[i]. Roots not yet synced when ctx is cancelled are skipped.
+func syncWorkspace(
+	ctx context.Context,
+	roots []workspace.Root,
+	ignoreMatchers []*ignore.Matcher,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	options IndexOptions,
+	logger *slog.Logger,
+) SyncResult {
+	start := time.Now()
+	var total SyncResult
+	for i, root := range roots {
+		if ctx.Err() != nil {
+			break
+		}
+		result := performSyncVerification(ctx, root, fileIndex, contentIndex, symbolIndex, ignoreMatchers[i], options, logger)
+		total.MissingFiles += result.MissingFiles
+		total.StaleFiles += result.StaleFiles
+		total.ModifiedFiles += result.ModifiedFiles
+	}
+	total.Duration = time.Since(start)
+	return total
+}
+
+// reconcileWorkspace is syncWorkspace for the catch-up after the indexes were restored
+// from a snapshot or rebuilt: every file is hashed, as edits that keep size and
+// modification time may have happened while the indexes were not watching.
+func reconcileWorkspace(
+	ctx context.Context,
+	roots []workspace.Root,
+	ignoreMatchers []*ignore.Matcher,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	options IndexOptions,
+	logger *slog.Logger,
+) SyncResult {
+	options.VerifyContent = true
+	return syncWorkspace(ctx, roots, ignoreMatchers, fileIndex, contentIndex, symbolIndex, options, logger)
+}
+
+// performSyncVerification compares the filesystem of one root with the current index
+// state and re-indexes any out-of-sync files. Files of other roots are left untouched.
 func performSyncVerification(
-	rootDir string,
+	ctx context.Context,
+	root workspace.Root,
 	fileIndex *index.FileIndex,
 	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
 	ignoreMatcher *ignore.Matcher,
+	options IndexOptions,
+	logger *slog.Logger,
+) SyncResult {
+	return syncSubtree(ctx, root, root.Dir, fileIndex, contentIndex, symbolIndex, ignoreMatcher, options, logger)
+}
+
+// subtreePrefix returns the prefix shared by the index paths of the files below dir,
+// a directory of root.
+func subtreePrefix(root workspace.Root, dir string) string {
+	relDir, err := filepath.Rel(root.Dir, dir)
+	if err != nil || relDir == "." {
+		return root.Prefix
+	}
+	return root.IndexPath(filepath.ToSlash(relDir) + "/")
+}
+
+// syncSubtree reconciles the indexed files below dir (a directory of root) with the
+// filesystem: files that are gone or now ignored are removed, new or newly included files
+// are indexed and modified files are re-indexed. If ctx is cancelled, the walk stops and
+// nothing is removed, as files not walked yet would look deleted.
+func syncSubtree(
+	ctx context.Context,
+	root workspace.Root,
+	dir string,
+	fileIndex *index.FileIndex,
+	contentIndex *index.ContentIndex,
+	symbolIndex *symbols.Index,
+	ignoreMatcher *ignore.Matcher,
+	options IndexOptions,
 	logger *slog.Logger,
 ) SyncResult {
 	start := time.Now()
@@ -71,12 +155,15 @@ func performSyncVerification(
 
 	// Step 1: Build a set of all files currently on disk
 	diskFiles := make(map[string]os.FileInfo) // key: relative path (forward slashes)
-	filepath.WalkDir(rootDir, func(path string, d os.DirEntry, err error) error {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
 		if err != nil {
 			return nil
 		}
 		if d.IsDir() {
-			if path != rootDir && ignoreMatcher.ShouldIgnoreDir(path) {
+			if path != root.Dir && ignoreMatcher.ShouldIgnoreDir(path) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -91,60 +178,68 @@ func performSyncVerification(
 		if ignoreMatcher.IsFileTooLarge(info.Size()) {
 			return nil
 		}
-		relPath, _ := filepath.Rel(rootDir, path)
-		relPath = filepath.ToSlash(relPath)
+		relPath, _ := filepath.Rel(root.Dir, path)
+		relPath = root.IndexPath(filepath.ToSlash(relPath))
 		diskFiles[relPath] = info
 		return nil
 	})
-
-	// Step 2: Get all currently indexed files
-	indexedFiles := fileIndex.AllFiles()
-	indexedSet := make(map[string]*index.IndexedFile, len(indexedFiles))
-	for _, f := range indexedFiles {
-		indexedSet[f.RelativePath] = f
+	if ctx.Err() != nil {
+		result.Duration = time.Since(start)
+		return result
 	}
 
-	// Step 3: Find missing files (on disk but not in index)
-	for relPath, info := range diskFiles {
-		if _, exists := indexedSet[relPath]; !exists {
-			absPath := filepath.Join(rootDir, filepath.FromSlash(relPath))
-			err := indexSingleFile(absPath, relPath, info, rootDir, fileIndex, contentIndex, ignoreMatcher)
-			if err != nil {
-				logger.Debug("sync: skipped missing file", "path", relPath, "error", err)
-				continue
-			}
-			logger.Info("sync: indexed missing file", "path", relPath)
-			result.MissingFiles++
+	// Step 2: Get all currently indexed files below dir
+	indexedPaths := fileIndex.PathsWithPrefix(subtreePrefix(root, dir))
+	indexedSet := make(map[string]*index.IndexedFile, len(indexedPaths))
+	for _, relPath := range indexedPaths {
+		if f := fileIndex.GetFile(relPath); f != nil {
+			indexedSet[relPath] = f
 		}
 	}
 
-	// Step 4: Find stale files (in index but not on disk)
+	// Step 3: Find stale files (in index but not on disk)
 	for relPath := range indexedSet {
 		if _, exists := diskFiles[relPath]; !exists {
 			fileIndex.RemoveFile(relPath)
 			contentIndex.RemoveFile(relPath)
+			symbolIndex.RemoveFile(relPath)
 			logger.Info("sync: removed stale file", "path", relPath)
 			result.StaleFiles++
 		}
 	}
 
-	// Step 5: Find modified files (ModTime differs)
+	// Step 4: Index missing files (on disk but not in index) and re-read files whose size or
+	// ModTime changed. Those are compared by content hash, so files rewritten with identical
+	// bytes are not re-indexed. With VerifyContent every file is hashed, which also catches
+	// changes that preserve ModTime and size but reads the whole tree.
+	changedFiles := make(map[string]os.FileInfo, len(diskFiles))
 	for relPath, info := range diskFiles {
-		indexed, exists := indexedSet[relPath]
-		if !exists {
-			continue // already handled as missing
+		indexed, wasIndexed := indexedSet[relPath]
+		if options.VerifyContent || !wasIndexed || indexed.SizeBytes != info.Size() || !indexed.ModTime.Equal(info.ModTime()) {
+			changedFiles[relPath] = info
 		}
-		if !info.ModTime().Equal(indexed.ModTime) {
-			absPath := filepath.Join(rootDir, filepath.FromSlash(relPath))
-			err := indexSingleFile(absPath, relPath, info, rootDir, fileIndex, contentIndex, ignoreMatcher)
-			if err != nil {
-				logger.Debug("sync: skipped modified file", "path", relPath, "error", err)
-				continue
-			}
-			logger.Info("sync: re-indexed modified file", "path", relPath)
+	}
+	options.Progress.AddDiscovered(len(changedFiles))
+	jobs := make(chan indexJob, 100)
+	go func() {
+		defer close(jobs)
+		for relPath, info := range changedFiles {
+			jobs <- indexJob{path: root.AbsPath(relPath), relPath: relPath, info: info}
+		}
+	}()
+	indexFiles(ctx, jobs, fileIndex, contentIndex, symbolIndex, options, func(job indexJob, changed bool, err error) {
+		_, wasIndexed := indexedSet[job.relPath]
+		switch {
+		case err != nil:
+			logger.Debug("sync: skipped file", "path", job.relPath, "error", err)
+		case !wasIndexed:
+			logger.Info("sync: indexed missing file", "path", job.relPath)
+			result.MissingFiles++
+		case changed:
+			logger.Info("sync: re-indexed modified file", "path", job.relPath)
 			result.ModifiedFiles++
 		}
-	}
+	})
 
 	result.Duration = time.Since(start)
 	return result
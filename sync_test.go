@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/ignore"
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/lexandro/codeindex-mcp/watcher"
+	"github.com/lexandro/codeindex-mcp/workspace"
 )
 
 func testLogger() *slog.Logger {
@@ -23,6 +29,11 @@ func testIgnoreMatcher(rootDir string) *ignore.Matcher {
 	})
 }
 
+// testRoot returns the root of a single-root workspace, whose index paths are unprefixed.
+func testRoot(rootDir string) workspace.Root {
+	return workspace.Root{Name: filepath.Base(rootDir), Dir: rootDir}
+}
+
 func Test_performSyncVerification_DetectsMissingFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	logger := testLogger()
@@ -39,7 +50,7 @@ func Test_performSyncVerification_DetectsMissingFiles(t *testing.T) {
 	filePath := filepath.Join(tmpDir, "missing.go")
 	os.WriteFile(filePath, []byte("package main\n"), 0644)
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.MissingFiles != 1 {
 		t.Errorf("expected 1 missing file, got %d", result.MissingFiles)
@@ -80,7 +91,7 @@ func Test_performSyncVerification_DetectsStaleFiles(t *testing.T) {
 	})
 	contentIndex.IndexFile("deleted.go", "package main\n", "Go")
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.StaleFiles != 1 {
 		t.Errorf("expected 1 stale file, got %d", result.StaleFiles)
@@ -122,7 +133,7 @@ func Test_performSyncVerification_DetectsModifiedFiles(t *testing.T) {
 	})
 	contentIndex.IndexFile("modified.go", "package main\n", "Go")
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.ModifiedFiles != 1 {
 		t.Errorf("expected 1 modified file, got %d", result.ModifiedFiles)
@@ -159,10 +170,11 @@ func Test_performSyncVerification_InSyncReturnsZeros(t *testing.T) {
 		SizeBytes:    info.Size(),
 		ModTime:      info.ModTime(),
 		LineCount:    1,
+		ContentHash:  index.ContentHash([]byte("package main\n")),
 	})
 	contentIndex.IndexFile("synced.go", "package main\n", "Go")
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.MissingFiles != 0 {
 		t.Errorf("expected 0 missing files, got %d", result.MissingFiles)
@@ -175,6 +187,35 @@ func Test_performSyncVerification_InSyncReturnsZeros(t *testing.T) {
 	}
 }
 
+func Test_performSyncVerification_CancelledLeavesIndexUntouched(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+	performIndexing(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+	os.WriteFile(filepath.Join(tmpDir, "new.go"), []byte("package main\n"), 0644)
+
+	// A cancelled walk must not mistake the files it did not reach for deleted ones
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := performSyncVerification(ctx, testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+
+	if result.MissingFiles != 0 || result.StaleFiles != 0 || result.ModifiedFiles != 0 {
+		t.Errorf("expected no changes after cancellation, got %+v", result)
+	}
+	if fileIndex.GetFile("main.go") == nil || fileIndex.GetFile("new.go") != nil {
+		t.Error("expected the index to be left as it was")
+	}
+}
+
 func Test_performSyncVerification_SkipsBinaryFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	logger := testLogger()
@@ -192,7 +233,7 @@ func Test_performSyncVerification_SkipsBinaryFiles(t *testing.T) {
 	binaryData := []byte{0x89, 0x50, 0x4E, 0x47, 0x00, 0x0A, 0x1A, 0x0A}
 	os.WriteFile(binaryPath, binaryData, 0644)
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	// Binary file should not count as missing (it's skipped by indexSingleFile)
 	if result.MissingFiles != 0 {
@@ -223,7 +264,7 @@ func Test_performSyncVerification_SkipsIgnoredDirectories(t *testing.T) {
 	// Create a normal file
 	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.MissingFiles != 1 {
 		t.Errorf("expected 1 missing file (main.go only), got %d", result.MissingFiles)
@@ -263,7 +304,7 @@ func Test_performSyncVerification_SkipsTooLargeFiles(t *testing.T) {
 	}
 	os.WriteFile(filepath.Join(tmpDir, "large.go"), largeContent, 0644)
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.MissingFiles != 1 {
 		t.Errorf("expected 1 missing file (small.go only), got %d", result.MissingFiles)
@@ -285,7 +326,7 @@ func Test_performSyncVerification_EmptyDirectory(t *testing.T) {
 	}
 	defer contentIndex.Close()
 
-	result := performSyncVerification(tmpDir, fileIndex, contentIndex, matcher, logger)
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
 
 	if result.MissingFiles != 0 {
 		t.Errorf("expected 0 missing files, got %d", result.MissingFiles)
@@ -315,7 +356,7 @@ func Test_runPeriodicSync_StopsOnChannelClose(t *testing.T) {
 	done := make(chan struct{})
 
 	go func() {
-		runPeriodicSync(1, tmpDir, fileIndex, contentIndex, matcher, logger, stop)
+		runPeriodicSync(1, []workspace.Root{testRoot(tmpDir)}, []*ignore.Matcher{matcher}, fileIndex, contentIndex, symbols.NewIndex(), &sync.RWMutex{}, IndexOptions{}, logger, stop)
 		close(done)
 	}()
 
@@ -330,3 +371,420 @@ func Test_runPeriodicSync_StopsOnChannelClose(t *testing.T) {
 		t.Fatal("runPeriodicSync did not stop within 3 seconds after closing stop channel")
 	}
 }
+
+func Test_performSyncVerification_DetectsSizeChangeWithSameModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	filePath := filepath.Join(tmpDir, "resized.go")
+	os.WriteFile(filePath, []byte("package main\n\nfunc added() {}\n"), 0644)
+
+	info, _ := os.Stat(filePath)
+	fileIndex.AddFile(&index.IndexedFile{
+		Path:         filePath,
+		RelativePath: "resized.go",
+		Language:     "Go",
+		SizeBytes:    13, // size of the previously indexed content
+		ModTime:      info.ModTime(),
+		LineCount:    1,
+	})
+	contentIndex.IndexFile("resized.go", "package main\n", "Go")
+
+	result := performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+
+	if result.ModifiedFiles != 1 {
+		t.Errorf("expected 1 modified file, got %d", result.ModifiedFiles)
+	}
+}
+
+func Test_performSyncVerification_DetectsContentChangeWithSameModTimeAndSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	filePath := filepath.Join(tmpDir, "config.go")
+	os.WriteFile(filePath, []byte("package main\n\nconst mode = \"dev\"\n"), 0644)
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+
+	// Same length, and the modification time is restored (e.g. by rsync -t or tar)
+	info, _ := os.Stat(filePath)
+	os.WriteFile(filePath, []byte("package main\n\nconst mode = \"prd\"\n"), 0644)
+	os.Chtimes(filePath, info.ModTime(), info.ModTime())
+
+	// Unchanged size and ModTime are trusted unless the content is verified
+	result := performSyncVerification(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+	if result.ModifiedFiles != 0 {
+		t.Errorf("expected no modified files without VerifyContent, got %d", result.ModifiedFiles)
+	}
+
+	result = performSyncVerification(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{VerifyContent: true}, logger)
+	if result.ModifiedFiles != 1 {
+		t.Errorf("expected 1 modified file, got %d", result.ModifiedFiles)
+	}
+	if content, _ := contentIndex.GetFileContent("config.go"); !strings.Contains(content, "prd") {
+		t.Errorf("expected the new content to be indexed, got %q", content)
+	}
+}
+
+func Test_reconcileWorkspace_DetectsContentChangeWithSameModTimeAndSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	filePath := filepath.Join(tmpDir, "config.go")
+	os.WriteFile(filePath, []byte("package main\n\nconst mode = \"dev\"\n"), 0644)
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+
+	info, _ := os.Stat(filePath)
+	os.WriteFile(filePath, []byte("package main\n\nconst mode = \"prd\"\n"), 0644)
+	os.Chtimes(filePath, info.ModTime(), info.ModTime())
+
+	// Reconciling a snapshot or a rebuild verifies the content without VerifyContent
+	result := reconcileWorkspace(context.Background(), []workspace.Root{root}, []*ignore.Matcher{matcher}, fileIndex, contentIndex, symbols.NewIndex(), IndexOptions{}, logger)
+	if result.ModifiedFiles != 1 {
+		t.Errorf("expected 1 modified file, got %d", result.ModifiedFiles)
+	}
+	if content, _ := contentIndex.GetFileContent("config.go"); !strings.Contains(content, "prd") {
+		t.Errorf("expected the new content to be indexed, got %q", content)
+	}
+}
+
+func Test_performSyncVerification_IdenticalRewriteNotReindexed(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+
+	filePath := filepath.Join(tmpDir, "main.go")
+	os.WriteFile(filePath, []byte("package main\n"), 0644)
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+	hash := fileIndex.GetFile("main.go").ContentHash
+	if hash != index.ContentHash([]byte("package main\n")) {
+		t.Fatalf("expected the content hash to be recorded, got %q", hash)
+	}
+
+	// A checkout or formatter rewrites the file with identical bytes
+	newModTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	os.Chtimes(filePath, newModTime, newModTime)
+
+	result := performSyncVerification(context.Background(), root, fileIndex, contentIndex, symbols.NewIndex(), matcher, IndexOptions{}, logger)
+
+	if result.ModifiedFiles != 0 {
+		t.Errorf("expected 0 modified files, got %d", result.ModifiedFiles)
+	}
+	if indexed := fileIndex.GetFile("main.go"); !indexed.ModTime.Equal(newModTime) || indexed.ContentHash != hash {
+		t.Errorf("expected refreshed ModTime and unchanged hash, got %+v", indexed)
+	}
+}
+
+func Test_performSyncVerification_UpdatesSymbols(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+
+	// Stale file with symbols must lose them
+	fileIndex.AddFile(&index.IndexedFile{RelativePath: "gone.go", Language: "Go", ModTime: time.Now()})
+	contentIndex.IndexFile("gone.go", "package main\n\nfunc Gone() {}\n", "Go")
+	symbolIndex.SetFile("gone.go", symbols.Extract("gone.go", "package main\n\nfunc Gone() {}\n", "Go"))
+
+	// Missing file must gain its symbols
+	os.WriteFile(filepath.Join(tmpDir, "added.go"), []byte("package main\n\nfunc Added() {}\n"), 0644)
+
+	performSyncVerification(context.Background(), testRoot(tmpDir), fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	if len(symbolIndex.FileSymbols("gone.go")) != 0 {
+		t.Error("expected symbols of stale file to be removed")
+	}
+	results, _ := symbolIndex.Lookup(symbols.LookupOptions{Query: "Added"})
+	if len(results) != 1 {
+		t.Errorf("expected Added to be indexed, got %+v", results)
+	}
+}
+
+func Test_performSyncVerification_MultiRootKeepsOtherRoots(t *testing.T) {
+	apiDir := t.TempDir()
+	webDir := t.TempDir()
+	logger := testLogger()
+
+	os.WriteFile(filepath.Join(apiDir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(webDir, "app.ts"), []byte("export {}\n"), 0644)
+
+	ws, err := workspace.New([]workspace.Root{{Name: "api", Dir: apiDir}, {Name: "web", Dir: webDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchers := []*ignore.Matcher{testIgnoreMatcher(apiDir), testIgnoreMatcher(webDir)}
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+
+	for i, root := range ws.Roots {
+		performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, matchers[i], IndexOptions{}, logger)
+	}
+	if fileIndex.GetFile("api/main.go") == nil || fileIndex.GetFile("web/app.ts") == nil {
+		t.Fatalf("expected prefixed paths, got %d files", fileIndex.FileCount())
+	}
+
+	// Syncing one root must not treat the files of the other root as stale
+	result := performSyncVerification(context.Background(), ws.Roots[0], fileIndex, contentIndex, symbolIndex, matchers[0], IndexOptions{}, logger)
+	if result.StaleFiles != 0 {
+		t.Errorf("expected 0 stale files, got %d", result.StaleFiles)
+	}
+
+	os.Remove(filepath.Join(webDir, "app.ts"))
+	os.WriteFile(filepath.Join(apiDir, "util.go"), []byte("package main\n"), 0644)
+	result = syncWorkspace(context.Background(), ws.Roots, matchers, fileIndex, contentIndex, symbolIndex, IndexOptions{}, logger)
+	if result.MissingFiles != 1 || result.StaleFiles != 1 {
+		t.Errorf("expected 1 missing and 1 stale file, got %+v", result)
+	}
+	if fileIndex.GetFile("api/util.go") == nil || fileIndex.GetFile("web/app.ts") != nil {
+		t.Error("expected api/util.go indexed and web/app.ts removed")
+	}
+}
+
+func Test_applyIgnoreChange_PrunesAndRestoresFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	os.MkdirAll(filepath.Join(tmpDir, "pkg", "gen"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "pkg", "gen", "types.go"), []byte("package gen\n\ntype Generated struct{}\n"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	fileWatcher, err := watcher.NewWatcher(tmpDir, matcher, watcher.Options{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileWatcher.Close()
+
+	// A nested .gitignore now excludes the generated code
+	pkgDir := filepath.Join(tmpDir, "pkg")
+	os.WriteFile(filepath.Join(pkgDir, ".gitignore"), []byte("gen/\n"), 0644)
+	applyIgnoreChange(pkgDir, fileWatcher, root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	if fileIndex.GetFile("pkg/gen/types.go") != nil {
+		t.Error("expected newly ignored file to be removed from the file index")
+	}
+	if _, ok := contentIndex.GetFileContent("pkg/gen/types.go"); ok {
+		t.Error("expected newly ignored file to be removed from the content index")
+	}
+	if len(symbolIndex.FileSymbols("pkg/gen/types.go")) != 0 {
+		t.Error("expected symbols of the ignored file to be removed")
+	}
+	if fileIndex.GetFile("main.go") == nil {
+		t.Error("expected files outside the changed directory to stay indexed")
+	}
+
+	// Removing the rule indexes the files again
+	os.Remove(filepath.Join(pkgDir, ".gitignore"))
+	applyIgnoreChange(pkgDir, fileWatcher, root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	if fileIndex.GetFile("pkg/gen/types.go") == nil {
+		t.Error("expected newly included file to be indexed")
+	}
+}
+
+func Test_removeFromIndexes_RemovesSubtree(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	root := testRoot(tmpDir)
+
+	os.MkdirAll(filepath.Join(tmpDir, "api", "v2"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "api", "handler.go"), []byte("package api\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "api", "v2", "routes.go"), []byte("package v2\n\nfunc Routes() {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "apiclient.go"), []byte("package main\n"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, testIgnoreMatcher(tmpDir), IndexOptions{}, logger)
+
+	os.RemoveAll(filepath.Join(tmpDir, "api"))
+	if removed := removeFromIndexes(root, filepath.Join(tmpDir, "api"), fileIndex, contentIndex, symbolIndex); removed != 2 {
+		t.Errorf("expected 2 files removed, got %d", removed)
+	}
+
+	if fileIndex.GetFile("api/v2/routes.go") != nil {
+		t.Error("expected nested file to be removed from the file index")
+	}
+	if _, ok := contentIndex.GetFileContent("api/handler.go"); ok {
+		t.Error("expected file to be removed from the content index")
+	}
+	if len(symbolIndex.FileSymbols("api/v2/routes.go")) != 0 {
+		t.Error("expected symbols of the removed directory to be removed")
+	}
+	if fileIndex.GetFile("apiclient.go") == nil {
+		t.Error("expected sibling file sharing the name prefix to stay indexed")
+	}
+}
+
+// waitForIndex polls until condition holds, failing the test after a timeout.
+func waitForIndex(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", description)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func Test_handleWatcherEvents_DirectoryRenameAndRemove(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	os.MkdirAll(filepath.Join(tmpDir, "old", "nested"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "old", "a.go"), []byte("package old\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "old", "nested", "b.go"), []byte("package nested\n"), 0644)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+	performIndexing(context.Background(), root, fileIndex, contentIndex, symbolIndex, matcher, IndexOptions{}, logger)
+
+	fileWatcher, err := watcher.NewWatcher(tmpDir, matcher, watcher.Options{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileWatcher.Close()
+	go fileWatcher.Start()
+	var generation sync.RWMutex
+	go handleWatcherEvents(fileWatcher, root, fileIndex, contentIndex, symbolIndex, matcher, &generation, IndexOptions{}, logger)
+
+	// Renaming the directory moves its whole subtree in the index
+	if err := os.Rename(filepath.Join(tmpDir, "old"), filepath.Join(tmpDir, "new")); err != nil {
+		t.Fatal(err)
+	}
+	waitForIndex(t, "the renamed subtree is reindexed", func() bool {
+		return fileIndex.GetFile("new/nested/b.go") != nil && fileIndex.GetFile("old/nested/b.go") == nil
+	})
+	if fileIndex.GetFile("old/a.go") != nil || fileIndex.GetFile("new/a.go") == nil {
+		t.Error("expected old/a.go to be replaced by new/a.go")
+	}
+	if _, ok := contentIndex.GetFileContent("old/nested/b.go"); ok {
+		t.Error("expected the old path to be removed from the content index")
+	}
+
+	// A directory moved in from outside the root is indexed
+	outsideDir := filepath.Join(t.TempDir(), "incoming")
+	os.MkdirAll(outsideDir, 0755)
+	os.WriteFile(filepath.Join(outsideDir, "c.go"), []byte("package incoming\n"), 0644)
+	if err := os.Rename(outsideDir, filepath.Join(tmpDir, "incoming")); err != nil {
+		t.Fatal(err)
+	}
+	waitForIndex(t, "the moved-in directory is indexed", func() bool {
+		return fileIndex.GetFile("incoming/c.go") != nil
+	})
+
+	// Deleting the directory prunes all its descendants
+	os.RemoveAll(filepath.Join(tmpDir, "new"))
+	waitForIndex(t, "the deleted subtree is removed", func() bool {
+		return fileIndex.GetFile("new/a.go") == nil && fileIndex.GetFile("new/nested/b.go") == nil
+	})
+	if fileIndex.GetFile("incoming/c.go") == nil {
+		t.Error("expected files outside the deleted directory to stay indexed")
+	}
+}
+
+func Test_handleWatcherEvents_WaitsForGenerationSwap(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := testLogger()
+	matcher := testIgnoreMatcher(tmpDir)
+	root := testRoot(tmpDir)
+
+	fileIndex := index.NewFileIndex()
+	contentIndex, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer contentIndex.Close()
+	symbolIndex := symbols.NewIndex()
+
+	fileWatcher, err := watcher.NewWatcher(tmpDir, matcher, watcher.Options{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileWatcher.Close()
+	go fileWatcher.Start()
+	var generation sync.RWMutex
+	go handleWatcherEvents(fileWatcher, root, fileIndex, contentIndex, symbolIndex, matcher, &generation, IndexOptions{}, logger)
+
+	// A rebuild is swapping the indexes: the event must not be applied until it is done
+	generation.Lock()
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+	time.Sleep(500 * time.Millisecond)
+	indexedDuringSwap := fileIndex.GetFile("main.go") != nil
+	generation.Unlock()
+	if indexedDuringSwap {
+		t.Error("expected the watcher to wait for the swap before updating the indexes")
+	}
+	waitForIndex(t, "the new file is indexed after the swap", func() bool {
+		_, ok := contentIndex.GetFileContent("main.go")
+		return fileIndex.GetFile("main.go") != nil && ok
+	})
+}
@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lexandro/codeindex-mcp/ignore"
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/language"
+	"github.com/lexandro/codeindex-mcp/workspace"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ExplainIgnoreArgs defines the input parameters for the codeindex_explain_ignore tool.
+type ExplainIgnoreArgs struct {
+	Path string `json:"path" jsonschema:"File or directory to explain: an absolute path or a path relative to the project root (e.g. build/gen.go)"`
+	Root string `json:"root,omitempty" jsonschema:"Workspace root the path is relative to (multi-root workspaces). Without it a relative path includes the root name prefix"`
+}
+
+// ExplainIgnoreOutput is the structured result of the codeindex_explain_ignore tool.
+type ExplainIgnoreOutput struct {
+	Path                  string   `json:"path" jsonschema:"Index path (relative, forward slashes)"`
+	Absolute              string   `json:"absolute" jsonschema:"Absolute path"`
+	Exists                string   `json:"exists" jsonschema:"file, directory or no"`
+	SizeBytes             int64    `json:"sizeBytes,omitempty" jsonschema:"File size in bytes"`
+	Ignored               bool     `json:"ignored" jsonschema:"Whether the deciding ignore rule excludes the path"`
+	Rule                  string   `json:"rule" jsonschema:"The deciding ignore rule in one line"`
+	RuleSource            string   `json:"ruleSource,omitempty" jsonschema:"always, default, exclude, gitignore, info-exclude, global-exclude or claudeignore"`
+	Pattern               string   `json:"pattern,omitempty" jsonschema:"The deciding pattern; a ! prefix re-includes the path"`
+	RuleFile              string   `json:"ruleFile,omitempty" jsonschema:"Ignore file containing the pattern"`
+	RuleLine              int      `json:"ruleLine,omitempty" jsonschema:"Line of the pattern in ruleFile (1-based)"`
+	ParentDir             string   `json:"parentDir,omitempty" jsonschema:"Parent directory the rule matches, relative to the root"`
+	ForceInclude          string   `json:"forceInclude,omitempty" jsonschema:"--force-include pattern overriding the rule"`
+	SuggestedForceInclude string   `json:"suggestedForceInclude,omitempty" jsonschema:"--force-include pattern that would override the rule"`
+	TooLarge              bool     `json:"tooLarge,omitempty" jsonschema:"Whether the file exceeds --max-file-size"`
+	MaxFileSizeBytes      int64    `json:"maxFileSizeBytes" jsonschema:"The --max-file-size limit"`
+	Binary                bool     `json:"binary,omitempty" jsonschema:"Whether the file has binary content"`
+	Indexed               *bool    `json:"indexed,omitempty" jsonschema:"Whether the file is in the index; absent for directories or without an index"`
+	Verdict               string   `json:"verdict" jsonschema:"included, not indexed, directory is traversed or would be indexed if it existed"`
+	Reasons               []string `json:"reasons" jsonschema:"Why the path is not indexed: ignore rule, size limit or binary content"`
+}
+
+// ExplainIgnoreHandler holds the dependencies for the explain ignore tool.
+type ExplainIgnoreHandler struct {
+	FileIndex      *index.FileIndex
+	Workspace      *workspace.Workspace
+	IgnoreMatchers []*ignore.Matcher // IgnoreMatchers[i] applies to Workspace.Roots[i]
+	Logger         *slog.Logger
+}
+
+// Handle processes a codeindex_explain_ignore request.
+func (h *ExplainIgnoreHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ExplainIgnoreArgs) (*mcp.CallToolResult, *ExplainIgnoreOutput, error) {
+	if args.Path == "" {
+		h.Logger.Warn("codeindex_explain_ignore called with empty path")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: path parameter is required"}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	explanation, err := explainIgnore(h.Workspace, h.IgnoreMatchers, h.FileIndex, args.Path, args.Root)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	h.Logger.Info("codeindex_explain_ignore", "path", args.Path, "root", args.Root)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: formatExplainIgnore(explanation)}},
+	}, explanation, nil
+}
+
+// ExplainIgnore reports why a path is or is not indexed: the deciding ignore rule (or the
+// parent directory pruned by one), the size limit, binary detection, and whether a
+// --force-include overrides the rule. fileIndex may be nil when no index is running.
+func ExplainIgnore(ws *workspace.Workspace, matchers []*ignore.Matcher, fileIndex *index.FileIndex, path string, rootName string) (string, error) {
+	explanation, err := explainIgnore(ws, matchers, fileIndex, path, rootName)
+	if err != nil {
+		return "", err
+	}
+	return formatExplainIgnore(explanation), nil
+}
+
+// explainIgnore collects what ExplainIgnore reports.
+func explainIgnore(ws *workspace.Workspace, matchers []*ignore.Matcher, fileIndex *index.FileIndex, path string, rootName string) (*ExplainIgnoreOutput, error) {
+	rootIdx, absolutePath, err := resolveExplainPath(ws, path, rootName)
+	if err != nil {
+		return nil, err
+	}
+	root := ws.Roots[rootIdx]
+	matcher := matchers[rootIdx]
+
+	relativePath, _ := filepath.Rel(root.Dir, absolutePath)
+	relativePath = filepath.ToSlash(relativePath)
+
+	output := &ExplainIgnoreOutput{
+		Path:             root.IndexPath(relativePath),
+		Absolute:         absolutePath,
+		MaxFileSizeBytes: matcher.MaxFileSizeBytes(),
+		Reasons:          []string{},
+	}
+
+	info, statErr := os.Stat(absolutePath)
+	switch {
+	case statErr != nil:
+		output.Exists = "no"
+	case info.IsDir():
+		output.Exists = "directory"
+	default:
+		output.Exists = "file"
+		output.SizeBytes = info.Size()
+	}
+
+	explanation := matcher.Explain(absolutePath)
+	output.Ignored = explanation.Ignored
+	output.Rule = describeExplanation(explanation, root.Dir)
+	output.RuleSource = string(explanation.Source)
+	output.Pattern = explanation.Pattern
+	output.RuleFile = explanation.File
+	output.RuleLine = explanation.Line
+	if explanation.ParentDir != "" {
+		parentDir, err := filepath.Rel(root.Dir, explanation.ParentDir)
+		if err != nil {
+			parentDir = explanation.ParentDir
+		}
+		output.ParentDir = filepath.ToSlash(parentDir)
+	}
+	if explanation.Ignored {
+		output.Reasons = append(output.Reasons, "ignore rule")
+	}
+
+	output.ForceInclude = explanation.ForceInclude
+	if explanation.ForceInclude == "" && explanation.Ignored && explanation.Source != ignore.SourceAlways {
+		output.SuggestedForceInclude = relativePath
+	}
+
+	if statErr == nil && !info.IsDir() {
+		if matcher.IsFileTooLarge(info.Size()) {
+			output.TooLarge = true
+			output.Reasons = append(output.Reasons, "size limit")
+		} else if isBinaryFile(absolutePath) {
+			output.Binary = true
+			output.Reasons = append(output.Reasons, "binary content")
+		}
+	}
+
+	if fileIndex != nil && (statErr != nil || !info.IsDir()) {
+		indexed := fileIndex.GetFile(output.Path) != nil
+		output.Indexed = &indexed
+	}
+
+	switch {
+	case len(output.Reasons) > 0:
+		output.Verdict = "not indexed"
+	case statErr != nil:
+		output.Verdict = "would be indexed if it existed"
+	case info.IsDir():
+		output.Verdict = "directory is traversed"
+	default:
+		output.Verdict = "included"
+	}
+	return output, nil
+}
+
+// formatExplainIgnore renders the report of explainIgnore, one "key: value" line per fact.
+func formatExplainIgnore(output *ExplainIgnoreOutput) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("path: %s\n", output.Path))
+	builder.WriteString(fmt.Sprintf("absolute: %s\n", output.Absolute))
+	if output.Exists == "file" {
+		builder.WriteString(fmt.Sprintf("exists: file (%s)\n", formatFileSize(output.SizeBytes)))
+	} else {
+		builder.WriteString(fmt.Sprintf("exists: %s\n", output.Exists))
+	}
+	builder.WriteString("rule: " + output.Rule + "\n")
+
+	if output.ForceInclude != "" {
+		builder.WriteString(fmt.Sprintf("force-include: --force-include %q overrides the rule\n", output.ForceInclude))
+	} else if output.SuggestedForceInclude != "" {
+		builder.WriteString(fmt.Sprintf("force-include: none matches; --force-include %q would override the rule\n", output.SuggestedForceInclude))
+	}
+
+	if output.TooLarge {
+		builder.WriteString(fmt.Sprintf("size: %s exceeds --max-file-size %s (force-include does not override this)\n",
+			formatFileSize(output.SizeBytes), formatFileSize(output.MaxFileSizeBytes)))
+	} else if output.Binary {
+		builder.WriteString("binary: yes, binary files are never indexed\n")
+	}
+
+	if output.Indexed != nil {
+		if *output.Indexed {
+			builder.WriteString("indexed: yes\n")
+		} else {
+			builder.WriteString("indexed: no\n")
+		}
+	}
+
+	if len(output.Reasons) > 0 {
+		builder.WriteString("verdict: " + output.Verdict + " (" + strings.Join(output.Reasons, ", ") + ")\n")
+	} else {
+		builder.WriteString("verdict: " + output.Verdict + "\n")
+	}
+	return builder.String()
+}
+
+// resolveExplainPath finds the root of a path and its absolute location. Absolute paths
+// select the root containing them; relative paths are root-relative when rootName is given,
+// otherwise they carry the root name prefix in multi-root workspaces. Paths outside the
+// roots (including relative ones escaping with ..) are rejected, so the tool cannot be used
+// to probe other files.
+func resolveExplainPath(ws *workspace.Workspace, path string, rootName string) (int, string, error) {
+	if filepath.IsAbs(path) {
+		path = filepath.Clean(path)
+		for i, root := range ws.Roots {
+			if rootName != "" && root.Name != rootName {
+				continue
+			}
+			if isWithinDir(root.Dir, path) {
+				return i, path, nil
+			}
+		}
+		return 0, "", fmt.Errorf("%s is outside the workspace roots", path)
+	}
+
+	root, err := resolveRoot(ws, rootName)
+	if err != nil {
+		return 0, "", err
+	}
+	indexPath := root.IndexPath(filepath.ToSlash(filepath.Clean(path)))
+	if rootName == "" && !ws.IsMulti() {
+		root = ws.Roots[0]
+	} else if rootName == "" {
+		var ok bool
+		if root, ok = ws.RootOf(indexPath); !ok {
+			return 0, "", fmt.Errorf("path %q does not start with a root name (available: %s); pass root or an absolute path", path, strings.Join(ws.Names(), ", "))
+		}
+	}
+	for i := range ws.Roots {
+		if ws.Roots[i].Name == root.Name {
+			absolutePath := root.AbsPath(indexPath)
+			if !isWithinDir(root.Dir, absolutePath) {
+				return 0, "", fmt.Errorf("%s is outside the workspace roots", path)
+			}
+			return i, absolutePath, nil
+		}
+	}
+	return 0, "", fmt.Errorf("unknown root %q", root.Name)
+}
+
+// isWithinDir reports whether the clean absolute path is dir or below it.
+func isWithinDir(dir string, path string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// describeExplanation renders the deciding ignore rule in one line.
+func describeExplanation(explanation ignore.Explanation, rootDir string) string {
+	var rule string
+	switch explanation.Source {
+	case ignore.SourceNone:
+		return "no ignore rule matches"
+	case ignore.SourceAlways:
+		rule = `built-in rule ".git" (cannot be overridden)`
+	case ignore.SourceDefault:
+		rule = fmt.Sprintf("default pattern %q", explanation.Pattern)
+	case ignore.SourceExclude:
+		rule = fmt.Sprintf("--exclude pattern %q", explanation.Pattern)
+	default:
+		rule = fmt.Sprintf("%s pattern %q", ignoreFileLabel(explanation.Source), explanation.Pattern)
+		if explanation.File != "" {
+			location := explanation.File
+			if explanation.Line > 0 {
+				location = fmt.Sprintf("%s:%d", location, explanation.Line)
+			}
+			rule += " at " + location
+		}
+	}
+
+	if explanation.ParentDir != "" {
+		parentDir, err := filepath.Rel(rootDir, explanation.ParentDir)
+		if err != nil {
+			parentDir = explanation.ParentDir
+		}
+		if explanation.ForceInclude != "" {
+			return fmt.Sprintf("parent directory %s/ would be skipped by %s", filepath.ToSlash(parentDir), rule)
+		}
+		return fmt.Sprintf("parent directory %s/ is skipped by %s", filepath.ToSlash(parentDir), rule)
+	}
+	switch {
+	case explanation.ForceInclude != "":
+		return "would be ignored by " + rule
+	case !explanation.Ignored:
+		return "re-included by " + rule
+	default:
+		return "ignored by " + rule
+	}
+}
+
+// ignoreFileLabel names the ignore file kind of a rule source.
+func ignoreFileLabel(source ignore.RuleSource) string {
+	switch source {
+	case ignore.SourceGitignore:
+		return ".gitignore"
+	case ignore.SourceInfoExclude:
+		return ".git/info/exclude"
+	case ignore.SourceGlobalExclude:
+		return "core.excludesFile"
+	case ignore.SourceClaudeignore:
+		return ".claudeignore"
+	}
+	return string(source)
+}
+
+// isBinaryFile reports whether the start of a file looks like binary content.
+func isBinaryFile(absolutePath string) bool {
+	file, err := os.Open(absolutePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return language.IsBinaryContent(head[:n])
+}
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/ignore"
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/workspace"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newTestExplainIgnoreHandler returns a handler for a workspace with the roots "api" and
+// "web" in a temporary directory. The api root ignores gen/ and *.tmp via .gitignore.
+func newTestExplainIgnoreHandler(t *testing.T) (*ExplainIgnoreHandler, string) {
+	t.Helper()
+	baseDir := t.TempDir()
+	for _, dir := range []string{"api/gen", "web"} {
+		os.MkdirAll(filepath.Join(baseDir, filepath.FromSlash(dir)), 0755)
+	}
+	os.WriteFile(filepath.Join(baseDir, "api", ".gitignore"), []byte("gen/\n*.tmp\n"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "api", "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "api", "scratch.tmp"), []byte("notes\n"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "api", "gen", "types.go"), []byte("package gen\n"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "web", "logo.dat"), []byte("PNG\x00\x01"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "web", "bundle.js"), []byte(strings.Repeat("x", 2048)), 0644)
+
+	ws, err := workspace.New([]workspace.Root{
+		{Name: "api", Dir: filepath.Join(baseDir, "api")},
+		{Name: "web", Dir: filepath.Join(baseDir, "web")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchers := make([]*ignore.Matcher, len(ws.Roots))
+	for i, root := range ws.Roots {
+		matchers[i] = ignore.NewMatcher(ignore.MatcherOptions{RootDir: root.Dir, MaxFileSizeBytes: 1024})
+	}
+
+	fileIndex := index.NewFileIndex()
+	fileIndex.AddFile(&index.IndexedFile{Path: filepath.Join(baseDir, "api", "main.go"), RelativePath: "api/main.go"})
+
+	return &ExplainIgnoreHandler{
+		FileIndex:      fileIndex,
+		Workspace:      ws,
+		IgnoreMatchers: matchers,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, baseDir
+}
+
+func Test_ExplainIgnoreHandler_EmptyPath(t *testing.T) {
+	h, _ := newTestExplainIgnoreHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, ExplainIgnoreArgs{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty path")
+	}
+}
+
+func Test_ExplainIgnoreHandler_Reasons(t *testing.T) {
+	h, baseDir := newTestExplainIgnoreHandler(t)
+
+	tests := []struct {
+		args ExplainIgnoreArgs
+		want []string
+	}{
+		{ExplainIgnoreArgs{Path: "api/main.go"}, []string{"no ignore rule matches", "indexed: yes", "verdict: included"}},
+		{ExplainIgnoreArgs{Path: "scratch.tmp", Root: "api"}, []string{
+			`ignored by .gitignore pattern "*.tmp" at ` + filepath.Join(baseDir, "api", ".gitignore") + ":2",
+			`--force-include "scratch.tmp" would override`,
+			"verdict: not indexed (ignore rule)",
+		}},
+		{ExplainIgnoreArgs{Path: filepath.Join(baseDir, "api", "gen", "types.go")}, []string{
+			`parent directory gen/ is skipped by .gitignore pattern "gen/"`,
+			"path: api/gen/types.go",
+		}},
+		{ExplainIgnoreArgs{Path: "web/logo.dat"}, []string{"binary: yes", "verdict: not indexed (binary content)"}},
+		{ExplainIgnoreArgs{Path: "web/bundle.js"}, []string{"exceeds --max-file-size", "verdict: not indexed (size limit)"}},
+		{ExplainIgnoreArgs{Path: "api/missing.go"}, []string{"exists: no", "indexed: no"}},
+	}
+	for _, tt := range tests {
+		result, _, err := h.Handle(context.Background(), nil, tt.args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(*mcp.TextContent).Text
+		if result.IsError {
+			t.Errorf("%+v: unexpected error result: %s", tt.args, text)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("%+v: expected %q in output, got:\n%s", tt.args, want, text)
+			}
+		}
+	}
+}
+
+func Test_ExplainIgnoreHandler_UnresolvedPath(t *testing.T) {
+	h, _ := newTestExplainIgnoreHandler(t)
+
+	for _, args := range []ExplainIgnoreArgs{
+		{Path: "main.go"},                          // no root name prefix
+		{Path: "main.go", Root: "docs"},            // unknown root
+		{Path: filepath.Join(t.TempDir(), "x.go")}, // outside the roots
+		{Path: "../secret.txt", Root: "api"},       // escapes the root
+		{Path: "api/../../secret.txt"},             // escapes through the root prefix
+		{Path: "../web/logo.dat", Root: "api"},     // escapes into another root
+	} {
+		result, _, err := h.Handle(context.Background(), nil, args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("%+v: expected IsError=true", args)
+		}
+	}
+}
+
+func Test_ExplainIgnoreHandler_StructuredOutput(t *testing.T) {
+	h, baseDir := newTestExplainIgnoreHandler(t)
+
+	_, output, err := h.Handle(context.Background(), nil, ExplainIgnoreArgs{Path: "scratch.tmp", Root: "api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ignoreFile := filepath.Join(baseDir, "api", ".gitignore")
+	assertJSON(t, output, fmt.Sprintf(`{
+		"path": "api/scratch.tmp",
+		"absolute": %q,
+		"exists": "file",
+		"sizeBytes": 6,
+		"ignored": true,
+		"rule": %q,
+		"ruleSource": "gitignore",
+		"pattern": "*.tmp",
+		"ruleFile": %q,
+		"ruleLine": 2,
+		"suggestedForceInclude": "scratch.tmp",
+		"maxFileSizeBytes": 1024,
+		"indexed": false,
+		"verdict": "not indexed",
+		"reasons": ["ignore rule"]
+	}`, filepath.Join(baseDir, "api", "scratch.tmp"), `ignored by .gitignore pattern "*.tmp" at `+ignoreFile+":2", ignoreFile))
+}
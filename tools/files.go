@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -15,16 +16,33 @@ type FilesArgs struct {
 	Pattern    string `json:"pattern" jsonschema:"Glob pattern to match files (e.g. **/*.ts or src/**/*.go)"`
 	NameOnly   bool   `json:"nameOnly,omitempty" jsonschema:"If true return only file paths without metadata"`
 	MaxResults int    `json:"maxResults,omitempty" jsonschema:"Maximum number of results to return (default 50)"`
+	Root       string `json:"root,omitempty" jsonschema:"Only match files of this workspace root; the pattern is then relative to the root"`
+}
+
+// FilesOutput is the structured result of the codeindex_files tool.
+type FilesOutput struct {
+	Files []FileInfo `json:"files" jsonschema:"Matching files sorted by path"`
+}
+
+// FileInfo is the metadata of an indexed file.
+type FileInfo struct {
+	Path      string `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	Language  string `json:"language" jsonschema:"Detected programming language"`
+	SizeBytes int64  `json:"sizeBytes" jsonschema:"File size in bytes"`
+	LineCount int    `json:"lineCount" jsonschema:"Number of lines in the file"`
+	ModTime   string `json:"modTime" jsonschema:"Last modification time (RFC 3339)"`
+	SHA256    string `json:"sha256,omitempty" jsonschema:"Hex-encoded SHA-256 of the content"`
 }
 
 // FilesHandler holds the dependencies for the files tool.
 type FilesHandler struct {
 	FileIndex *index.FileIndex
+	Workspace *workspace.Workspace
 	Logger    *slog.Logger
 }
 
 // Handle processes a codeindex_files request.
-func (h *FilesHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args FilesArgs) (*mcp.CallToolResult, any, error) {
+func (h *FilesHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args FilesArgs) (*mcp.CallToolResult, *FilesOutput, error) {
 	start := time.Now()
 
 	if args.Pattern == "" {
@@ -35,7 +53,15 @@ func (h *FilesHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, arg
 		}, nil, nil
 	}
 
-	results, err := h.FileIndex.SearchByGlob(args.Pattern, args.MaxResults)
+	root, err := resolveRoot(h.Workspace, args.Root)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	results, err := h.FileIndex.SearchByGlob(root.IndexPath(args.Pattern), args.MaxResults)
 	if err != nil {
 		h.Logger.Error("codeindex_files failed", "pattern", args.Pattern, "error", err)
 		return &mcp.CallToolResult{
@@ -47,13 +73,31 @@ func (h *FilesHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, arg
 	elapsed := time.Since(start)
 	h.Logger.Info("codeindex_files",
 		"pattern", args.Pattern,
+		"root", args.Root,
 		"results", len(results),
 		"elapsed", elapsed,
 	)
 
 	output := FormatFileResults(results, args.NameOnly)
 
+	structured := &FilesOutput{Files: make([]FileInfo, len(results))}
+	for i, result := range results {
+		structured.Files[i] = newFileInfo(result.File)
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: output}},
-	}, nil, nil
+	}, structured, nil
+}
+
+// newFileInfo converts an indexed file to its structured metadata.
+func newFileInfo(file *index.IndexedFile) FileInfo {
+	return FileInfo{
+		Path:      file.RelativePath,
+		Language:  file.Language,
+		SizeBytes: file.SizeBytes,
+		LineCount: file.LineCount,
+		ModTime:   file.ModTime.UTC().Format(time.RFC3339),
+		SHA256:    file.ContentHash,
+	}
 }
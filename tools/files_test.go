@@ -99,3 +99,58 @@ func Test_FilesHandler_NoResults(t *testing.T) {
 		t.Errorf("expected 'No files matched', got:\n%s", text)
 	}
 }
+
+func Test_FilesHandler_RootFilter(t *testing.T) {
+	h := newTestFilesHandler(t)
+	h.Workspace = newTestWorkspace(t)
+
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "api/main.go", Language: "Go"})
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "web/main.go", Language: "Go"})
+
+	result, _, err := h.Handle(context.Background(), nil, FilesArgs{Pattern: "*.go", Root: "web", NameOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected success, got error result")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "web/main.go") || strings.Contains(text, "api/main.go") {
+		t.Errorf("expected only web/main.go, got:\n%s", text)
+	}
+
+	result, _, _ = h.Handle(context.Background(), nil, FilesArgs{Pattern: "*.go", Root: "docs"})
+	if !result.IsError {
+		t.Error("expected error for unknown root")
+	}
+}
+
+func Test_FilesHandler_StructuredOutput(t *testing.T) {
+	h := newTestFilesHandler(t)
+	h.FileIndex.AddFile(&index.IndexedFile{
+		Path:         "/project/src/main.go",
+		RelativePath: "src/main.go",
+		Language:     "Go",
+		SizeBytes:    512,
+		LineCount:    20,
+		ModTime:      time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		ContentHash:  "0123456789abcdef",
+	})
+
+	_, output, err := h.Handle(context.Background(), nil, FilesArgs{Pattern: "**/*.go", NameOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Metadata is included even when the text lists only names
+	assertJSON(t, output, `{
+		"files": [{
+			"path": "src/main.go",
+			"language": "Go",
+			"sizeBytes": 512,
+			"lineCount": 20,
+			"modTime": "2024-05-01T12:30:00Z",
+			"sha256": "0123456789abcdef"
+		}]
+	}`)
+}
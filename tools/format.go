@@ -5,9 +5,11 @@ import (
 	"strings"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
 )
 
-// FormatSearchResults formats content search results for AI consumption.
+// FormatSearchResults formats content search results for AI consumption. Each matching
+// line ends with the query terms found on it, e.g. "[terms: user, session]".
 func FormatSearchResults(results []index.ContentSearchResult, totalMatches int) string {
 	if len(results) == 0 {
 		return "No matches found."
@@ -26,7 +28,7 @@ func FormatSearchResults(results []index.ContentSearchResult, totalMatches int)
 			for _, ctxLine := range match.ContextBefore {
 				builder.WriteString(fmt.Sprintf("  %s\n", ctxLine))
 			}
-			builder.WriteString(fmt.Sprintf("  %d: %s\n", match.LineNumber, match.LineText))
+			builder.WriteString(fmt.Sprintf("  %d: %s%s\n", match.LineNumber, match.LineText, formatMatchTerms(match.Terms)))
 			for _, ctxLine := range match.ContextAfter {
 				builder.WriteString(fmt.Sprintf("  %s\n", ctxLine))
 			}
@@ -48,11 +50,12 @@ func FormatFileResults(results []index.FileSearchResult, nameOnly bool) string {
 			builder.WriteString(result.File.RelativePath)
 			builder.WriteString("\n")
 		} else {
-			builder.WriteString(fmt.Sprintf("%s (%s, %s, %dL)\n",
+			builder.WriteString(fmt.Sprintf("%s (%s, %s, %dL%s)\n",
 				result.File.RelativePath,
 				result.File.Language,
 				formatFileSize(result.File.SizeBytes),
 				result.File.LineCount,
+				formatContentHash(result.File.ContentHash),
 			))
 		}
 	}
@@ -60,36 +63,201 @@ func FormatFileResults(results []index.FileSearchResult, nameOnly bool) string {
 	return builder.String()
 }
 
+// formatMatchTerms returns the "  [terms: ...]" suffix of a matching line, or "" if no
+// terms were recorded for it.
+func formatMatchTerms(terms []string) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	return "  [terms: " + strings.Join(terms, ", ") + "]"
+}
+
+// formatContentHash returns the ", sha256:..." suffix of a file metadata line, abbreviated
+// to 12 hex digits, or "" if the file has no recorded hash.
+func formatContentHash(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return ", sha256:" + hash
+}
+
+// formatProgress describes the startup indexing progress, e.g. "indexing in progress: 1200/3400 files".
+// The total grows while directories are still being walked.
+func formatProgress(state index.ProgressState) string {
+	switch state.Phase {
+	case index.PhaseReady:
+		return fmt.Sprintf("ready (built in %s)", formatDuration(state.Elapsed))
+	case index.PhaseSyncing:
+		return fmt.Sprintf("syncing snapshot in progress: %d/%d files", state.Processed, state.Discovered)
+	default:
+		return fmt.Sprintf("indexing in progress: %d/%d files", state.Processed, state.Discovered)
+	}
+}
+
+// IndexingNotice returns the note prepended to tool results answered before the startup
+// indexing completed.
+func IndexingNotice(state index.ProgressState) string {
+	return fmt.Sprintf("Note: %s; results may be incomplete.\n", formatProgress(state))
+}
+
 // FormatFileContent formats a file's content with line numbers for AI consumption.
 // offset: 1-based starting line (0 = from beginning). limit: max lines (0 = all).
 // Line numbers in the output reflect actual file positions, not local indices.
 func FormatFileContent(content string, offset, limit int) string {
-	lines := strings.Split(content, "\n")
+	lines, firstLineNum, _ := selectLines(content, offset, limit)
+	if len(lines) == 0 {
+		return "Offset exceeds file length.\n"
+	}
+
+	lastLineNum := firstLineNum + len(lines) - 1
+	width := len(fmt.Sprintf("%d", lastLineNum))
+
+	var builder strings.Builder
+	for i, line := range lines {
+		builder.WriteString(fmt.Sprintf("%*d: %s\n", width, firstLineNum+i, line))
+	}
+	return builder.String()
+}
+
+// selectLines returns the lines of content selected by offset (1-based starting line,
+// 0 = from beginning) and limit (max lines, 0 = all), the number of the first selected
+// line, and the total number of lines. No lines are selected if offset exceeds the file.
+func selectLines(content string, offset, limit int) (lines []string, firstLine int, totalLines int) {
+	lines = strings.Split(content, "\n")
+	totalLines = len(lines)
 
 	startIdx := 0
 	if offset > 1 {
 		startIdx = offset - 1
 	}
 	if startIdx >= len(lines) {
-		return "Offset exceeds file length.\n"
+		return nil, startIdx + 1, totalLines
 	}
 	lines = lines[startIdx:]
 
 	if limit > 0 && limit < len(lines) {
 		lines = lines[:limit]
 	}
+	return lines, startIdx + 1, totalLines
+}
 
-	firstLineNum := startIdx + 1
-	lastLineNum := firstLineNum + len(lines) - 1
-	width := len(fmt.Sprintf("%d", lastLineNum))
+// FormatSymbolResults formats symbol lookup results for AI consumption.
+// Each line: path:start-end kind Container.Name
+func FormatSymbolResults(results []symbols.Symbol) string {
+	if len(results) == 0 {
+		return "No symbols found."
+	}
 
 	var builder strings.Builder
-	for i, line := range lines {
-		builder.WriteString(fmt.Sprintf("%*d: %s\n", width, firstLineNum+i, line))
+	for _, symbol := range results {
+		name := symbol.Name
+		if symbol.Container != "" {
+			name = symbol.Container + "." + symbol.Name
+		}
+		builder.WriteString(fmt.Sprintf("%s:%d-%d %s %s\n",
+			symbol.RelativePath,
+			symbol.StartLine,
+			symbol.EndLine,
+			symbol.Kind,
+			name,
+		))
+	}
+	return builder.String()
+}
+
+// FormatReferenceResults formats symbol references for AI consumption.
+// Each line: path:line:column kind | source line
+func FormatReferenceResults(
+	symbol string,
+	references []symbols.Reference,
+	totalReferences int,
+	kindCounts map[symbols.ReferenceKind]int,
+) string {
+	if totalReferences == 0 {
+		return "No references found."
+	}
+
+	var counts []string
+	for _, kind := range symbols.AllReferenceKinds {
+		if kindCounts[kind] > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", kind, kindCounts[kind]))
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%d references to %s (%s):\n", totalReferences, symbol, strings.Join(counts, ", ")))
+	for _, reference := range references {
+		builder.WriteString(fmt.Sprintf("%s:%d:%d %s | %s\n",
+			reference.RelativePath,
+			reference.Line,
+			reference.Column,
+			reference.Kind,
+			reference.LineText,
+		))
+	}
+	if len(references) < totalReferences {
+		builder.WriteString(fmt.Sprintf("(showing first %d)\n", len(references)))
 	}
 	return builder.String()
 }
 
+// FormatOutline formats a file outline for AI consumption.
+// Members are indented under their container; each symbol line: start-end kind Name | signature
+func FormatOutline(outline *symbols.Outline) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s (%s, %d lines)\n", outline.RelativePath, outline.Language, outline.LineCount))
+
+	if outline.Package != "" {
+		builder.WriteString(fmt.Sprintf("package %s (line %d)\n", outline.Package, outline.PackageLine))
+	}
+	if len(outline.Imports) > 0 {
+		paths := make([]string, len(outline.Imports))
+		for i, imp := range outline.Imports {
+			paths[i] = imp.Path
+		}
+		builder.WriteString(fmt.Sprintf("imports (lines %d-%d): %s\n",
+			outline.Imports[0].Line,
+			outline.Imports[len(outline.Imports)-1].Line,
+			strings.Join(paths, ", "),
+		))
+	}
+
+	if len(outline.Items) == 0 {
+		builder.WriteString("No symbols found.\n")
+		return builder.String()
+	}
+	builder.WriteString("\n")
+	writeOutlineItems(&builder, outline.Items, 0)
+	return builder.String()
+}
+
+// writeOutlineItems writes outline items recursively, indenting two spaces per level.
+func writeOutlineItems(builder *strings.Builder, items []symbols.OutlineItem, depth int) {
+	for _, item := range items {
+		symbol := item.Symbol
+		name := symbol.Name
+		// Members whose container is not defined in this file keep their qualified name
+		if depth == 0 && symbol.Container != "" {
+			name = symbol.Container + "." + symbol.Name
+		}
+		builder.WriteString(fmt.Sprintf("%s%d-%d %s %s",
+			strings.Repeat("  ", depth),
+			symbol.StartLine,
+			symbol.EndLine,
+			symbol.Kind,
+			name,
+		))
+		if symbol.Signature != "" {
+			builder.WriteString(" | " + symbol.Signature)
+		}
+		builder.WriteString("\n")
+		writeOutlineItems(builder, item.Children, depth+1)
+	}
+}
+
 // formatFileSize converts bytes to a human-readable string.
 func formatFileSize(bytes int64) string {
 	switch {
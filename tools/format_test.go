@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
 )
 
 // --- formatFileSize ---
@@ -40,6 +41,32 @@ func Test_FormatSearchResults_NoMatches(t *testing.T) {
 	}
 }
 
+func Test_FormatSearchResults_ShowsMatchedTerms(t *testing.T) {
+	results := []index.ContentSearchResult{
+		{
+			RelativePath: "auth/session.go",
+			Terms:        []string{"user", "session"},
+			Matches: []index.LineMatch{
+				{LineNumber: 3, LineText: "func NewSession(user User) *Session {", Terms: []string{"user", "session"}},
+				{LineNumber: 9, LineText: "	return user.ID", Terms: []string{"user"}},
+				{LineNumber: 12, LineText: "	// no recorded terms"},
+			},
+		},
+	}
+
+	got := FormatSearchResults(results, 3)
+
+	for _, want := range []string{
+		"  3: func NewSession(user User) *Session {  [terms: user, session]\n",
+		"  9: 	return user.ID  [terms: user]\n",
+		"  12: 	// no recorded terms\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected line %q, got:\n%s", want, got)
+		}
+	}
+}
+
 func Test_FormatSearchResults_WithMatches(t *testing.T) {
 	results := []index.ContentSearchResult{
 		{
@@ -110,6 +137,30 @@ func Test_FormatFileResults_WithMetadata(t *testing.T) {
 	if !strings.Contains(got, "50L") {
 		t.Errorf("expected line count, got:\n%s", got)
 	}
+	if strings.Contains(got, "sha256") {
+		t.Errorf("expected no hash for a file without one, got:\n%s", got)
+	}
+}
+
+func Test_FormatFileResults_ContentHash(t *testing.T) {
+	results := []index.FileSearchResult{
+		{
+			File: &index.IndexedFile{
+				RelativePath: "src/app.go",
+				Language:     "Go",
+				SizeBytes:    13,
+				LineCount:    1,
+				ContentHash:  index.ContentHash([]byte("package main\n")),
+			},
+		},
+	}
+
+	got := FormatFileResults(results, false)
+
+	want := "src/app.go (Go, 13 B, 1L, sha256:" + results[0].File.ContentHash[:12] + ")\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
 }
 
 func Test_FormatFileResults_NameOnly(t *testing.T) {
@@ -211,3 +262,30 @@ func Test_FormatFileContent_OffsetBeyondEOF(t *testing.T) {
 		t.Errorf("expected error message for offset beyond EOF, got:\n%s", got)
 	}
 }
+
+// --- FormatOutline ---
+
+func Test_FormatOutline_NoSymbols(t *testing.T) {
+	output := FormatOutline(&symbols.Outline{RelativePath: "README.md", Language: "Markdown", LineCount: 3})
+
+	if output != "README.md (Markdown, 3 lines)\nNo symbols found.\n" {
+		t.Errorf("unexpected output: %q", output)
+	}
+}
+
+func Test_FormatOutline_QualifiesForeignMethods(t *testing.T) {
+	outline := &symbols.Outline{
+		RelativePath: "stop.go",
+		Language:     "Go",
+		LineCount:    3,
+		Items: []symbols.OutlineItem{
+			{Symbol: symbols.Symbol{Name: "Stop", Kind: symbols.KindMethod, Container: "Server", StartLine: 3, EndLine: 3}},
+		},
+	}
+
+	output := FormatOutline(outline)
+
+	if !strings.Contains(output, "3-3 method Server.Stop\n") {
+		t.Errorf("expected qualified method name, got:\n%s", output)
+	}
+}
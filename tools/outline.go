@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// OutlineArgs defines the input parameters for the codeindex_outline tool.
+type OutlineArgs struct {
+	FilePath string `json:"filePath" jsonschema:"Relative file path to outline (e.g. src/main.go)"`
+}
+
+// OutlineOutput is the structured result of the codeindex_outline tool.
+type OutlineOutput struct {
+	Path        string          `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	Language    string          `json:"language" jsonschema:"Detected programming language"`
+	LineCount   int             `json:"lineCount" jsonschema:"Number of lines in the file"`
+	Package     string          `json:"package,omitempty" jsonschema:"Package or module declaration"`
+	PackageLine int             `json:"packageLine,omitempty" jsonschema:"Line of the package declaration"`
+	Imports     []OutlineImport `json:"imports" jsonschema:"Imported packages, modules or headers"`
+	Symbols     []OutlineSymbol `json:"symbols" jsonschema:"Symbols in outline order: each type is followed by its members with depth one higher"`
+}
+
+// OutlineImport is an import in OutlineOutput.
+type OutlineImport struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// OutlineSymbol is a symbol in OutlineOutput.
+type OutlineSymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind" jsonschema:"Symbol kind, e.g. function, method, struct"`
+	Container string `json:"container,omitempty" jsonschema:"Enclosing type or class"`
+	StartLine int    `json:"startLine" jsonschema:"First line (1-based, inclusive)"`
+	EndLine   int    `json:"endLine" jsonschema:"Last line (1-based, inclusive)"`
+	Signature string `json:"signature,omitempty" jsonschema:"Declaration header on one line"`
+	Depth     int    `json:"depth" jsonschema:"Nesting level: 0 for top-level symbols"`
+}
+
+// OutlineHandler holds the dependencies for the outline tool.
+type OutlineHandler struct {
+	FileIndex    *index.FileIndex
+	ContentIndex *index.ContentIndex
+	Logger       *slog.Logger
+}
+
+// Handle processes a codeindex_outline request.
+func (h *OutlineHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args OutlineArgs) (*mcp.CallToolResult, *OutlineOutput, error) {
+	start := time.Now()
+
+	if args.FilePath == "" {
+		h.Logger.Warn("codeindex_outline called with empty filePath")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: filePath parameter is required"}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	file := h.FileIndex.GetFile(args.FilePath)
+	content, ok := h.ContentIndex.GetFileContent(args.FilePath)
+	if file == nil || !ok {
+		h.Logger.Info("codeindex_outline file not found", "filePath", args.FilePath)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("File not found in index: %s", args.FilePath)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	outline := symbols.BuildOutline(args.FilePath, content, file.Language)
+
+	elapsed := time.Since(start)
+	h.Logger.Info("codeindex_outline",
+		"filePath", args.FilePath,
+		"language", file.Language,
+		"items", len(outline.Items),
+		"elapsed", elapsed,
+	)
+
+	output := FormatOutline(outline)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: output}},
+	}, newOutlineOutput(outline), nil
+}
+
+// newOutlineOutput converts an outline to the structured output, flattening the symbol tree.
+func newOutlineOutput(outline *symbols.Outline) *OutlineOutput {
+	output := &OutlineOutput{
+		Path:        outline.RelativePath,
+		Language:    outline.Language,
+		LineCount:   outline.LineCount,
+		Package:     outline.Package,
+		PackageLine: outline.PackageLine,
+		Imports:     make([]OutlineImport, len(outline.Imports)),
+		Symbols:     []OutlineSymbol{},
+	}
+	for i, imp := range outline.Imports {
+		output.Imports[i] = OutlineImport{Path: imp.Path, Line: imp.Line}
+	}
+	var flatten func(items []symbols.OutlineItem, depth int)
+	flatten = func(items []symbols.OutlineItem, depth int) {
+		for _, item := range items {
+			symbol := item.Symbol
+			output.Symbols = append(output.Symbols, OutlineSymbol{
+				Name:      symbol.Name,
+				Kind:      string(symbol.Kind),
+				Container: symbol.Container,
+				StartLine: symbol.StartLine,
+				EndLine:   symbol.EndLine,
+				Signature: symbol.Signature,
+				Depth:     depth,
+			})
+			flatten(item.Children, depth+1)
+		}
+	}
+	flatten(outline.Items, 0)
+	return output
+}
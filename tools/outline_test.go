@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newTestOutlineHandler(t *testing.T) *OutlineHandler {
+	t.Helper()
+	ci, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatalf("failed to create content index: %v", err)
+	}
+	t.Cleanup(func() { ci.Close() })
+
+	fi := index.NewFileIndex()
+	content := "package server\n\nimport \"context\"\n\ntype Server struct{}\n\nfunc (s *Server) Start(ctx context.Context) error {\n\treturn nil\n}\n"
+	fi.AddFile(&index.IndexedFile{RelativePath: "server/server.go", Language: "Go"})
+	ci.IndexFile("server/server.go", content, "Go")
+
+	return &OutlineHandler{
+		FileIndex:    fi,
+		ContentIndex: ci,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func Test_OutlineHandler_EmptyFilePath(t *testing.T) {
+	h := newTestOutlineHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, OutlineArgs{FilePath: ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty filePath")
+	}
+}
+
+func Test_OutlineHandler_FileNotFound(t *testing.T) {
+	h := newTestOutlineHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, OutlineArgs{FilePath: "missing.go"})
+	if !result.IsError {
+		t.Fatal("expected IsError=true for missing file")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "File not found in index") {
+		t.Errorf("expected not-found message, got: %s", text)
+	}
+}
+
+func Test_OutlineHandler_Outline(t *testing.T) {
+	h := newTestOutlineHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, OutlineArgs{FilePath: "server/server.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected success, got error result")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	expected := []string{
+		"server/server.go (Go, 9 lines)",
+		"package server (line 1)",
+		"imports (lines 3-3): context",
+		"5-5 struct Server | type Server struct",
+		"  7-9 method Start | func (s *Server) Start(ctx context.Context) error",
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("expected %q in output, got:\n%s", line, text)
+		}
+	}
+}
+
+func Test_OutlineHandler_StructuredOutput(t *testing.T) {
+	h := newTestOutlineHandler(t)
+
+	_, output, err := h.Handle(context.Background(), nil, OutlineArgs{FilePath: "server/server.go"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{
+		"path": "server/server.go",
+		"language": "Go",
+		"lineCount": 9,
+		"package": "server",
+		"packageLine": 1,
+		"imports": [{"path": "context", "line": 3}],
+		"symbols": [
+			{"name": "Server", "kind": "struct", "startLine": 5, "endLine": 5, "signature": "type Server struct", "depth": 0},
+			{"name": "Start", "kind": "method", "container": "Server", "startLine": 7, "endLine": 9, "signature": "func (s *Server) Start(ctx context.Context) error", "depth": 1}
+		]
+	}`)
+}
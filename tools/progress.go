@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressInterval is how often poll samples the progress of a long tool call.
+const progressInterval = 250 * time.Millisecond
+
+// progressReporter sends MCP progress notifications for a tool call whose client passed a
+// progress token. All methods are no-ops on a nil reporter, which newProgressReporter
+// returns when the client did not ask for progress.
+type progressReporter struct {
+	session *mcp.ServerSession
+	token   any
+
+	mu           sync.Mutex
+	lastProgress int
+}
+
+// newProgressReporter returns a reporter for req, or nil if the client did not ask for progress.
+func newProgressReporter(req *mcp.CallToolRequest) *progressReporter {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return nil
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return nil
+	}
+	return &progressReporter{session: req.Session, token: token, lastProgress: -1}
+}
+
+// report notifies the client that progress of total items are done (total 0 = unknown).
+// Progress must increase with every notification, so calls without progress are dropped.
+func (r *progressReporter) report(ctx context.Context, progress int, total int, message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if progress <= r.lastProgress {
+		r.mu.Unlock()
+		return
+	}
+	r.lastProgress = progress
+	r.mu.Unlock()
+
+	// Progress is best effort: a failed notification does not fail the call
+	r.session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: r.token,
+		Progress:      float64(progress),
+		Total:         float64(total),
+		Message:       message,
+	})
+}
+
+// poll reports the value of state every progressInterval until the returned stop function
+// is called or ctx is done. Use it when progress is produced where notifications must not
+// be sent, such as under an index lock.
+func (r *progressReporter) poll(ctx context.Context, state func() (progress int, total int, message string)) (stop func()) {
+	if r == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				progress, total, message := state()
+				r.report(ctx, progress, total, message)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type progressArgs struct{}
+
+// callWithProgress calls a tool that reports progress through a progressReporter and
+// returns the notifications received by the client, waiting until there are at least want.
+func callWithProgress(t *testing.T, token any, want int, report func(ctx context.Context, reporter *progressReporter)) []*mcp.ProgressNotificationParams {
+	t.Helper()
+	mcpServer := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "0.0.1"}, nil)
+	mcp.AddTool(mcpServer, &mcp.Tool{Name: "work"}, func(ctx context.Context, req *mcp.CallToolRequest, args progressArgs) (*mcp.CallToolResult, any, error) {
+		report(ctx, newProgressReporter(req))
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+	})
+
+	var mu sync.Mutex
+	var received []*mcp.ProgressNotificationParams
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, req.Params)
+		},
+	})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	if _, err := mcpServer.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	params := &mcp.CallToolParams{Name: "work", Arguments: map[string]any{}}
+	if token != nil {
+		// SetProgressToken only adds to an existing Meta map
+		params.Meta = mcp.Meta{}
+		params.SetProgressToken(token)
+	}
+	if _, err := session.CallTool(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	// The client handles notifications asynchronously, possibly after the result
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		if len(received) >= want || time.Now().After(deadline) {
+			defer mu.Unlock()
+			return received
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_progressReporter_Report(t *testing.T) {
+	received := callWithProgress(t, "reindex-1", 3, func(ctx context.Context, reporter *progressReporter) {
+		reporter.report(ctx, 0, 10, "starting")
+		reporter.report(ctx, 4, 10, "working")
+		reporter.report(ctx, 4, 10, "no progress, dropped")
+		reporter.report(ctx, 10, 10, "finished")
+	})
+
+	if len(received) != 3 {
+		t.Fatalf("expected 3 notifications, got %d", len(received))
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i].Progress < received[j].Progress })
+	for i, want := range []float64{0, 4, 10} {
+		if received[i].ProgressToken != "reindex-1" || received[i].Progress != want || received[i].Total != 10 {
+			t.Errorf("notification %d: expected %v/10 for the request token, got %+v", i, want, received[i])
+		}
+	}
+}
+
+func Test_progressReporter_NoToken(t *testing.T) {
+	received := callWithProgress(t, nil, 0, func(ctx context.Context, reporter *progressReporter) {
+		if reporter != nil {
+			t.Error("expected no reporter without a progress token")
+		}
+		reporter.report(ctx, 1, 1, "ignored")
+		reporter.poll(ctx, func() (int, int, string) { return 1, 1, "ignored" })()
+	})
+
+	if len(received) != 0 {
+		t.Errorf("expected no notifications, got %d", len(received))
+	}
+}
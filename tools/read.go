@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -15,16 +16,27 @@ type ReadArgs struct {
 	FilePath string `json:"filePath" jsonschema:"Relative file path to read from the index (e.g. src/main.go)"`
 	Offset   int    `json:"offset,omitempty" jsonschema:"Line number to start reading from (1-based). Only provide if the file is too large to read at once"`
 	Limit    int    `json:"limit,omitempty" jsonschema:"Number of lines to read. Only provide if the file is too large to read at once"`
+	Root     string `json:"root,omitempty" jsonschema:"Workspace root the filePath is relative to (multi-root workspaces). Without it filePath includes the root name prefix"`
+}
+
+// ReadOutput is the structured result of the codeindex_read tool.
+type ReadOutput struct {
+	Path       string   `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	StartLine  int      `json:"startLine" jsonschema:"Line number of the first returned line (1-based)"`
+	EndLine    int      `json:"endLine" jsonschema:"Line number of the last returned line; startLine-1 if no lines were returned"`
+	TotalLines int      `json:"totalLines" jsonschema:"Number of lines in the file"`
+	Lines      []string `json:"lines" jsonschema:"The returned lines without line terminators"`
 }
 
 // ReadHandler holds the dependencies for the read tool.
 type ReadHandler struct {
 	ContentIndex *index.ContentIndex
+	Workspace    *workspace.Workspace
 	Logger       *slog.Logger
 }
 
 // Handle processes a codeindex_read request.
-func (h *ReadHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ReadArgs) (*mcp.CallToolResult, any, error) {
+func (h *ReadHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ReadArgs) (*mcp.CallToolResult, *ReadOutput, error) {
 	start := time.Now()
 
 	if args.FilePath == "" {
@@ -35,21 +47,39 @@ func (h *ReadHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args
 		}, nil, nil
 	}
 
-	content, ok := h.ContentIndex.GetFileContent(args.FilePath)
+	root, err := resolveRoot(h.Workspace, args.Root)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	filePath := root.IndexPath(args.FilePath)
+	content, ok := h.ContentIndex.GetFileContent(filePath)
 	if !ok {
-		h.Logger.Info("codeindex_read file not found", "filePath", args.FilePath)
+		h.Logger.Info("codeindex_read file not found", "filePath", filePath)
 		return &mcp.CallToolResult{
-			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("File not found in index: %s", args.FilePath)}},
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("File not found in index: %s", filePath)}},
 			IsError: true,
 		}, nil, nil
 	}
 
 	elapsed := time.Since(start)
-	h.Logger.Info("codeindex_read", "filePath", args.FilePath, "elapsed", elapsed)
+	h.Logger.Info("codeindex_read", "filePath", filePath, "elapsed", elapsed)
 
 	output := FormatFileContent(content, args.Offset, args.Limit)
 
+	lines, firstLine, totalLines := selectLines(content, args.Offset, args.Limit)
+	structured := &ReadOutput{
+		Path:       filePath,
+		StartLine:  firstLine,
+		EndLine:    firstLine + len(lines) - 1,
+		TotalLines: totalLines,
+		Lines:      lines,
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: output}},
-	}, nil, nil
+	}, structured, nil
 }
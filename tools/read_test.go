@@ -133,3 +133,47 @@ func Test_ReadHandler_WithLimit(t *testing.T) {
 		t.Errorf("expected limit to stop after 2 lines, got:\n%s", text)
 	}
 }
+
+func Test_ReadHandler_RootRelativePath(t *testing.T) {
+	h := newTestReadHandler(t)
+	h.Workspace = newTestWorkspace(t)
+	h.ContentIndex.IndexFile("api/main.go", "package api\n", "Go")
+	h.ContentIndex.IndexFile("web/main.go", "package web\n", "Go")
+
+	result, _, err := h.Handle(context.Background(), nil, ReadArgs{FilePath: "main.go", Root: "web"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected success, got error result")
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "package web") {
+		t.Errorf("expected content of web/main.go, got:\n%s", text)
+	}
+
+	// Without a root the path includes the root name
+	result, _, _ = h.Handle(context.Background(), nil, ReadArgs{FilePath: "api/main.go"})
+	if result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "package api") {
+		t.Errorf("expected content of api/main.go, got: %+v", result)
+	}
+}
+
+func Test_ReadHandler_StructuredOutput(t *testing.T) {
+	h := newTestReadHandler(t)
+	h.ContentIndex.IndexFile("main.go", "line1\nline2\nline3\nline4", "Go")
+
+	_, output, err := h.Handle(context.Background(), nil, ReadArgs{FilePath: "main.go", Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{
+		"path": "main.go",
+		"startLine": 2,
+		"endLine": 3,
+		"totalLines": 4,
+		"lines": ["line2", "line3"]
+	}`)
+
+	_, output, _ = h.Handle(context.Background(), nil, ReadArgs{FilePath: "main.go", Offset: 10})
+	assertJSON(t, output, `{"path": "main.go", "startLine": 10, "endLine": 9, "totalLines": 4, "lines": null}`)
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ReferencesArgs defines the input parameters for the codeindex_references tool.
+type ReferencesArgs struct {
+	Symbol         string `json:"symbol" jsonschema:"Identifier to find references to (e.g. NewServer). Matched as a whole identifier, case-sensitive"`
+	DefinitionPath string `json:"definitionPath,omitempty" jsonschema:"Relative path of the file defining the symbol. Unexported Go identifiers are then only searched in the defining package"`
+	DefinitionLine int    `json:"definitionLine,omitempty" jsonschema:"Line of the definition in definitionPath (1-based). The occurrence on this line is reported as the definition"`
+	Kind           string `json:"kind,omitempty" jsonschema:"Comma-separated reference kinds to include: definition, call, type, import, reference, comment, string (default: all)"`
+	FileGlob       string `json:"fileGlob,omitempty" jsonschema:"Glob pattern to restrict which files are searched (e.g. **/*.go)"`
+	MaxResults     int    `json:"maxResults,omitempty" jsonschema:"Maximum number of references to return (default 100)"`
+}
+
+// ReferencesOutput is the structured result of the codeindex_references tool.
+type ReferencesOutput struct {
+	Symbol          string          `json:"symbol"`
+	TotalReferences int             `json:"totalReferences" jsonschema:"Number of references found, including those beyond maxResults"`
+	KindCounts      map[string]int  `json:"kindCounts,omitempty" jsonschema:"Number of references per kind"`
+	References      []ReferenceInfo `json:"references" jsonschema:"References, definitions first, then by path and position"`
+}
+
+// ReferenceInfo is a usage of an identifier.
+type ReferenceInfo struct {
+	Path   string `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	Line   int    `json:"line" jsonschema:"Line number (1-based)"`
+	Column int    `json:"column" jsonschema:"Byte column (1-based)"`
+	Kind   string `json:"kind" jsonschema:"definition, call, type, import, reference, comment or string"`
+	Text   string `json:"text" jsonschema:"The source line, trimmed"`
+}
+
+// ReferencesHandler holds the dependencies for the references tool.
+type ReferencesHandler struct {
+	FileIndex    *index.FileIndex
+	ContentIndex *index.ContentIndex
+	Logger       *slog.Logger
+}
+
+// Handle processes a codeindex_references request.
+func (h *ReferencesHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ReferencesArgs) (*mcp.CallToolResult, *ReferencesOutput, error) {
+	start := time.Now()
+
+	if args.Symbol == "" {
+		h.Logger.Warn("codeindex_references called with empty symbol")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: symbol parameter is required"}},
+			IsError: true,
+		}, nil, nil
+	}
+	if strings.ContainsAny(args.Symbol, " \t\n") {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: symbol must be a single identifier, got %q", args.Symbol)}},
+			IsError: true,
+		}, nil, nil
+	}
+	if args.MaxResults <= 0 {
+		args.MaxResults = 100
+	}
+
+	kindFilter := make(map[symbols.ReferenceKind]bool)
+	if args.Kind != "" {
+		for _, kindName := range strings.Split(args.Kind, ",") {
+			kind, ok := symbols.ParseReferenceKind(kindName)
+			if !ok {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: unknown reference kind %q", strings.TrimSpace(kindName))}},
+					IsError: true,
+				}, nil, nil
+			}
+			kindFilter[kind] = true
+		}
+	}
+	normalizedGlob := strings.ReplaceAll(args.FileGlob, "\\", "/")
+	if normalizedGlob != "" && !doublestar.ValidatePattern(normalizedGlob) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: invalid fileGlob pattern %q", args.FileGlob)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	definitionPath := strings.ReplaceAll(args.DefinitionPath, "\\", "/")
+	if args.DefinitionLine > 0 && definitionPath == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: definitionLine requires definitionPath"}},
+			IsError: true,
+		}, nil, nil
+	}
+	packageDir := ""
+	definitionColumn := 0
+	if definitionPath != "" {
+		definitionFile := h.FileIndex.GetFile(definitionPath)
+		definitionContent, ok := h.ContentIndex.GetFileContent(definitionPath)
+		if definitionFile == nil || !ok {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("File not found in index: %s", args.DefinitionPath)}},
+				IsError: true,
+			}, nil, nil
+		}
+		// Unexported Go identifiers are only visible inside their package (directory)
+		if definitionFile.Language == "Go" && !token.IsExported(args.Symbol) {
+			packageDir = path.Dir(definitionPath)
+		}
+		if args.DefinitionLine > 0 {
+			definitionColumn = findDefinitionColumn(definitionPath, definitionContent, definitionFile.Language, args.Symbol, args.DefinitionLine)
+			if definitionColumn == 0 {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %s does not occur in code on line %d of %s", args.Symbol, args.DefinitionLine, definitionPath)}},
+					IsError: true,
+				}, nil, nil
+			}
+		}
+	}
+
+	var references []symbols.Reference
+	for _, relativePath := range h.ContentIndex.FilesContaining(args.Symbol) {
+		if packageDir != "" && (path.Dir(relativePath) != packageDir || !strings.HasSuffix(relativePath, ".go")) {
+			continue
+		}
+		if normalizedGlob != "" {
+			if matched, _ := doublestar.Match(normalizedGlob, relativePath); !matched {
+				continue
+			}
+		}
+		file := h.FileIndex.GetFile(relativePath)
+		content, ok := h.ContentIndex.GetFileContent(relativePath)
+		if file == nil || !ok {
+			continue
+		}
+
+		for _, reference := range symbols.FindReferences(relativePath, content, file.Language, args.Symbol) {
+			if relativePath == definitionPath && reference.Line == args.DefinitionLine && reference.Column == definitionColumn {
+				reference.Kind = symbols.RefDefinition
+			}
+			references = append(references, reference)
+		}
+	}
+
+	kindCounts := make(map[symbols.ReferenceKind]int)
+	filtered := references[:0]
+	for _, reference := range references {
+		if len(kindFilter) > 0 && !kindFilter[reference.Kind] {
+			continue
+		}
+		kindCounts[reference.Kind]++
+		filtered = append(filtered, reference)
+	}
+	references = filtered
+
+	// Definitions first, then in path and position order
+	sort.SliceStable(references, func(i, j int) bool {
+		iDefinition := references[i].Kind == symbols.RefDefinition
+		jDefinition := references[j].Kind == symbols.RefDefinition
+		return iDefinition && !jDefinition
+	})
+
+	totalReferences := len(references)
+	if len(references) > args.MaxResults {
+		references = references[:args.MaxResults]
+	}
+
+	elapsed := time.Since(start)
+	h.Logger.Info("codeindex_references",
+		"symbol", args.Symbol,
+		"definitionPath", definitionPath,
+		"kind", args.Kind,
+		"references", totalReferences,
+		"elapsed", elapsed,
+	)
+
+	output := FormatReferenceResults(args.Symbol, references, totalReferences, kindCounts)
+
+	structured := &ReferencesOutput{
+		Symbol:          args.Symbol,
+		TotalReferences: totalReferences,
+		KindCounts:      make(map[string]int, len(kindCounts)),
+		References:      make([]ReferenceInfo, len(references)),
+	}
+	for kind, count := range kindCounts {
+		structured.KindCounts[string(kind)] = count
+	}
+	for i, reference := range references {
+		structured.References[i] = ReferenceInfo{
+			Path:   reference.RelativePath,
+			Line:   reference.Line,
+			Column: reference.Column,
+			Kind:   string(reference.Kind),
+			Text:   reference.LineText,
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: output}},
+	}, structured, nil
+}
+
+// findDefinitionColumn returns the column of the first occurrence of symbol on the given line
+// outside comments and strings, or 0 if there is none.
+func findDefinitionColumn(relativePath string, content string, language string, symbol string, line int) int {
+	for _, reference := range symbols.FindReferences(relativePath, content, language, symbol) {
+		if reference.Line == line && reference.Kind != symbols.RefComment && reference.Kind != symbols.RefString {
+			return reference.Column
+		}
+	}
+	return 0
+}
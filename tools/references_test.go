@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newTestReferencesHandler(t *testing.T) *ReferencesHandler {
+	t.Helper()
+	ci, err := index.NewContentIndex()
+	if err != nil {
+		t.Fatalf("failed to create content index: %v", err)
+	}
+	t.Cleanup(func() { ci.Close() })
+
+	fi := index.NewFileIndex()
+	files := map[string]string{
+		"server/server.go": "package server\n\n// start begins serving\nfunc start() {}\n\nfunc Run() { start() }\n",
+		"server/util.go":   "package server\n\nfunc init() { start() }\n",
+		"other/other.go":   "package other\n\nfunc start() {}\n",
+		"web/app.ts":       "import { Run } from \"./run\";\n\nRun();\n",
+	}
+	for relativePath, content := range files {
+		language := "Go"
+		if strings.HasSuffix(relativePath, ".ts") {
+			language = "TypeScript"
+		}
+		fi.AddFile(&index.IndexedFile{RelativePath: relativePath, Language: language})
+		ci.IndexFile(relativePath, content, language)
+	}
+
+	return &ReferencesHandler{
+		FileIndex:    fi,
+		ContentIndex: ci,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func Test_ReferencesHandler_EmptySymbol(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, ReferencesArgs{Symbol: ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty symbol")
+	}
+}
+
+func Test_ReferencesHandler_AllFiles(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, ReferencesArgs{Symbol: "Run"})
+	if result.IsError {
+		t.Fatalf("expected success, got error result")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	expected := []string{
+		"3 references to Run (definition: 1, call: 1, import: 1):",
+		"server/server.go:6:6 definition | func Run() { start() }",
+		"web/app.ts:1:10 import | import { Run } from \"./run\";",
+		"web/app.ts:3:1 call | Run();",
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("expected %q in output, got:\n%s", line, text)
+		}
+	}
+	if !strings.HasPrefix(strings.SplitN(text, "\n", 3)[1], "server/server.go:6:6 definition") {
+		t.Errorf("expected definition listed first, got:\n%s", text)
+	}
+}
+
+func Test_ReferencesHandler_UnexportedGoScopedToPackage(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, ReferencesArgs{
+		Symbol:         "start",
+		DefinitionPath: "server/server.go",
+		DefinitionLine: 4,
+		Kind:           "definition,call",
+	})
+	if result.IsError {
+		t.Fatalf("expected success, got: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if strings.Contains(text, "other/other.go") {
+		t.Errorf("expected other package to be excluded, got:\n%s", text)
+	}
+	if strings.Contains(text, "comment") {
+		t.Errorf("expected comments to be filtered out, got:\n%s", text)
+	}
+	if !strings.Contains(text, "3 references to start (definition: 1, call: 2):") {
+		t.Errorf("unexpected summary, got:\n%s", text)
+	}
+}
+
+func Test_ReferencesHandler_DefinitionLineMismatch(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, ReferencesArgs{
+		Symbol:         "start",
+		DefinitionPath: "server/server.go",
+		DefinitionLine: 3, // only a comment mentions start on this line
+	})
+	if !result.IsError {
+		t.Fatal("expected IsError=true when the symbol is not on the definition line")
+	}
+}
+
+func Test_ReferencesHandler_UnknownKind(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, ReferencesArgs{Symbol: "Run", Kind: "usage"})
+	if !result.IsError {
+		t.Fatal("expected IsError=true for unknown kind")
+	}
+}
+
+func Test_ReferencesHandler_StructuredOutput(t *testing.T) {
+	h := newTestReferencesHandler(t)
+
+	_, output, err := h.Handle(context.Background(), nil, ReferencesArgs{Symbol: "start", DefinitionPath: "server/server.go", DefinitionLine: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{
+		"symbol": "start",
+		"totalReferences": 4,
+		"kindCounts": {"call": 2, "comment": 1, "definition": 1},
+		"references": [
+			{"path": "server/server.go", "line": 4, "column": 6, "kind": "definition", "text": "func start() {}"},
+			{"path": "server/server.go", "line": 3, "column": 4, "kind": "comment", "text": "// start begins serving"},
+			{"path": "server/server.go", "line": 6, "column": 14, "kind": "call", "text": "func Run() { start() }"},
+			{"path": "server/util.go", "line": 3, "column": 15, "kind": "call", "text": "func init() { start() }"}
+		]
+	}`)
+}
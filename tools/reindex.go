@@ -2,18 +2,28 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
+	"github.com/lexandro/codeindex-mcp/index"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // ReindexArgs defines the input parameters for the codeindex_reindex tool.
 type ReindexArgs struct{}
 
+// ReindexOutput is the structured result of the codeindex_reindex tool.
+type ReindexOutput struct {
+	Files     int    `json:"files" jsonschema:"Number of files indexed"`
+	SizeBytes int64  `json:"sizeBytes" jsonschema:"Total size of the indexed files"`
+	Elapsed   string `json:"elapsed" jsonschema:"Duration of the rebuild, e.g. 1.234s"`
+}
+
 // ReindexFunc is the function signature for the reindex operation.
-// It is provided by main.go to avoid circular dependencies.
-type ReindexFunc func() (indexedCount int, totalSize int64, elapsed string, err error)
+// It is provided by main.go to avoid circular dependencies. onProgress is called
+// periodically while the rebuild runs; if ctx is cancelled, it returns ctx.Err().
+type ReindexFunc func(ctx context.Context, onProgress func(index.ProgressState)) (indexedCount int, totalSize int64, elapsed string, err error)
 
 // ReindexHandler holds the dependencies for the reindex tool.
 type ReindexHandler struct {
@@ -22,10 +32,21 @@ type ReindexHandler struct {
 }
 
 // Handle processes a codeindex_reindex request.
-func (h *ReindexHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ReindexArgs) (*mcp.CallToolResult, any, error) {
+func (h *ReindexHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args ReindexArgs) (*mcp.CallToolResult, *ReindexOutput, error) {
 	h.Logger.Info("codeindex_reindex started")
 
-	indexedCount, totalSize, elapsed, err := h.DoReindex()
+	// Report the files indexed so far to clients that passed a progress token
+	reporter := newProgressReporter(req)
+	indexedCount, totalSize, elapsed, err := h.DoReindex(ctx, func(state index.ProgressState) {
+		reporter.report(ctx, state.Processed, state.Discovered, "reindexing files")
+	})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		h.Logger.Info("codeindex_reindex cancelled", "error", err)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Reindex cancelled; the previous index is still in use"}},
+			IsError: true,
+		}, nil, nil
+	}
 	if err != nil {
 		h.Logger.Error("codeindex_reindex failed", "error", err)
 		return &mcp.CallToolResult{
@@ -45,5 +66,5 @@ func (h *ReindexHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, a
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: output}},
-	}, nil, nil
+	}, &ReindexOutput{Files: indexedCount, SizeBytes: totalSize, Elapsed: elapsed}, nil
 }
@@ -8,12 +8,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/lexandro/codeindex-mcp/index"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func Test_ReindexHandler_Success(t *testing.T) {
 	h := &ReindexHandler{
-		DoReindex: func() (int, int64, string, error) {
+		DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
 			return 42, 1024 * 1024, "1.5s", nil
 		},
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
@@ -45,7 +46,7 @@ func Test_ReindexHandler_Success(t *testing.T) {
 
 func Test_ReindexHandler_Error(t *testing.T) {
 	h := &ReindexHandler{
-		DoReindex: func() (int, int64, string, error) {
+		DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
 			return 0, 0, "", fmt.Errorf("disk full")
 		},
 		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
@@ -64,3 +65,41 @@ func Test_ReindexHandler_Error(t *testing.T) {
 		t.Errorf("expected error message 'disk full', got: %s", text)
 	}
 }
+
+func Test_ReindexHandler_Cancelled(t *testing.T) {
+	h := &ReindexHandler{
+		DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
+			<-ctx.Done()
+			return 0, 0, "", ctx.Err()
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, _, err := h.Handle(ctx, nil, ReindexArgs{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for cancelled reindex")
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "cancelled") {
+		t.Errorf("expected a cancellation message, got: %s", text)
+	}
+}
+
+func Test_ReindexHandler_StructuredOutput(t *testing.T) {
+	h := &ReindexHandler{
+		DoReindex: func(ctx context.Context, onProgress func(index.ProgressState)) (int, int64, string, error) {
+			return 42, 1024 * 1024, "1.5s", nil
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	_, output, err := h.Handle(context.Background(), nil, ReindexArgs{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{"files": 42, "sizeBytes": 1048576, "elapsed": "1.5s"}`)
+}
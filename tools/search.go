@@ -2,31 +2,80 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // SearchArgs defines the input parameters for the codeindex_search tool.
 type SearchArgs struct {
-	Query        string `json:"query" jsonschema:"Search query. Plain text for word match, quoted for exact phrase, /regex/ for regular expression"`
-	FilePath     string `json:"filePath,omitempty" jsonschema:"Exact relative file path to search in (overrides fileGlob). Use this to search within a single specific file"`
-	FileGlob     string `json:"fileGlob,omitempty" jsonschema:"Optional glob pattern to filter files (e.g. **/*.go)"`
-	MaxResults   int    `json:"maxResults,omitempty" jsonschema:"Maximum number of file results to return (default 50)"`
-	ContextLines int    `json:"contextLines,omitempty" jsonschema:"Number of context lines before and after each match (default 2)"`
+	Query         string `json:"query" jsonschema:"Search query. Plain text for word match, quoted for exact phrase, /regex/ for regular expression; combine with AND, OR, NOT or -term and parentheses, and filter with lang:, path:, file:, sym: and case:yes"`
+	FilePath      string `json:"filePath,omitempty" jsonschema:"Exact relative file path to search in (overrides fileGlob). Use this to search within a single specific file"`
+	FileGlob      string `json:"fileGlob,omitempty" jsonschema:"Optional glob pattern to filter files (e.g. **/*.go)"`
+	MaxResults    int    `json:"maxResults,omitempty" jsonschema:"Maximum number of file results to return (default 50)"`
+	ContextLines  int    `json:"contextLines,omitempty" jsonschema:"Number of context lines before and after each match (default 2)"`
+	Root          string `json:"root,omitempty" jsonschema:"Only search files of this workspace root; filePath and fileGlob are then relative to the root"`
+	CaseSensitive bool   `json:"caseSensitive,omitempty" jsonschema:"Match words, phrases and symbol names with their case (default false; case:yes or case:no in the query override it). Regexes are always matched as written"`
+	WholeWord     bool   `json:"wholeWord,omitempty" jsonschema:"Only match whole words: id does not match valid or id_token (default false)"`
+	Terms         string `json:"terms,omitempty" jsonschema:"How the words of plain text must occur: any (files and lines with any word, default), all (files with every word) or line (lines with every word)"`
+	Sort          string `json:"sort,omitempty" jsonschema:"Result order: relevance (default; definitions, file name matches, short and recent files first, tests and generated code last), path or recent (last modified first)"`
+	PreferTests   bool   `json:"preferTests,omitempty" jsonschema:"Rank test files above other files instead of below them (default false)"`
+}
+
+// SearchOutput is the structured result of the codeindex_search tool.
+type SearchOutput struct {
+	TotalMatches int          `json:"totalMatches" jsonschema:"Number of matching lines in all returned files"`
+	Files        []SearchFile `json:"files" jsonschema:"Matching files in result order"`
+}
+
+// SearchFile is a file with matches in SearchOutput.
+type SearchFile struct {
+	Path      string        `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	Language  string        `json:"language,omitempty" jsonschema:"Detected programming language"`
+	SizeBytes int64         `json:"sizeBytes,omitempty" jsonschema:"File size in bytes"`
+	LineCount int           `json:"lineCount,omitempty" jsonschema:"Number of lines in the file"`
+	Terms     []string      `json:"terms" jsonschema:"Distinct query terms found in the file, in query order"`
+	Matches   []SearchMatch `json:"matches" jsonschema:"Matching lines in line order"`
+}
+
+// SearchMatch is a matching line in SearchFile.
+type SearchMatch struct {
+	Line          int         `json:"line" jsonschema:"Line number (1-based)"`
+	Column        int         `json:"column" jsonschema:"Byte column of the first match on the line (1-based)"`
+	Text          string      `json:"text" jsonschema:"The full line"`
+	Spans         []MatchSpan `json:"spans" jsonschema:"Matched byte ranges within text"`
+	Terms         []string    `json:"terms" jsonschema:"Query terms found on the line: words, \"phrases\", /regexes/ and sym:names"`
+	ContextBefore []string    `json:"contextBefore,omitempty" jsonschema:"Lines before the match"`
+	ContextAfter  []string    `json:"contextAfter,omitempty" jsonschema:"Lines after the match"`
+}
+
+// MatchSpan is a matched byte range [start, end) within a line, 0-based.
+type MatchSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 // SearchHandler holds the dependencies for the search tool.
 type SearchHandler struct {
 	ContentIndex *index.ContentIndex
+	FileIndex    *index.FileIndex // file metadata of the structured results; may be nil
+	SymbolIndex  *symbols.Index   // definitions for sym: terms; may be nil
+	Workspace    *workspace.Workspace
 	Logger       *slog.Logger
 }
 
 // Handle processes a codeindex_search request.
-func (h *SearchHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args SearchArgs) (*mcp.CallToolResult, any, error) {
+func (h *SearchHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args SearchArgs) (*mcp.CallToolResult, *SearchOutput, error) {
 	start := time.Now()
 
 	if args.Query == "" {
@@ -37,18 +86,81 @@ func (h *SearchHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, ar
 		}, nil, nil
 	}
 
+	root, err := resolveRoot(h.Workspace, args.Root)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	// A root filter scopes the path filters to the root's files
+	filePath := args.FilePath
+	fileGlob := args.FileGlob
+	if root.Prefix != "" {
+		if filePath != "" {
+			filePath = root.IndexPath(filePath)
+		} else if fileGlob != "" {
+			fileGlob = root.IndexPath(fileGlob)
+		} else {
+			fileGlob = root.IndexPath("**")
+		}
+	}
+
 	contextLines := args.ContextLines
 	if contextLines == 0 {
 		contextLines = 2
 	}
 
-	results, totalMatches, err := h.ContentIndex.Search(index.SearchOptions{
-		Query:        args.Query,
-		FilePath:     args.FilePath,
-		FileGlob:     args.FileGlob,
-		MaxResults:   args.MaxResults,
-		ContextLines: contextLines,
-	})
+	options := index.SearchOptions{
+		Query:         args.Query,
+		FilePath:      filePath,
+		FileGlob:      fileGlob,
+		MaxResults:    args.MaxResults,
+		ContextLines:  contextLines,
+		CaseSensitive: args.CaseSensitive,
+		WholeWord:     args.WholeWord,
+		Terms:         index.TermMode(args.Terms),
+		Sort:          index.SortOrder(args.Sort),
+		PreferTests:   args.PreferTests,
+	}
+	if h.SymbolIndex != nil {
+		options.SymbolDefinitions = h.symbolDefinitions
+		options.FileDefinitions = h.fileDefinitions
+	}
+	if h.FileIndex != nil {
+		options.ModTime = h.modTime
+	}
+	// Report the candidate files checked so far to clients that passed a progress token.
+	// The search holds the index lock, so it only records progress and a poller sends it.
+	if reporter := newProgressReporter(req); reporter != nil {
+		var checked, candidates atomic.Int64
+		options.Progress = func(checkedFiles int, candidateFiles int) {
+			checked.Store(int64(checkedFiles))
+			candidates.Store(int64(candidateFiles))
+		}
+		stop := reporter.poll(ctx, func() (int, int, string) {
+			return int(checked.Load()), int(candidates.Load()), "searching candidate files"
+		})
+		defer stop()
+	}
+
+	results, totalMatches, err := h.ContentIndex.Search(ctx, options)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		h.Logger.Info("codeindex_search cancelled", "query", args.Query, "error", err)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Search cancelled"}},
+			IsError: true,
+		}, nil, nil
+	}
+	var queryErr *index.QueryError
+	if errors.As(err, &queryErr) {
+		h.Logger.Warn("codeindex_search invalid query", "query", args.Query, "error", err)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: formatQueryError(queryErr)}},
+			IsError: true,
+		}, nil, nil
+	}
 	if err != nil {
 		h.Logger.Error("codeindex_search failed", "query", args.Query, "error", err)
 		return &mcp.CallToolResult{
@@ -62,6 +174,12 @@ func (h *SearchHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, ar
 		"query", args.Query,
 		"filePath", args.FilePath,
 		"fileGlob", args.FileGlob,
+		"root", args.Root,
+		"caseSensitive", args.CaseSensitive,
+		"wholeWord", args.WholeWord,
+		"terms", args.Terms,
+		"sort", args.Sort,
+		"preferTests", args.PreferTests,
 		"files", len(results),
 		"matches", totalMatches,
 		"elapsed", elapsed,
@@ -71,5 +189,82 @@ func (h *SearchHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, ar
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: output}},
-	}, nil, nil
+	}, newSearchOutput(results, totalMatches, h.FileIndex), nil
+}
+
+// symbolDefinitions returns the lines defining symbols named name, keyed by relative path.
+func (h *SearchHandler) symbolDefinitions(name string) map[string][]int {
+	definitions, _ := h.SymbolIndex.Lookup(symbols.LookupOptions{Query: name, Mode: symbols.MatchExact, MaxResults: math.MaxInt})
+	lines := make(map[string][]int)
+	for _, symbol := range definitions {
+		lines[symbol.RelativePath] = append(lines[symbol.RelativePath], symbol.StartLine)
+	}
+	return lines
+}
+
+// fileDefinitions returns the names of the symbols defined in a file by their first line.
+func (h *SearchHandler) fileDefinitions(relativePath string) map[int][]string {
+	names := make(map[int][]string)
+	for _, symbol := range h.SymbolIndex.FileSymbols(relativePath) {
+		names[symbol.StartLine] = append(names[symbol.StartLine], symbol.Name)
+	}
+	return names
+}
+
+// modTime returns the modification time of an indexed file, or the zero time if it is unknown.
+func (h *SearchHandler) modTime(relativePath string) time.Time {
+	if file := h.FileIndex.GetFile(relativePath); file != nil {
+		return file.ModTime
+	}
+	return time.Time{}
+}
+
+// formatQueryError describes a malformed query, pointing at the column of the error:
+//
+//	Invalid query: unterminated phrase at column 5
+//	  foo "bar
+//	      ^
+func formatQueryError(queryErr *index.QueryError) string {
+	text := fmt.Sprintf("Invalid query: %v", queryErr)
+	if queryErr.Column < 1 || queryErr.Column > len(queryErr.Query)+1 || strings.ContainsAny(queryErr.Query, "\n\r") {
+		return text
+	}
+	indent := utf8.RuneCountInString(queryErr.Query[:queryErr.Column-1])
+	return fmt.Sprintf("%s\n  %s\n  %s^", text, queryErr.Query, strings.Repeat(" ", indent))
+}
+
+// newSearchOutput converts search results to the structured output, adding the metadata
+// of each file from fileIndex if it is not nil.
+func newSearchOutput(results []index.ContentSearchResult, totalMatches int, fileIndex *index.FileIndex) *SearchOutput {
+	output := &SearchOutput{TotalMatches: totalMatches, Files: make([]SearchFile, 0, len(results))}
+	for _, result := range results {
+		file := SearchFile{Path: result.RelativePath, Terms: result.Terms, Matches: make([]SearchMatch, 0, len(result.Matches))}
+		if fileIndex != nil {
+			if indexed := fileIndex.GetFile(result.RelativePath); indexed != nil {
+				file.Language = indexed.Language
+				file.SizeBytes = indexed.SizeBytes
+				file.LineCount = indexed.LineCount
+			}
+		}
+		for _, match := range result.Matches {
+			searchMatch := SearchMatch{
+				Line:          match.LineNumber,
+				Column:        1,
+				Text:          match.LineText,
+				Spans:         make([]MatchSpan, len(match.Spans)),
+				Terms:         match.Terms,
+				ContextBefore: match.ContextBefore,
+				ContextAfter:  match.ContextAfter,
+			}
+			for i, span := range match.Spans {
+				searchMatch.Spans[i] = MatchSpan{Start: span.Start, End: span.End}
+			}
+			if len(match.Spans) > 0 {
+				searchMatch.Column = match.Spans[0].Start + 1
+			}
+			file.Matches = append(file.Matches, searchMatch)
+		}
+		output.Files = append(output.Files, file)
+	}
+	return output
 }
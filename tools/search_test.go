@@ -1,13 +1,18 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/symbols"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -25,6 +30,24 @@ func newTestSearchHandler(t *testing.T) *SearchHandler {
 	}
 }
 
+// assertJSON fails the test unless value marshals to the JSON document want. The expected
+// documents spell out the field names of the structured tool results, which are part of
+// the tool contract and must stay stable.
+func assertJSON(t *testing.T, value any, want string) {
+	t.Helper()
+	got, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(want)); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if string(got) != compact.String() {
+		t.Errorf("structured output mismatch:\ngot:  %s\nwant: %s", got, compact.String())
+	}
+}
+
 func Test_SearchHandler_EmptyQuery(t *testing.T) {
 	h := newTestSearchHandler(t)
 
@@ -83,3 +106,198 @@ func Test_SearchHandler_NoResults(t *testing.T) {
 		t.Errorf("expected 'No matches found', got:\n%s", text)
 	}
 }
+
+func Test_SearchHandler_InvalidRegex(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	h.ContentIndex.IndexFile("main.go", "package main\n", "Go")
+
+	result, _, err := h.Handle(context.Background(), nil, SearchArgs{Query: "/[a-/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for invalid regex")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "Invalid query") || !strings.Contains(text, "invalid regex") {
+		t.Errorf("expected invalid regex message, got: %s", text)
+	}
+}
+
+func Test_SearchHandler_QuerySyntaxError(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	h.ContentIndex.IndexFile("main.go", "package main\n", "Go")
+
+	result, _, err := h.Handle(context.Background(), nil, SearchArgs{Query: `foo "bar`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for an unterminated phrase")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	want := "Invalid query: unterminated phrase at column 5\n  foo \"bar\n      ^"
+	if text != want {
+		t.Errorf("expected the error to point at the quote, got:\n%s", text)
+	}
+}
+
+func Test_SearchHandler_SymbolQualifier(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	content := "package main\n\nfunc Serve() {}\n\nfunc main() {\n\tServe()\n}\n"
+	h.ContentIndex.IndexFile("main.go", content, "Go")
+	h.SymbolIndex = symbols.NewIndex()
+	h.SymbolIndex.SetFile("main.go", symbols.Extract("main.go", content, "Go"))
+
+	result, output, err := h.Handle(context.Background(), nil, SearchArgs{Query: "sym:serve", ContextLines: -1})
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %+v", err, result)
+	}
+	if output.TotalMatches != 1 || output.Files[0].Matches[0].Line != 3 {
+		t.Errorf("expected the definition on line 3 only, got %+v", output)
+	}
+}
+
+func Test_SearchHandler_CaseSensitiveWholeWord(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	h.ContentIndex.IndexFile("a.go", "var id = ID\nvalid := Id\n", "Go")
+
+	_, output, err := h.Handle(context.Background(), nil, SearchArgs{Query: "id", CaseSensitive: true, WholeWord: true, ContextLines: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{"totalMatches": 1, "files": [{"path": "a.go", "terms": ["id"], "matches": [
+		{"line": 1, "column": 5, "text": "var id = ID", "spans": [{"start": 4, "end": 6}], "terms": ["id"]}
+	]}]}`)
+}
+
+func Test_SearchHandler_RootFilter(t *testing.T) {
+	h := newTestSearchHandler(t)
+	h.Workspace = newTestWorkspace(t)
+	h.ContentIndex.IndexFile("api/handler.go", "package api\n\nfunc handleOrder() {}\n", "Go")
+	h.ContentIndex.IndexFile("web/order.ts", "export function handleOrder() {}\n", "TypeScript")
+
+	result, _, err := h.Handle(context.Background(), nil, SearchArgs{Query: "handleOrder", Root: "web"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "web/order.ts") || strings.Contains(text, "api/handler.go") {
+		t.Errorf("expected only web/order.ts, got:\n%s", text)
+	}
+
+	// fileGlob is relative to the root
+	result, _, _ = h.Handle(context.Background(), nil, SearchArgs{Query: "handleOrder", Root: "api", FileGlob: "*.go"})
+	text = result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "api/handler.go") {
+		t.Errorf("expected api/handler.go, got:\n%s", text)
+	}
+}
+
+func Test_SearchHandler_RootFilter_ManyMatchesInOtherRoot(t *testing.T) {
+	h := newTestSearchHandler(t)
+	h.Workspace = newTestWorkspace(t)
+
+	// Files in api score higher than the one in web and outnumber the Bleve candidate pool
+	docs := make([]index.Document, 0, 601)
+	for i := range 600 {
+		docs = append(docs, index.Document{RelativePath: fmt.Sprintf("api/h%03d.go", i), Content: "handleOrder handleOrder\n", Language: "Go"})
+	}
+	docs = append(docs, index.Document{RelativePath: "web/order.ts", Content: "export function handleOrder() {}\n// more text to lower its score\n", Language: "TypeScript"})
+	if err := h.ContentIndex.IndexBatch(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range []SearchArgs{
+		{Query: "handleOrder", Root: "web"},
+		{Query: "handleOrder", Root: "web", FileGlob: "*.ts"},
+		{Query: "handleOrder", FilePath: "web/order.ts"},
+		{Query: "handleOrder", FileGlob: "web/**"},
+	} {
+		_, output, err := h.Handle(context.Background(), nil, args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output == nil || len(output.Files) != 1 || output.Files[0].Path != "web/order.ts" {
+			t.Errorf("%+v: expected web/order.ts, got %+v", args, output)
+		}
+	}
+}
+
+func Test_SearchHandler_StructuredOutput(t *testing.T) {
+	h := newTestSearchHandler(t)
+	h.FileIndex = index.NewFileIndex()
+	content := "package main\n\nfunc main() {\n\tfmt.Println(\"hello world\")\n}\n"
+	h.ContentIndex.IndexFile("main.go", content, "Go")
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "main.go", Language: "Go", SizeBytes: 58, LineCount: 6})
+
+	_, output, err := h.Handle(context.Background(), nil, SearchArgs{Query: "hello fmt", ContextLines: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{
+		"totalMatches": 1,
+		"files": [{
+			"path": "main.go",
+			"language": "Go",
+			"sizeBytes": 58,
+			"lineCount": 6,
+			"terms": ["hello", "fmt"],
+			"matches": [{
+				"line": 4,
+				"column": 2,
+				"text": "\tfmt.Println(\"hello world\")",
+				"spans": [{"start": 1, "end": 4}, {"start": 14, "end": 19}],
+				"terms": ["hello", "fmt"],
+				"contextBefore": ["func main() {"],
+				"contextAfter": ["}"]
+			}]
+		}]
+	}`)
+}
+
+func Test_SearchHandler_Sort(t *testing.T) {
+	h := newTestSearchHandler(t)
+	h.FileIndex = index.NewFileIndex()
+	h.SymbolIndex = symbols.NewIndex()
+	now := time.Now()
+	for i, path := range []string{"a.go", "b.go"} {
+		content := "package main\n\nfunc Serve() {}\n"
+		if path == "a.go" {
+			content = "package main\n\nfunc main() { Serve() }\n"
+		}
+		h.ContentIndex.IndexFile(path, content, "Go")
+		h.SymbolIndex.SetFile(path, symbols.Extract(path, content, "Go"))
+		h.FileIndex.AddFile(&index.IndexedFile{RelativePath: path, Language: "Go", ModTime: now.Add(-time.Duration(i) * time.Hour)})
+	}
+
+	order := func(sort string) string {
+		result, output, err := h.Handle(context.Background(), nil, SearchArgs{Query: "serve", Sort: sort})
+		if err != nil || result.IsError {
+			t.Fatalf("%s: unexpected error: %v %+v", sort, err, result)
+		}
+		var paths []string
+		for _, file := range output.Files {
+			paths = append(paths, file.Path)
+		}
+		return strings.Join(paths, " ")
+	}
+	// b.go defines Serve, a.go was modified more recently
+	if got := order(""); got != "b.go a.go" {
+		t.Errorf("relevance: expected the definition first, got %s", got)
+	}
+	if got := order("recent"); got != "a.go b.go" {
+		t.Errorf("recent: expected the last modified file first, got %s", got)
+	}
+
+	result, _, _ := h.Handle(context.Background(), nil, SearchArgs{Query: "serve", Sort: "size"})
+	if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "unknown sort order") {
+		t.Errorf("expected an error for an unknown sort order, got %+v", result)
+	}
+}
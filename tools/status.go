@@ -10,29 +10,53 @@ import (
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // StatusArgs defines the input parameters for the codeindex_status tool (none required).
 type StatusArgs struct{}
 
+// StatusOutput is the structured result of the codeindex_status tool.
+type StatusOutput struct {
+	Roots         []RootStatus   `json:"roots" jsonschema:"Workspace roots with their indexed files"`
+	UptimeSeconds int            `json:"uptimeSeconds"`
+	Phase         string         `json:"phase" jsonschema:"indexing, syncing or ready"`
+	Processed     int            `json:"processed" jsonschema:"Files read by the startup indexing so far"`
+	Discovered    int            `json:"discovered" jsonschema:"Files found by the startup indexing so far"`
+	Files         int            `json:"files" jsonschema:"Number of indexed files"`
+	SizeBytes     int64          `json:"sizeBytes" jsonschema:"Total size of the indexed files"`
+	MemoryBytes   uint64         `json:"memoryBytes" jsonschema:"Heap memory in use by the server"`
+	Languages     map[string]int `json:"languages,omitempty" jsonschema:"Number of indexed files per language"`
+}
+
+// RootStatus is a workspace root in StatusOutput.
+type RootStatus struct {
+	Name      string `json:"name" jsonschema:"Root name, the path prefix of its files in a multi-root workspace"`
+	Dir       string `json:"dir" jsonschema:"Absolute directory"`
+	Files     int    `json:"files"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
 // StatusHandler holds the dependencies for the status tool.
 type StatusHandler struct {
 	FileIndex    *index.FileIndex
 	ContentIndex *index.ContentIndex
 	StartTime    time.Time
-	RootDir      string
+	Progress     *index.Progress // startup indexing progress; nil reports the index as ready
+	Workspace    *workspace.Workspace
 	Logger       *slog.Logger
 }
 
 // Handle processes a codeindex_status request.
-func (h *StatusHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args StatusArgs) (*mcp.CallToolResult, any, error) {
+func (h *StatusHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args StatusArgs) (*mcp.CallToolResult, *StatusOutput, error) {
 	var builder strings.Builder
 
 	fileCount := h.FileIndex.FileCount()
 	totalSize := h.FileIndex.TotalSizeBytes()
 	langCounts := h.FileIndex.LanguageCounts()
 	uptime := time.Since(h.StartTime)
+	progress := h.Progress.State()
 
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
@@ -42,10 +66,42 @@ func (h *StatusHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, ar
 		"totalSize", totalSize,
 		"memory", memStats.Alloc,
 		"uptime", uptime,
+		"phase", progress.Phase,
 	)
 
-	builder.WriteString(fmt.Sprintf("root: %s\n", h.RootDir))
+	structured := &StatusOutput{
+		UptimeSeconds: int(uptime.Seconds()),
+		Phase:         string(progress.Phase),
+		Processed:     progress.Processed,
+		Discovered:    progress.Discovered,
+		Files:         fileCount,
+		SizeBytes:     totalSize,
+		MemoryBytes:   memStats.Alloc,
+		Languages:     langCounts,
+	}
+
+	if h.Workspace.IsMulti() {
+		rootFiles := make(map[string]int)
+		rootSizes := make(map[string]int64)
+		for _, file := range h.FileIndex.AllFiles() {
+			if root, ok := h.Workspace.RootOf(file.RelativePath); ok {
+				rootFiles[root.Name]++
+				rootSizes[root.Name] += file.SizeBytes
+			}
+		}
+		builder.WriteString("roots:\n")
+		for _, root := range h.Workspace.Roots {
+			builder.WriteString(fmt.Sprintf("  %s: %s (%d files, %s)\n", root.Name, root.Dir, rootFiles[root.Name], formatFileSize(rootSizes[root.Name])))
+			structured.Roots = append(structured.Roots, RootStatus{Name: root.Name, Dir: root.Dir, Files: rootFiles[root.Name], SizeBytes: rootSizes[root.Name]})
+		}
+	} else {
+		builder.WriteString(fmt.Sprintf("root: %s\n", h.Workspace.Roots[0].Dir))
+		structured.Roots = []RootStatus{{Name: h.Workspace.Roots[0].Name, Dir: h.Workspace.Roots[0].Dir, Files: fileCount, SizeBytes: totalSize}}
+	}
 	builder.WriteString(fmt.Sprintf("uptime: %s\n", formatDuration(uptime)))
+	if h.Progress != nil {
+		builder.WriteString(fmt.Sprintf("index: %s\n", formatProgress(progress)))
+	}
 	builder.WriteString(fmt.Sprintf("files: %d (%s)\n", fileCount, formatFileSize(totalSize)))
 	builder.WriteString(fmt.Sprintf("memory: %s\n", formatFileSize(int64(memStats.Alloc))))
 
@@ -71,7 +127,7 @@ func (h *StatusHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, ar
 
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: builder.String()}},
-	}, nil, nil
+	}, structured, nil
 }
 
 // formatDuration formats a duration in a human-readable way.
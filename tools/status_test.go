@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/lexandro/codeindex-mcp/index"
+	"github.com/lexandro/codeindex-mcp/workspace"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -53,7 +54,7 @@ func newTestStatusHandler(t *testing.T) *StatusHandler {
 		FileIndex:    index.NewFileIndex(),
 		ContentIndex: ci,
 		StartTime:    time.Now(),
-		RootDir:      "/test/project",
+		Workspace:    &workspace.Workspace{Roots: []workspace.Root{{Name: "project", Dir: "/test/project"}}},
 		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
 }
@@ -91,3 +92,69 @@ func Test_StatusHandler_Handle(t *testing.T) {
 		}
 	}
 }
+
+func Test_StatusHandler_MultiRootCounts(t *testing.T) {
+	h := newTestStatusHandler(t)
+	h.Workspace = newTestWorkspace(t)
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "api/main.go", Language: "Go", SizeBytes: 100})
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "api/util.go", Language: "Go", SizeBytes: 100})
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "web/app.ts", Language: "TypeScript", SizeBytes: 50})
+
+	result, _, err := h.Handle(context.Background(), nil, StatusArgs{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	for _, check := range []string{"roots:", "api: /src/api (2 files", "web: /src/web (1 files", "files: 3"} {
+		if !strings.Contains(text, check) {
+			t.Errorf("expected output to contain %q, got:\n%s", check, text)
+		}
+	}
+}
+
+func Test_StatusHandler_IndexingProgress(t *testing.T) {
+	h := newTestStatusHandler(t)
+	h.Progress = index.NewProgress(index.PhaseIndexing)
+	h.Progress.AddDiscovered(40)
+	h.Progress.AddProcessed(25)
+
+	result, _, _ := h.Handle(context.Background(), nil, StatusArgs{})
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "index: indexing in progress: 25/40 files") {
+		t.Errorf("expected indexing progress, got:\n%s", text)
+	}
+
+	h.Progress.Finish()
+	result, _, _ = h.Handle(context.Background(), nil, StatusArgs{})
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "index: ready (built in") {
+		t.Errorf("expected ready phase, got:\n%s", text)
+	}
+}
+
+func Test_StatusHandler_StructuredOutput(t *testing.T) {
+	h := newTestStatusHandler(t)
+	h.Progress = index.NewProgress(index.PhaseIndexing)
+	h.Progress.AddDiscovered(2)
+	h.Progress.AddProcessed(1)
+	h.FileIndex.AddFile(&index.IndexedFile{RelativePath: "main.go", Language: "Go", SizeBytes: 1024})
+
+	_, output, err := h.Handle(context.Background(), nil, StatusArgs{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.MemoryBytes == 0 {
+		t.Error("expected the memory in use")
+	}
+	output.MemoryBytes = 0 // varies between runs
+	assertJSON(t, output, `{
+		"roots": [{"name": "project", "dir": "/test/project", "files": 1, "sizeBytes": 1024}],
+		"uptimeSeconds": 0,
+		"phase": "indexing",
+		"processed": 1,
+		"discovered": 2,
+		"files": 1,
+		"sizeBytes": 1024,
+		"memoryBytes": 0,
+		"languages": {"Go": 1}
+	}`)
+}
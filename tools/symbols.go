@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SymbolsArgs defines the input parameters for the codeindex_symbols tool.
+type SymbolsArgs struct {
+	Query      string `json:"query" jsonschema:"Symbol name to look up (e.g. NewServer or handleRequest)"`
+	Mode       string `json:"mode,omitempty" jsonschema:"Name matching: exact (default), prefix or fuzzy (subsequence, e.g. gubi matches getUserByID)"`
+	Kind       string `json:"kind,omitempty" jsonschema:"Comma-separated kinds to include: function, method, class, struct, interface, type, enum, constant, variable, module"`
+	Language   string `json:"language,omitempty" jsonschema:"Only return symbols from files of this language (e.g. Go, TypeScript)"`
+	MaxResults int    `json:"maxResults,omitempty" jsonschema:"Maximum number of results to return (default 50)"`
+}
+
+// SymbolsOutput is the structured result of the codeindex_symbols tool.
+type SymbolsOutput struct {
+	Symbols []SymbolInfo `json:"symbols" jsonschema:"Matching definitions, best matches first"`
+}
+
+// SymbolInfo is a symbol definition.
+type SymbolInfo struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind" jsonschema:"Symbol kind, e.g. function, method, struct"`
+	Container string `json:"container,omitempty" jsonschema:"Enclosing type or class"`
+	Path      string `json:"path" jsonschema:"Relative file path (forward slashes)"`
+	Language  string `json:"language" jsonschema:"Programming language of the file"`
+	StartLine int    `json:"startLine" jsonschema:"First line (1-based, inclusive)"`
+	EndLine   int    `json:"endLine" jsonschema:"Last line (1-based, inclusive)"`
+	Signature string `json:"signature,omitempty" jsonschema:"Declaration header on one line"`
+}
+
+// SymbolsHandler holds the dependencies for the symbols tool.
+type SymbolsHandler struct {
+	SymbolIndex *symbols.Index
+	Logger      *slog.Logger
+}
+
+// Handle processes a codeindex_symbols request.
+func (h *SymbolsHandler) Handle(ctx context.Context, req *mcp.CallToolRequest, args SymbolsArgs) (*mcp.CallToolResult, *SymbolsOutput, error) {
+	start := time.Now()
+
+	if args.Query == "" {
+		h.Logger.Warn("codeindex_symbols called with empty query")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: query parameter is required"}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	var kinds []symbols.Kind
+	if args.Kind != "" {
+		for _, kindName := range strings.Split(args.Kind, ",") {
+			kind, ok := symbols.ParseKind(kindName)
+			if !ok {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: unknown kind %q", strings.TrimSpace(kindName))}},
+					IsError: true,
+				}, nil, nil
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	results, err := h.SymbolIndex.Lookup(symbols.LookupOptions{
+		Query:      args.Query,
+		Mode:       symbols.MatchMode(strings.ToLower(args.Mode)),
+		Kinds:      kinds,
+		Language:   args.Language,
+		MaxResults: args.MaxResults,
+	})
+	if err != nil {
+		h.Logger.Warn("codeindex_symbols failed", "query", args.Query, "error", err)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil, nil
+	}
+
+	elapsed := time.Since(start)
+	h.Logger.Info("codeindex_symbols",
+		"query", args.Query,
+		"mode", args.Mode,
+		"kind", args.Kind,
+		"language", args.Language,
+		"results", len(results),
+		"elapsed", elapsed,
+	)
+
+	output := FormatSymbolResults(results)
+
+	structured := &SymbolsOutput{Symbols: make([]SymbolInfo, len(results))}
+	for i, symbol := range results {
+		structured.Symbols[i] = SymbolInfo{
+			Name:      symbol.Name,
+			Kind:      string(symbol.Kind),
+			Container: symbol.Container,
+			Path:      symbol.RelativePath,
+			Language:  symbol.Language,
+			StartLine: symbol.StartLine,
+			EndLine:   symbol.EndLine,
+			Signature: symbol.Signature,
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: output}},
+	}, structured, nil
+}
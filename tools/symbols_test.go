@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/symbols"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newTestSymbolsHandler(t *testing.T) *SymbolsHandler {
+	t.Helper()
+	symbolIndex := symbols.NewIndex()
+	symbolIndex.SetFile("server.go", symbols.Extract("server.go",
+		"package server\n\ntype Server struct{}\n\nfunc (s *Server) Start() error {\n\treturn nil\n}\n", "Go"))
+
+	return &SymbolsHandler{
+		SymbolIndex: symbolIndex,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func Test_SymbolsHandler_EmptyQuery(t *testing.T) {
+	h := newTestSymbolsHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, SymbolsArgs{Query: ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty query")
+	}
+}
+
+func Test_SymbolsHandler_Lookup(t *testing.T) {
+	h := newTestSymbolsHandler(t)
+
+	result, _, err := h.Handle(context.Background(), nil, SymbolsArgs{Query: "start"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected success, got error result")
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "server.go:5-7 method Server.Start") {
+		t.Errorf("expected method location in output, got:\n%s", text)
+	}
+}
+
+func Test_SymbolsHandler_UnknownKind(t *testing.T) {
+	h := newTestSymbolsHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, SymbolsArgs{Query: "Server", Kind: "widget"})
+	if !result.IsError {
+		t.Fatal("expected IsError=true for unknown kind")
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "unknown kind") {
+		t.Errorf("expected unknown kind message, got: %s", text)
+	}
+}
+
+func Test_SymbolsHandler_NoResults(t *testing.T) {
+	h := newTestSymbolsHandler(t)
+
+	result, _, _ := h.Handle(context.Background(), nil, SymbolsArgs{Query: "Missing"})
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "No symbols found") {
+		t.Errorf("expected 'No symbols found', got: %s", text)
+	}
+}
+
+func Test_SymbolsHandler_StructuredOutput(t *testing.T) {
+	h := newTestSymbolsHandler(t)
+
+	_, output, err := h.Handle(context.Background(), nil, SymbolsArgs{Query: "start"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertJSON(t, output, `{
+		"symbols": [{
+			"name": "Start",
+			"kind": "method",
+			"container": "Server",
+			"path": "server.go",
+			"language": "Go",
+			"startLine": 5,
+			"endLine": 7,
+			"signature": "func (s *Server) Start() error"
+		}]
+	}`)
+}
@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lexandro/codeindex-mcp/workspace"
+)
+
+// resolveRoot looks up the root filter of a request. An empty name selects all roots and
+// returns a root with an empty prefix, so paths and globs pass through unchanged.
+func resolveRoot(ws *workspace.Workspace, name string) (workspace.Root, error) {
+	if name == "" {
+		return workspace.Root{}, nil
+	}
+	if ws != nil {
+		if root, ok := ws.Root(name); ok {
+			return root, nil
+		}
+	}
+	var available []string
+	if ws != nil {
+		available = ws.Names()
+	}
+	return workspace.Root{}, fmt.Errorf("unknown root %q (available: %s)", name, strings.Join(available, ", "))
+}
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lexandro/codeindex-mcp/workspace"
+)
+
+// newTestWorkspace returns a workspace with the roots "api" and "web".
+func newTestWorkspace(t *testing.T) *workspace.Workspace {
+	t.Helper()
+	ws, err := workspace.New([]workspace.Root{{Name: "api", Dir: "/src/api"}, {Name: "web", Dir: "/src/web"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func Test_resolveRoot(t *testing.T) {
+	ws := newTestWorkspace(t)
+
+	root, err := resolveRoot(ws, "")
+	if err != nil || root.Prefix != "" {
+		t.Errorf("expected empty filter to select all roots, got %+v, %v", root, err)
+	}
+
+	root, err = resolveRoot(ws, "web")
+	if err != nil || root.Prefix != "web/" {
+		t.Errorf("expected web root, got %+v, %v", root, err)
+	}
+
+	_, err = resolveRoot(ws, "docs")
+	if err == nil || !strings.Contains(err.Error(), "api, web") {
+		t.Errorf("expected unknown root error listing the roots, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package watcher
+
+import "os"
+
+// fileID returns 0: the file index is not part of the FileInfo returned by a directory
+// listing on Windows, and opening every file on each scan would be too slow.
+func fileID(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,136 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileState is what the poller remembers about a file between scans.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	fileID  uint64 // inode where available, 0 otherwise
+}
+
+// changed reports whether a file was modified or replaced between two scans.
+func (s fileState) changed(other fileState) bool {
+	return !s.modTime.Equal(other.modTime) || s.size != other.size || s.fileID != other.fileID
+}
+
+// poller detects changes by periodically scanning directory trees and comparing file
+// metadata. It covers the subtrees fsnotify cannot watch (network and container
+// filesystems, or when the watch limit is reached).
+type poller struct {
+	ignoreChecker IgnoreChecker
+	rootDir       string
+
+	mu    sync.Mutex
+	trees map[string]map[string]fileState // polled directory -> files below it from the last scan
+}
+
+func newPoller(rootDir string, ignoreChecker IgnoreChecker) *poller {
+	return &poller{
+		ignoreChecker: ignoreChecker,
+		rootDir:       rootDir,
+		trees:         make(map[string]map[string]fileState),
+	}
+}
+
+// addTree starts polling the subtree at dir. Files already present are recorded without
+// reporting them. Returns false if dir is already polled.
+func (p *poller) addTree(dir string) bool {
+	files := p.scanTree(dir)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.trees[dir]; ok {
+		return false
+	}
+	p.trees[dir] = files
+	return true
+}
+
+// hasTree reports whether dir is the top of a polled subtree.
+func (p *poller) hasTree(dir string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.trees[dir]
+	return ok
+}
+
+// removeTrees stops polling the subtrees at or below dir. Returns the number of subtrees removed.
+func (p *poller) removeTrees(dir string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	removed := 0
+	for tree := range p.trees {
+		if isWithin(tree, dir) {
+			delete(p.trees, tree)
+			removed++
+		}
+	}
+	return removed
+}
+
+// poll scans every polled subtree once and calls emit for each created, modified or removed file.
+func (p *poller) poll(emit func(path string, op EventOp)) {
+	p.mu.Lock()
+	dirs := make([]string, 0, len(p.trees))
+	for dir := range p.trees {
+		dirs = append(dirs, dir)
+	}
+	p.mu.Unlock()
+
+	for _, dir := range dirs {
+		files := p.scanTree(dir)
+
+		p.mu.Lock()
+		previous, ok := p.trees[dir]
+		if ok {
+			p.trees[dir] = files
+		}
+		p.mu.Unlock()
+		if !ok {
+			continue // removed while scanning
+		}
+
+		for path, state := range files {
+			if old, existed := previous[path]; !existed {
+				emit(path, OpCreate)
+			} else if state.changed(old) {
+				emit(path, OpWrite)
+			}
+		}
+		for path := range previous {
+			if _, exists := files[path]; !exists {
+				emit(path, OpRemove)
+			}
+		}
+	}
+}
+
+// scanTree records the files below dir, skipping ignored directories. Ignore rules for
+// files are applied when a change is reported, so unchanged files cost only a stat.
+func (p *poller) scanTree(dir string) map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip entries that can't be read
+		}
+		if d.IsDir() {
+			if path != p.rootDir && p.ignoreChecker.ShouldIgnoreDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size(), fileID: fileID(info)}
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,143 @@
+package watcher
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// collectPolled runs one poll and returns the reported changes by path.
+func collectPolled(p *poller) map[string]EventOp {
+	changes := make(map[string]EventOp)
+	p.poll(func(path string, op EventOp) {
+		changes[path] = op
+	})
+	return changes
+}
+
+func Test_poller_DetectsChanges(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "src"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "generated"), 0755)
+	modified := filepath.Join(rootDir, "src", "modified.go")
+	replaced := filepath.Join(rootDir, "src", "replaced.go")
+	removed := filepath.Join(rootDir, "src", "removed.go")
+	for _, path := range []string{modified, replaced, removed} {
+		os.WriteFile(path, []byte("package src\n"), 0644)
+	}
+
+	checker := &fakeIgnoreChecker{ignored: map[string]bool{filepath.Join(rootDir, "generated"): true}}
+	p := newPoller(rootDir, checker)
+	if !p.addTree(rootDir) {
+		t.Fatal("expected the tree to be added")
+	}
+	if changes := collectPolled(p); len(changes) != 0 {
+		t.Fatalf("expected existing files not to be reported, got %v", changes)
+	}
+
+	created := filepath.Join(rootDir, "src", "created.go")
+	os.WriteFile(created, []byte("package src\n"), 0644)
+	os.WriteFile(modified, []byte("package src\n\nfunc F() {}\n"), 0644)
+	os.Remove(removed)
+	os.WriteFile(filepath.Join(rootDir, "generated", "types.go"), []byte("package generated\n"), 0644)
+
+	// An atomic save replaces the file with one of the same size and modification time
+	info, _ := os.Stat(replaced)
+	tmpPath := replaced + ".tmp"
+	os.WriteFile(tmpPath, []byte("package dst\n"), 0644)
+	os.Chtimes(tmpPath, info.ModTime(), info.ModTime())
+	os.Rename(tmpPath, replaced)
+
+	changes := collectPolled(p)
+	want := map[string]EventOp{created: OpCreate, modified: OpWrite, removed: OpRemove}
+	if fileID(info) != 0 {
+		want[replaced] = OpWrite
+	}
+	if len(changes) != len(want) {
+		t.Errorf("expected %d changes, got %v", len(want), changes)
+	}
+	for path, op := range want {
+		if changes[path] != op {
+			t.Errorf("expected op %v for %s, got %v", op, path, changes)
+		}
+	}
+}
+
+func Test_poller_RemoveTrees(t *testing.T) {
+	rootDir := t.TempDir()
+	p := newPoller(rootDir, &fakeIgnoreChecker{ignored: make(map[string]bool)})
+	p.addTree(filepath.Join(rootDir, "a"))
+	p.addTree(filepath.Join(rootDir, "a", "b"))
+	p.addTree(filepath.Join(rootDir, "c"))
+
+	if removed := p.removeTrees(filepath.Join(rootDir, "a")); removed != 2 {
+		t.Errorf("expected 2 trees removed, got %d", removed)
+	}
+	if !p.hasTree(filepath.Join(rootDir, "c")) {
+		t.Error("expected unrelated tree to stay polled")
+	}
+}
+
+func Test_Watcher_PollMode(t *testing.T) {
+	rootDir := t.TempDir()
+	w, err := NewWatcher(rootDir, &fakeIgnoreChecker{ignored: make(map[string]bool)}, Options{Mode: ModePoll, PollInterval: 20 * time.Millisecond}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.fsWatcher != nil {
+		t.Fatal("expected no fsnotify watcher in poll mode")
+	}
+	go w.Start()
+
+	os.MkdirAll(filepath.Join(rootDir, "pkg"), 0755)
+	path := filepath.Join(rootDir, "pkg", "main.go")
+	os.WriteFile(path, []byte("package pkg\n"), 0644)
+
+	batch := receiveEvents(t, w)
+	if len(batch) != 1 || batch[0].Path != path || batch[0].Op != OpCreate {
+		t.Errorf("expected a create event for %s, got %+v", path, batch)
+	}
+}
+
+func Test_Watcher_AutoModeFallsBackToPolling(t *testing.T) {
+	rootDir := t.TempDir()
+	w, err := NewWatcher(rootDir, &fakeIgnoreChecker{ignored: make(map[string]bool)}, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Adding a watch fails once the fsnotify watcher is closed, as it does at the watch limit
+	w.fsWatcher.Close()
+	mountDir := filepath.Join(rootDir, "mnt")
+	os.MkdirAll(filepath.Join(mountDir, "nested"), 0755)
+	w.watchSubtree(mountDir)
+
+	if !w.poller.hasTree(mountDir) {
+		t.Fatal("expected the unwatchable directory to be polled")
+	}
+	if w.poller.hasTree(filepath.Join(mountDir, "nested")) {
+		t.Error("expected subdirectories to be covered by the polled tree")
+	}
+
+	path := filepath.Join(mountDir, "nested", "file.go")
+	os.WriteFile(path, []byte("package nested\n"), 0644)
+	if changes := collectPolled(w.poller); changes[path] != OpCreate {
+		t.Errorf("expected the polled tree to report %s, got %v", path, changes)
+	}
+}
+
+func Test_ParseMode(t *testing.T) {
+	for _, name := range []string{"auto", "fsnotify", "Poll"} {
+		if _, err := ParseMode(name); err != nil {
+			t.Errorf("ParseMode(%q): unexpected error %v", name, err)
+		}
+	}
+	if _, err := ParseMode("inotify"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -1,9 +1,12 @@
 package watcher
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,53 +18,172 @@ type IgnoreChecker interface {
 	ShouldIgnore(absolutePath string) bool
 }
 
-// Watcher provides recursive file system watching with debouncing.
+// Mode selects how a Watcher detects changes.
+type Mode string
+
+const (
+	// ModeAuto uses fsnotify and polls the subtrees it cannot watch.
+	ModeAuto Mode = "auto"
+	// ModeFSNotify uses fsnotify only; directories it cannot watch are not updated.
+	ModeFSNotify Mode = "fsnotify"
+	// ModePoll scans the whole tree periodically, for filesystems without change
+	// notifications (NFS, SSHFS, bind-mounted container volumes, WSL shares).
+	ModePoll Mode = "poll"
+)
+
+// DefaultPollInterval is the scan interval of polled subtrees when none is configured.
+const DefaultPollInterval = 2 * time.Second
+
+// ParseMode converts a --watch-mode value to a Mode.
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case ModeAuto, ModeFSNotify, ModePoll:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown watch mode %q (must be auto, fsnotify or poll)", name)
+}
+
+// Options configures a Watcher.
+type Options struct {
+	Mode         Mode          // Default: ModeAuto
+	PollInterval time.Duration // Scan interval of polled subtrees. Default: DefaultPollInterval
+}
+
+// Watcher provides recursive file system watching with debouncing. Subtrees that fsnotify
+// cannot watch are polled instead, with the same events.
 type Watcher struct {
-	fsWatcher     *fsnotify.Watcher
+	fsWatcher     *fsnotify.Watcher // nil when polling only
+	poller        *poller
+	pollInterval  time.Duration
+	mode          Mode
 	debouncer     *Debouncer
 	ignoreChecker IgnoreChecker
 	rootDir       string
 	logger        *slog.Logger
+	done          chan struct{}
+	closeOnce     sync.Once
+
+	mu   sync.Mutex
+	dirs map[string]bool // directories watched with fsnotify
 }
 
 // NewWatcher creates a recursive file watcher on the given root directory.
 // It registers all non-ignored subdirectories for watching.
-func NewWatcher(rootDir string, ignoreChecker IgnoreChecker, logger *slog.Logger) (*Watcher, error) {
-	fsWatcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
+func NewWatcher(rootDir string, ignoreChecker IgnoreChecker, options Options, logger *slog.Logger) (*Watcher, error) {
+	if options.Mode == "" {
+		options.Mode = ModeAuto
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
 	}
 
 	w := &Watcher{
-		fsWatcher:     fsWatcher,
+		poller:        newPoller(rootDir, ignoreChecker),
+		pollInterval:  options.PollInterval,
+		mode:          options.Mode,
 		debouncer:     NewDebouncer(100 * time.Millisecond),
 		ignoreChecker: ignoreChecker,
 		rootDir:       rootDir,
 		logger:        logger,
+		done:          make(chan struct{}),
+		dirs:          make(map[string]bool),
+	}
+
+	if options.Mode != ModePoll {
+		fsWatcher, err := fsnotify.NewWatcher()
+		switch {
+		case err == nil:
+			w.fsWatcher = fsWatcher
+		case options.Mode == ModeFSNotify:
+			return nil, err
+		default:
+			logger.Warn("fsnotify unavailable, polling for changes instead", "error", err, "interval", options.PollInterval)
+		}
 	}
 
 	// Walk directory tree and add all non-ignored directories to the watcher
-	err = filepath.WalkDir(rootDir, func(path string, d os.DirEntry, err error) error {
+	w.watchSubtree(rootDir)
+
+	return w, nil
+}
+
+// watchSubtree watches dir and every non-ignored directory below it that is not watched yet.
+// Without fsnotify, or where adding a watch fails in auto mode, the subtree is polled instead.
+// Returns the number of directories added to fsnotify.
+func (w *Watcher) watchSubtree(dir string) int {
+	if w.fsWatcher == nil {
+		w.poller.addTree(dir)
+		return 0
+	}
+
+	added := 0
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip entries that can't be read
 		}
 		if !d.IsDir() {
 			return nil
 		}
-		if path != rootDir && ignoreChecker.ShouldIgnoreDir(path) {
+		if path != w.rootDir && w.ignoreChecker.ShouldIgnoreDir(path) {
+			return filepath.SkipDir
+		}
+		if w.poller.hasTree(path) {
+			return filepath.SkipDir
+		}
+		watched, err := w.watchDir(path)
+		if err != nil {
+			if w.mode == ModeFSNotify {
+				w.logger.Warn("failed to watch directory", "path", path, "error", err)
+				return nil
+			}
+			w.logger.Warn("failed to watch directory, polling it instead", "path", path, "error", err, "interval", w.pollInterval)
+			w.poller.addTree(path)
 			return filepath.SkipDir
 		}
-		if watchErr := fsWatcher.Add(path); watchErr != nil {
-			w.logger.Warn("failed to watch directory", "path", path, "error", watchErr)
+		if watched {
+			added++
 		}
 		return nil
 	})
-	if err != nil {
-		fsWatcher.Close()
-		return nil, err
+	return added
+}
+
+// watchDir starts watching a single directory with fsnotify. Returns false if it was
+// already watched.
+func (w *Watcher) watchDir(path string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dirs[path] {
+		return false, nil
 	}
+	if err := w.fsWatcher.Add(path); err != nil {
+		return false, err
+	}
+	w.dirs[path] = true
+	return true, nil
+}
 
-	return w, nil
+// unwatchSubtree stops watching and polling dir and every directory below it. A path that
+// is not a watched directory (e.g. a removed file) is a no-op. Returns the number of
+// directories removed from fsnotify.
+func (w *Watcher) unwatchSubtree(dir string) int {
+	w.poller.removeTrees(dir)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.dirs[dir] {
+		return 0
+	}
+	removed := 0
+	for path := range w.dirs {
+		if isWithin(path, dir) {
+			// The backend may already have dropped the watch of a removed or moved directory
+			w.fsWatcher.Remove(path)
+			delete(w.dirs, path)
+			removed++
+		}
+	}
+	return removed
 }
 
 // Events returns the channel that receives debounced file system events.
@@ -69,43 +191,72 @@ func (w *Watcher) Events() <-chan []DebouncedEvent {
 	return w.debouncer.Output()
 }
 
-// Start begins listening for file system events. Call this in a goroutine.
-// It runs until the watcher is closed.
+// Start begins listening for file system events and polling the subtrees fsnotify cannot
+// watch. Call this in a goroutine. It runs until the watcher is closed.
 func (w *Watcher) Start() {
+	var fsEvents <-chan fsnotify.Event
+	var fsErrors <-chan error
+	if w.fsWatcher != nil {
+		fsEvents = w.fsWatcher.Events
+		fsErrors = w.fsWatcher.Errors
+	}
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case event, ok := <-w.fsWatcher.Events:
+		case event, ok := <-fsEvents:
 			if !ok {
 				return
 			}
 			w.handleEvent(event)
 
-		case err, ok := <-w.fsWatcher.Errors:
+		case err, ok := <-fsErrors:
 			if !ok {
 				return
 			}
 			w.logger.Warn("watcher error", "error", err)
+
+		case <-ticker.C:
+			w.poller.poll(w.handlePolledChange)
+
+		case <-w.done:
+			return
 		}
 	}
 }
 
+// handlePolledChange converts a change found by the poller to a debounced event.
+func (w *Watcher) handlePolledChange(path string, op EventOp) {
+	if w.ignoreChecker.ShouldIgnore(path) {
+		return
+	}
+	w.debouncer.Add(path, op)
+}
+
 // handleEvent processes a single fsnotify event, converting it to a debounced event.
 func (w *Watcher) handleEvent(event fsnotify.Event) {
 	path := event.Name
 
-	// If a new directory was created, start watching it
+	// A new directory (created, or moved in from elsewhere) may already contain files and
+	// subdirectories: watch its whole subtree and report the directory so it gets indexed
 	if event.Has(fsnotify.Create) {
 		info, err := os.Stat(path)
 		if err == nil && info.IsDir() {
 			if !w.ignoreChecker.ShouldIgnoreDir(path) {
-				if err := w.fsWatcher.Add(path); err != nil {
-					w.logger.Warn("failed to watch new directory", "path", path, "error", err)
-				}
+				w.watchSubtree(path)
+				w.debouncer.Add(path, OpCreate)
 			}
-			return // Don't emit events for directory creation
+			return
 		}
 	}
 
+	// A removed or moved directory takes its subtree with it. Subdirectory watches of a moved
+	// directory would otherwise linger under their old paths.
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		w.unwatchSubtree(path)
+	}
+
 	// Skip ignored files
 	if w.ignoreChecker.ShouldIgnore(path) {
 		return
@@ -128,7 +279,54 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	w.debouncer.Add(path, op)
 }
 
+// Resync re-evaluates the watched directories below dir (inclusive) after ignore rules changed:
+// directories that are now ignored stop being watched and newly included ones are watched.
+// Returns the number of watches added and removed.
+func (w *Watcher) Resync(dir string) (added int, removed int) {
+	// Polled subtrees apply the new rules on their next scan
+	if w.fsWatcher == nil {
+		return 0, 0
+	}
+
+	wanted := make(map[string]bool)
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != w.rootDir && w.ignoreChecker.ShouldIgnoreDir(path) {
+			return filepath.SkipDir
+		}
+		if w.poller.hasTree(path) {
+			return filepath.SkipDir
+		}
+		wanted[path] = true
+		return nil
+	})
+
+	w.mu.Lock()
+	for path := range w.dirs {
+		if isWithin(path, dir) && !wanted[path] {
+			w.fsWatcher.Remove(path)
+			delete(w.dirs, path)
+			removed++
+		}
+	}
+	w.mu.Unlock()
+
+	return w.watchSubtree(dir), removed
+}
+
+// isWithin reports whether path is dir or inside it.
+func isWithin(path string, dir string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
 // Close stops the watcher and releases resources.
 func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	if w.fsWatcher == nil {
+		return nil
+	}
 	return w.fsWatcher.Close()
 }
@@ -0,0 +1,169 @@
+package watcher
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIgnoreChecker ignores the directories in its set.
+type fakeIgnoreChecker struct {
+	mu      sync.Mutex
+	ignored map[string]bool
+}
+
+func (c *fakeIgnoreChecker) ShouldIgnoreDir(absolutePath string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ignored[absolutePath]
+}
+
+func (c *fakeIgnoreChecker) ShouldIgnore(absolutePath string) bool {
+	return c.ShouldIgnoreDir(absolutePath)
+}
+
+func (c *fakeIgnoreChecker) set(path string, ignored bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ignored[path] = ignored
+}
+
+func watchedPaths(w *Watcher) []string {
+	paths := w.fsWatcher.WatchList()
+	sort.Strings(paths)
+	return paths
+}
+
+func Test_Watcher_Resync(t *testing.T) {
+	rootDir := t.TempDir()
+	generatedDir := filepath.Join(rootDir, "generated")
+	os.MkdirAll(filepath.Join(generatedDir, "nested"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "src"), 0755)
+
+	checker := &fakeIgnoreChecker{ignored: make(map[string]bool)}
+	w, err := NewWatcher(rootDir, checker, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if got := len(watchedPaths(w)); got != 4 {
+		t.Fatalf("expected 4 watched directories, got %v", watchedPaths(w))
+	}
+
+	// The directory becomes ignored: it and its subdirectory are no longer watched
+	checker.set(generatedDir, true)
+	added, removed := w.Resync(rootDir)
+	if added != 0 || removed != 2 {
+		t.Errorf("expected 0 added and 2 removed, got %d and %d", added, removed)
+	}
+	for _, path := range watchedPaths(w) {
+		if path == generatedDir {
+			t.Errorf("expected %s to be unwatched", path)
+		}
+	}
+
+	// Un-ignoring it again restores the watches
+	checker.set(generatedDir, false)
+	added, removed = w.Resync(generatedDir)
+	if added != 2 || removed != 0 {
+		t.Errorf("expected 2 added and 0 removed, got %d and %d", added, removed)
+	}
+	if got := len(watchedPaths(w)); got != 4 {
+		t.Errorf("expected 4 watched directories, got %v", watchedPaths(w))
+	}
+}
+
+// receiveEvents waits for the next batch of debounced events.
+func receiveEvents(t *testing.T, w *Watcher) []DebouncedEvent {
+	t.Helper()
+	select {
+	case batch := <-w.Events():
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watcher events")
+		return nil
+	}
+}
+
+func Test_Watcher_DirectoryRename(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "old", "nested"), 0755)
+
+	w, err := NewWatcher(rootDir, &fakeIgnoreChecker{ignored: make(map[string]bool)}, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	go w.Start()
+
+	if err := os.Rename(filepath.Join(rootDir, "old"), filepath.Join(rootDir, "new")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old path is reported as renamed and the new directory as created
+	ops := make(map[string]EventOp)
+	for len(ops) < 2 {
+		for _, event := range receiveEvents(t, w) {
+			ops[event.Path] = event.Op
+		}
+	}
+	if op, ok := ops[filepath.Join(rootDir, "old")]; !ok || (op != OpRename && op != OpRemove) {
+		t.Errorf("expected a rename event for the old directory, got %v", ops)
+	}
+	if op, ok := ops[filepath.Join(rootDir, "new")]; !ok || op != OpCreate {
+		t.Errorf("expected a create event for the new directory, got %v", ops)
+	}
+
+	// The whole subtree is watched under its new path only
+	want := []string{rootDir, filepath.Join(rootDir, "new"), filepath.Join(rootDir, "new", "nested")}
+	w.mu.Lock()
+	got := len(w.dirs)
+	w.mu.Unlock()
+	if got != len(want) {
+		t.Errorf("expected %d watched directories, got %d", len(want), got)
+	}
+	for _, path := range want {
+		w.mu.Lock()
+		watched := w.dirs[path]
+		w.mu.Unlock()
+		if !watched {
+			t.Errorf("expected %s to be watched", path)
+		}
+	}
+}
+
+func Test_Watcher_DirectoryMovedIn(t *testing.T) {
+	rootDir := t.TempDir()
+	outsideDir := filepath.Join(t.TempDir(), "incoming")
+	os.MkdirAll(filepath.Join(outsideDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(outsideDir, "sub", "file.go"), []byte("package sub\n"), 0644)
+
+	w, err := NewWatcher(rootDir, &fakeIgnoreChecker{ignored: make(map[string]bool)}, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	go w.Start()
+
+	movedDir := filepath.Join(rootDir, "incoming")
+	if err := os.Rename(outsideDir, movedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := receiveEvents(t, w)
+	if len(batch) != 1 || batch[0].Path != movedDir || batch[0].Op != OpCreate {
+		t.Errorf("expected one create event for the moved-in directory, got %+v", batch)
+	}
+
+	// Files created later in its subdirectory are reported too
+	os.WriteFile(filepath.Join(movedDir, "sub", "later.go"), []byte("package sub\n"), 0644)
+	batch = receiveEvents(t, w)
+	if len(batch) == 0 || batch[0].Path != filepath.Join(movedDir, "sub", "later.go") {
+		t.Errorf("expected an event for the file in the moved-in subdirectory, got %+v", batch)
+	}
+}
@@ -0,0 +1,180 @@
+// Package workspace describes the project roots served by one server.
+//
+// A workspace with a single root keeps index paths relative to that root. With several
+// roots every index path is prefixed with the name of its root ("api/cmd/main.go"), so
+// paths stay unique and results show which repository they come from.
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Root is one indexed project directory.
+type Root struct {
+	Name   string // Unique root name
+	Dir    string // Absolute directory
+	Prefix string // Prefix of the root's index paths: "" in a single-root workspace, otherwise Name + "/"
+}
+
+// IndexPath converts a path relative to the root (forward slashes) to its index path.
+func (r Root) IndexPath(relativePath string) string {
+	return r.Prefix + relativePath
+}
+
+// Owns reports whether an index path belongs to the root.
+func (r Root) Owns(indexPath string) bool {
+	return strings.HasPrefix(indexPath, r.Prefix)
+}
+
+// AbsPath converts an index path of the root to an absolute filesystem path.
+func (r Root) AbsPath(indexPath string) string {
+	return filepath.Join(r.Dir, filepath.FromSlash(strings.TrimPrefix(indexPath, r.Prefix)))
+}
+
+// Workspace is the ordered set of roots served by one server.
+type Workspace struct {
+	Roots []Root
+}
+
+// New creates a workspace from roots with absolute directories. Roots without a name are
+// named after their directory. Returns an error for duplicate or invalid names.
+func New(roots []Root) (*Workspace, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("workspace has no roots")
+	}
+
+	workspace := &Workspace{Roots: make([]Root, len(roots))}
+	seen := make(map[string]string)
+	for i, root := range roots {
+		if root.Name == "" {
+			root.Name = filepath.Base(root.Dir)
+		}
+		if strings.ContainsAny(root.Name, `/\`) || root.Name == "." || root.Name == ".." {
+			return nil, fmt.Errorf("invalid root name %q", root.Name)
+		}
+		if otherDir, exists := seen[root.Name]; exists {
+			return nil, fmt.Errorf("roots %s and %s are both named %q; name them explicitly with name=dir", otherDir, root.Dir, root.Name)
+		}
+		seen[root.Name] = root.Dir
+
+		root.Prefix = ""
+		if len(roots) > 1 {
+			root.Prefix = root.Name + "/"
+		}
+		workspace.Roots[i] = root
+	}
+	return workspace, nil
+}
+
+// ParseRootSpec parses a --root value: either a directory or name=directory.
+// Relative directories are resolved against the working directory.
+func ParseRootSpec(spec string) (Root, error) {
+	var root Root
+	if name, dir, found := strings.Cut(spec, "="); found && name != "" && !strings.ContainsAny(name, `/\:`) {
+		root.Name = name
+		spec = dir
+	}
+	if spec == "" {
+		return Root{}, fmt.Errorf("empty root directory")
+	}
+	dir, err := filepath.Abs(spec)
+	if err != nil {
+		return Root{}, fmt.Errorf("resolving root %s: %w", spec, err)
+	}
+	root.Dir = dir
+	return root, nil
+}
+
+// workspaceFile is the JSON format of a workspace file.
+type workspaceFile struct {
+	Roots []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"roots"`
+}
+
+// LoadFile reads the roots of a workspace file:
+//
+//	{"roots": [{"name": "api", "path": "../api"}, {"path": "../web"}]}
+//
+// Relative paths are resolved against the directory of the file.
+func LoadFile(path string) ([]Root, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading workspace file: %w", err)
+	}
+	var file workspaceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing workspace file %s: %w", path, err)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]Root, 0, len(file.Roots))
+	for _, entry := range file.Roots {
+		if entry.Path == "" {
+			return nil, fmt.Errorf("workspace file %s: root %q has no path", path, entry.Name)
+		}
+		dir := entry.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		roots = append(roots, Root{Name: entry.Name, Dir: filepath.Clean(dir)})
+	}
+	return roots, nil
+}
+
+// IsMulti reports whether the workspace has more than one root.
+func (w *Workspace) IsMulti() bool {
+	return len(w.Roots) > 1
+}
+
+// Root returns the root with the given name.
+func (w *Workspace) Root(name string) (Root, bool) {
+	for _, root := range w.Roots {
+		if root.Name == name {
+			return root, true
+		}
+	}
+	return Root{}, false
+}
+
+// RootOf returns the root an index path belongs to.
+func (w *Workspace) RootOf(indexPath string) (Root, bool) {
+	for _, root := range w.Roots {
+		if root.Owns(indexPath) {
+			return root, true
+		}
+	}
+	return Root{}, false
+}
+
+// Names returns the root names in workspace order.
+func (w *Workspace) Names() []string {
+	names := make([]string, len(w.Roots))
+	for i, root := range w.Roots {
+		names[i] = root.Name
+	}
+	return names
+}
+
+// Key identifies the workspace for per-workspace state such as snapshots and the shared
+// daemon. A single-root workspace is keyed by its directory, so existing caches stay valid.
+func (w *Workspace) Key() string {
+	if !w.IsMulti() {
+		return w.Roots[0].Dir
+	}
+	entries := make([]string, len(w.Roots))
+	for i, root := range w.Roots {
+		entries[i] = root.Name + "=" + root.Dir
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\n")
+}
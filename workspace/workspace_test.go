@@ -0,0 +1,98 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_New_SingleRootHasNoPrefix(t *testing.T) {
+	ws, err := New([]Root{{Dir: "/src/api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ws.Roots[0]
+	if root.Name != "api" || root.Prefix != "" {
+		t.Errorf("unexpected root: %+v", root)
+	}
+	if got := root.IndexPath("cmd/main.go"); got != "cmd/main.go" {
+		t.Errorf("expected unprefixed index path, got %q", got)
+	}
+	if ws.Key() != "/src/api" {
+		t.Errorf("expected single-root key to be the directory, got %q", ws.Key())
+	}
+}
+
+func Test_New_MultiRootPrefixesPaths(t *testing.T) {
+	ws, err := New([]Root{{Dir: "/src/api"}, {Name: "frontend", Dir: "/src/web"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ws.Roots[1].IndexPath("src/app.ts"); got != "frontend/src/app.ts" {
+		t.Errorf("expected prefixed index path, got %q", got)
+	}
+	if got := ws.Roots[1].AbsPath("frontend/src/app.ts"); got != filepath.Join("/src/web", "src", "app.ts") {
+		t.Errorf("unexpected absolute path %q", got)
+	}
+
+	root, ok := ws.RootOf("api/go.mod")
+	if !ok || root.Name != "api" {
+		t.Errorf("expected api root, got %+v (found=%v)", root, ok)
+	}
+	if _, ok := ws.RootOf("other/file.txt"); ok {
+		t.Error("expected no root for a path without a root prefix")
+	}
+	if !strings.Contains(ws.Key(), "api=/src/api") || !strings.Contains(ws.Key(), "frontend=/src/web") {
+		t.Errorf("expected key to contain every root, got %q", ws.Key())
+	}
+}
+
+func Test_New_DuplicateNames(t *testing.T) {
+	_, err := New([]Root{{Dir: "/a/service"}, {Dir: "/b/service"}})
+	if err == nil || !strings.Contains(err.Error(), "name=dir") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+func Test_ParseRootSpec(t *testing.T) {
+	root, err := ParseRootSpec("api=/src/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "api" || root.Dir != filepath.Clean("/src/api") {
+		t.Errorf("unexpected named root: %+v", root)
+	}
+
+	root, err = ParseRootSpec("/src/web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "" || root.Dir != filepath.Clean("/src/web") {
+		t.Errorf("unexpected unnamed root: %+v", root)
+	}
+
+	if _, err := ParseRootSpec("api="); err == nil {
+		t.Error("expected error for a root without directory")
+	}
+}
+
+func Test_LoadFile_ResolvesRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codeindex.workspace.json")
+	os.WriteFile(path, []byte(`{"roots": [{"name": "api", "path": "../api"}, {"path": "/abs/web"}]}`), 0644)
+
+	roots, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(roots))
+	}
+	if roots[0].Name != "api" || roots[0].Dir != filepath.Join(filepath.Dir(dir), "api") {
+		t.Errorf("unexpected first root: %+v", roots[0])
+	}
+	if roots[1].Dir != filepath.Clean("/abs/web") {
+		t.Errorf("unexpected second root: %+v", roots[1])
+	}
+}